@@ -20,13 +20,39 @@ func init() {
 }
 
 func main() {
-	ec := server.NewECServer()
-	mfwam := server.NewMFWAMServer()
-	smoc := server.NewSMOCSever()
+	// 所有数据集共用一个消息中间件的连接
+	broker, err := server.NewBroker()
+	if err != nil {
+		logrus.Fatalf("new broker error: %v", err)
+	}
+
+	ec, err := server.NewECServer(broker)
+	if err != nil {
+		logrus.Fatalf("new ec server error: %v", err)
+	}
+
+	mfwam, err := server.NewMFWAMServer(broker)
+	if err != nil {
+		logrus.Fatalf("new mfwam server error: %v", err)
+	}
+
+	smoc, err := server.NewSMOCSever(broker)
+	if err != nil {
+		logrus.Fatalf("new smoc server error: %v", err)
+	}
+
+	servers := []manager.Server{ec, mfwam, smoc}
+	if config.Get().Combined.Enable {
+		combined, err := server.NewCombinedServer(ec, mfwam, smoc, broker)
+		if err != nil {
+			logrus.Fatalf("new combined server error: %v", err)
+		}
+		servers = append(servers, combined)
+	}
 
 	manager := manager.New(
 		"气象源数据处理",
-		manager.AddServer(ec, mfwam, smoc),
+		manager.AddServer(servers...),
 		manager.BeforeStart(BeforeStartFunc),
 		manager.AfterStop(AfterStopFunc),
 		manager.Signal(syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT),
@@ -36,6 +62,13 @@ func main() {
 		if err := manager.Stop(); err != nil {
 			logrus.Errorf("停止所有的服务失败: %v", err)
 		}
+
+		// 所有服务停止后关闭消息中间件的连接, 等待缓存的事件发送完成
+		if broker != nil {
+			if err := broker.Close(); err != nil {
+				logrus.Errorf("关闭消息中间件的连接失败: %v", err)
+			}
+		}
 	}()
 
 	if err := manager.Run(); err != nil {
@@ -2,10 +2,12 @@ package config
 
 import (
 	"fmt"
+	"gen-meteo-file/pkg/global"
 	"gen-meteo-file/pkg/logger"
 
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
@@ -20,6 +22,23 @@ func Get() *Conf {
 type Conf struct {
 	Log    logger.Log `mapstructure:"log" yaml:"log"`
 	Server Server     `mapstructure:"base_server" yaml:"base_server"`
+	EC     Dataset    `mapstructure:"ec" yaml:"ec"`
+	MFWAM  Dataset    `mapstructure:"mfwam" yaml:"mfwam"`
+	SMOC   Dataset    `mapstructure:"smoc" yaml:"smoc"`
+
+	// 自定义区域, 格式: name:west,south,east,north
+	Regions []string `mapstructure:"regions" yaml:"regions"`
+
+	Regrid   Regrid   `mapstructure:"regrid" yaml:"regrid"`
+	Combined Combined `mapstructure:"combined" yaml:"combined"`
+	Output   Output   `mapstructure:"output" yaml:"output"`
+	S3       S3       `mapstructure:"s3" yaml:"s3"`
+	SFTP     SFTP     `mapstructure:"sftp" yaml:"sftp"`
+	Webhook  Webhook  `mapstructure:"webhook" yaml:"webhook"`
+	Broker   Broker   `mapstructure:"broker" yaml:"broker"`
+
+	// 额外的输出方案, 同一个输入文件解码一次, 按照每个方案输出到各自的根目录
+	Profiles []Profile `mapstructure:"profiles" yaml:"profiles"`
 }
 
 type Server struct {
@@ -27,9 +46,270 @@ type Server struct {
 	CSVDir string `mapstructure:"csv_dir" yaml:"csv_dir"`
 }
 
+// Dataset 单个数据源的输出配置
+type Dataset struct {
+	// 输入文件的路径模板, 相对 NC_DIR, 可以使用通配符, 例如: mfwam/{year}/{month}/*{cycle:2006010215}*
+	InputLayout string `mapstructure:"input_layout" yaml:"input_layout"`
+
+	// 输出文件的路径模板, 相对 CSV_DIR, 必须以 .{ext} 结尾, 例如: {year}/{month}/{date}/{dataset}_{cycle:2006010215}.{ext}
+	OutputLayout string `mapstructure:"output_layout" yaml:"output_layout"`
+
+	LatStride  int `mapstructure:"lat_stride" yaml:"lat_stride"`
+	LonStride  int `mapstructure:"lon_stride" yaml:"lon_stride"`
+	TimeStride int `mapstructure:"time_stride" yaml:"time_stride"`
+
+	// 时间插值的步长, 例如: 1h, 为空时不插值, 插值后时间抽样步长不再生效
+	TimeInterval string `mapstructure:"time_interval" yaml:"time_interval"`
+
+	// 输出的区域名称, 为空时只输出全球数据, global 表示全球
+	Regions []string `mapstructure:"regions" yaml:"regions"`
+
+	// 追加输出的派生列, 例如: windSpeed, windDirection, beaufort
+	Derived []string `mapstructure:"derived" yaml:"derived"`
+
+	// 只输出指定的列(包括派生列), 为空时输出所有列
+	Columns []string `mapstructure:"columns" yaml:"columns"`
+
+	// 输出列的单位方案: si, marine, imperial, 为空时输出原始单位
+	Units string `mapstructure:"units" yaml:"units"`
+
+	// 输出格式: csv, parquet, netcdf, geojson, 为空时只输出 csv
+	Formats []string `mapstructure:"formats" yaml:"formats"`
+
+	// GeoJSON 每隔多少个格点输出一个, 只对 geojson 格式生效, 小于等于 1 时输出所有格点
+	GeoJSONThin int `mapstructure:"geojson_thin" yaml:"geojson_thin"`
+
+	// PNG 瓦片, EC: 风速, MFWAM: 有效波高, SMOC: 流速
+	Tiles Tiles `mapstructure:"tiles" yaml:"tiles"`
+
+	// 输出 GeoTIFF 的变量, NetCDF 变量名或列名(包括派生列), 每个变量一个文件
+	GeoTIFF []string `mapstructure:"geotiff" yaml:"geotiff"`
+
+	// leaflet-velocity 格式的 u/v JSON, 只支持 EC 和 SMOC
+	Velocity Velocity `mapstructure:"velocity" yaml:"velocity"`
+}
+
+// Velocity 单个数据源的 velocity JSON 配置
+type Velocity struct {
+	Enable     bool    `mapstructure:"enable" yaml:"enable"`
+	Resolution float64 `mapstructure:"resolution" yaml:"resolution"` // 输出的分辨率(度), 0 表示使用原始网格
+	Region     string  `mapstructure:"region" yaml:"region"`         // 区域名称, 为空时输出全球
+}
+
+// Tiles 单个数据源的瓦片配置
+type Tiles struct {
+	Enable   bool   `mapstructure:"enable" yaml:"enable"`
+	Ramp     string `mapstructure:"ramp" yaml:"ramp"`         // value:#rrggbb,value:#rrggbb,...
+	Encoding string `mapstructure:"encoding" yaml:"encoding"` // color: 按色带着色, value: 值编码到 RGB
+}
+
+// Regrid 所有数据源共用的插值目标网格, 插值后空间抽样步长不再生效
+type Regrid struct {
+	Method     string    `mapstructure:"method" yaml:"method"` // bilinear / nearest, 为空时不插值
+	Resolution float64   `mapstructure:"resolution" yaml:"resolution"`
+	Extent     []float64 `mapstructure:"extent" yaml:"extent"`       // west, south, east, north
+	LatOrder   string    `mapstructure:"lat_order" yaml:"lat_order"` // desc: 90 ~ -90, asc: -90 ~ 90
+}
+
+// Combined 风, 浪, 流合并输出, 未配置插值方法时使用双线性插值到默认网格
+type Combined struct {
+	Enable       bool     `mapstructure:"enable" yaml:"enable"`
+	OutputLayout string   `mapstructure:"output_layout" yaml:"output_layout"`
+	Regions      []string `mapstructure:"regions" yaml:"regions"`
+	Columns      []string `mapstructure:"columns" yaml:"columns"`
+	Units        string   `mapstructure:"units" yaml:"units"`
+	Formats      []string `mapstructure:"formats" yaml:"formats"`
+
+	GeoJSONThin int `mapstructure:"geojson_thin" yaml:"geojson_thin"`
+}
+
+// Profile 输出方案, 例如为不同的客户输出不同的格式和区域, 为空的配置使用数据源自身的配置
+type Profile struct {
+	Name         string   `mapstructure:"name" yaml:"name"`
+	Dir          string   `mapstructure:"dir" yaml:"dir"`           // 输出的根目录
+	Layout       string   `mapstructure:"layout" yaml:"layout"`     // 相对根目录的文件路径模板, 为空时使用数据源的输出路径模板
+	Datasets     []string `mapstructure:"datasets" yaml:"datasets"` // 适用的数据源: ec, mfwam, smoc, combined, 为空时适用于所有数据源
+	Regions      []string `mapstructure:"regions" yaml:"regions"`
+	Columns      []string `mapstructure:"columns" yaml:"columns"`
+	Units        string   `mapstructure:"units" yaml:"units"`
+	Formats      []string `mapstructure:"formats" yaml:"formats"`
+	LatStride    int      `mapstructure:"lat_stride" yaml:"lat_stride"`
+	LonStride    int      `mapstructure:"lon_stride" yaml:"lon_stride"`
+	TimeStride   int      `mapstructure:"time_stride" yaml:"time_stride"`
+	ArchiveCodec string   `mapstructure:"archive_codec" yaml:"archive_codec"`
+	ArchiveLevel int      `mapstructure:"archive_level" yaml:"archive_level"`
+}
+
+// Output 所有输出格式共用的配置
+type Output struct {
+	ParquetCodec string `mapstructure:"parquet_codec" yaml:"parquet_codec"` // snappy / zstd / gzip / none
+	ArchiveCodec string `mapstructure:"archive_codec" yaml:"archive_codec"` // zip / gzip / zstd / xz / none
+	ArchiveLevel int    `mapstructure:"archive_level" yaml:"archive_level"` // 压缩级别 1~9, 0 表示默认级别, none 只能为 0
+	TileMinZoom  int    `mapstructure:"tile_min_zoom" yaml:"tile_min_zoom"`
+	TileMaxZoom  int    `mapstructure:"tile_max_zoom" yaml:"tile_max_zoom"`
+}
+
+// S3 兼容的对象存储(例如: MinIO)的配置, 用于上传输出文件和读取 s3:// 的输入文件
+type S3 struct {
+	Enable      bool   `mapstructure:"enable" yaml:"enable"`
+	Endpoint    string `mapstructure:"endpoint" yaml:"endpoint"` // host:port, 不含协议
+	AccessKey   string `mapstructure:"access_key" yaml:"access_key"`
+	SecretKey   string `mapstructure:"secret_key" yaml:"-"`
+	Region      string `mapstructure:"region" yaml:"region"`
+	Secure      bool   `mapstructure:"secure" yaml:"secure"` // 使用 https
+	Bucket      string `mapstructure:"bucket" yaml:"bucket"`
+	Prefix      string `mapstructure:"prefix" yaml:"prefix"`             // 对象前缀模板, 对象名为前缀加上文件名
+	PartSize    int    `mapstructure:"part_size" yaml:"part_size"`       // 分片大小(MB), 大于该大小的文件分片上传, 最小 5
+	Retries     int    `mapstructure:"retries" yaml:"retries"`           // 失败后的重试次数
+	DeleteLocal bool   `mapstructure:"delete_local" yaml:"delete_local"` // 上传成功后删除本地文件
+	CacheDir    string `mapstructure:"cache_dir" yaml:"cache_dir"`       // NC_DIR 为 s3:// 时输入文件的本地缓存目录, 为空时使用系统临时目录
+	CacheDays   int    `mapstructure:"cache_days" yaml:"cache_days"`     // 缓存文件超过多少天没有使用时删除, 0 表示不删除
+}
+
+// SFTP 输出文件投递到客户的 SFTP 服务器
+type SFTP struct {
+	Targets    []SFTPTarget `mapstructure:"targets" yaml:"targets"`
+	Retries    int          `mapstructure:"retries" yaml:"retries"`         // 失败后的重试次数
+	Timeout    int          `mapstructure:"timeout" yaml:"timeout"`         // 连接超时(秒)
+	StatusDir  string       `mapstructure:"status_dir" yaml:"status_dir"`   // 投递状态的目录, 为空时使用 CSV_DIR/.delivery
+	StatusDays int          `mapstructure:"status_days" yaml:"status_days"` // 投递状态超过多少天没有更新时删除, 0 表示不删除
+}
+
+// SFTPTarget SFTP 投递目标, 只支持密钥认证
+type SFTPTarget struct {
+	Name       string   `mapstructure:"name" yaml:"name"`
+	Addr       string   `mapstructure:"addr" yaml:"addr"` // host:port
+	User       string   `mapstructure:"user" yaml:"user"`
+	KeyFile    string   `mapstructure:"key_file" yaml:"key_file"` // PEM 格式的私钥文件
+	Passphrase string   `mapstructure:"passphrase" yaml:"-"`
+	KnownHosts string   `mapstructure:"known_hosts" yaml:"known_hosts"` // 校验服务器主机密钥的 known_hosts 文件
+	Insecure   bool     `mapstructure:"insecure" yaml:"insecure"`       // 不校验主机密钥, 只用于测试环境
+	Path       string   `mapstructure:"path" yaml:"path"`               // 远端目录模板, 文件名与本地相同
+	Datasets   []string `mapstructure:"datasets" yaml:"datasets"`       // 投递的数据源, 为空时投递所有数据源
+	Profile    string   `mapstructure:"profile" yaml:"profile"`         // 投递的输出方案, 为空时投递默认输出
+}
+
+// Webhook 起报时次的输出文件生成后通知下游服务
+type Webhook struct {
+	Targets    []WebhookTarget `mapstructure:"targets" yaml:"targets"`
+	Retries    int             `mapstructure:"retries" yaml:"retries"`         // 失败后的重试次数
+	Timeout    int             `mapstructure:"timeout" yaml:"timeout"`         // 单次请求的超时(秒)
+	DeadLetter string          `mapstructure:"dead_letter" yaml:"dead_letter"` // 重试后仍然失败的事件, 为空时使用 CSV_DIR/.webhook/dead_letter.jsonl
+}
+
+// WebhookTarget 事件以 JSON POST 到 URL, Secret 不为空时使用 HMAC-SHA256 签名
+type WebhookTarget struct {
+	Name     string   `mapstructure:"name" yaml:"name"`
+	URL      string   `mapstructure:"url" yaml:"url"`
+	Secret   string   `mapstructure:"secret" yaml:"-"`
+	Datasets []string `mapstructure:"datasets" yaml:"datasets"` // 通知的数据源, 为空时通知所有数据源
+}
+
+// Broker 起报时次的事件发布到消息中间件, 多个内部服务可以同时订阅
+type Broker struct {
+	Type    string      `mapstructure:"type" yaml:"type"`       // nats 或者 redis, 为空时不发布
+	Timeout int         `mapstructure:"timeout" yaml:"timeout"` // 单次发布的超时(秒)
+	NATS    NATSBroker  `mapstructure:"nats" yaml:"nats"`
+	Redis   RedisBroker `mapstructure:"redis" yaml:"redis"`
+}
+
+type NATSBroker struct {
+	URL     string `mapstructure:"url" yaml:"url"`         // 多个地址用 , 分隔
+	Subject string `mapstructure:"subject" yaml:"subject"` // 主题前缀, 事件发布到 {subject}.{事件类型}
+}
+
+type RedisBroker struct {
+	Addr     string `mapstructure:"addr" yaml:"addr"` // host:port
+	Password string `mapstructure:"password" yaml:"-"`
+	DB       int    `mapstructure:"db" yaml:"db"`
+	Stream   string `mapstructure:"stream" yaml:"stream"`
+	MaxLen   int64  `mapstructure:"max_len" yaml:"max_len"` // Stream 近似保留的最大长度, 0 表示不裁剪
+}
+
 func New() (*Conf, error) {
 	config = &Conf{
 		Log: logger.NewLog(),
+		EC: Dataset{
+			InputLayout:  global.DefaultECInputLayout,
+			OutputLayout: global.DefaultOutputLayout,
+			LatStride:    global.DefaultECLatStride,
+			LonStride:    global.DefaultECLonStride,
+			TimeStride:   global.DefaultECTimeStride,
+			Tiles: Tiles{
+				Ramp:     global.DefaultECTilesRamp,
+				Encoding: global.DefaultTilesEncoding,
+			},
+			Velocity: Velocity{
+				Resolution: global.DefaultVelocityResolution,
+			},
+		},
+		MFWAM: Dataset{
+			InputLayout:  global.DefaultMFWAMInputLayout,
+			OutputLayout: global.DefaultOutputLayout,
+			LatStride:    global.DefaultMFWAMLatStride,
+			LonStride:    global.DefaultMFWAMLonStride,
+			TimeStride:   global.DefaultMFWAMTimeStride,
+			Tiles: Tiles{
+				Ramp:     global.DefaultMFWAMTilesRamp,
+				Encoding: global.DefaultTilesEncoding,
+			},
+		},
+		SMOC: Dataset{
+			InputLayout:  global.DefaultSMOCInputLayout,
+			OutputLayout: global.DefaultSMOCOutputLayout,
+			LatStride:    global.DefaultSMOCLatStride,
+			LonStride:    global.DefaultSMOCLonStride,
+			TimeStride:   global.DefaultSMOCTimeStride,
+			Tiles: Tiles{
+				Ramp:     global.DefaultSMOCTilesRamp,
+				Encoding: global.DefaultTilesEncoding,
+			},
+			Velocity: Velocity{
+				Resolution: global.DefaultVelocityResolution,
+			},
+		},
+		Combined: Combined{
+			OutputLayout: global.DefaultOutputLayout,
+		},
+		Regrid: Regrid{
+			Method:     global.DefaultRegridMethod,
+			Resolution: global.DefaultRegridResolution,
+			Extent:     global.DefaultRegridExtent,
+			LatOrder:   global.DefaultRegridLatOrder,
+		},
+		Output: Output{
+			ParquetCodec: global.DefaultParquetCodec,
+			ArchiveCodec: global.DefaultArchiveCodec,
+			TileMinZoom:  global.DefaultTileMinZoom,
+			TileMaxZoom:  global.DefaultTileMaxZoom,
+		},
+		S3: S3{
+			Prefix:    global.DefaultS3Prefix,
+			PartSize:  global.DefaultS3PartSize,
+			Retries:   global.DefaultS3Retries,
+			CacheDays: global.DefaultS3CacheDays,
+		},
+		SFTP: SFTP{
+			Retries:    global.DefaultSFTPRetries,
+			Timeout:    global.DefaultSFTPTimeout,
+			StatusDays: global.DefaultSFTPStatusDays,
+		},
+		Webhook: Webhook{
+			Retries: global.DefaultWebhookRetries,
+			Timeout: global.DefaultWebhookTimeout,
+		},
+		Broker: Broker{
+			Timeout: global.DefaultBrokerTimeout,
+			NATS: NATSBroker{
+				URL:     global.DefaultNATSURL,
+				Subject: global.DefaultNATSSubject,
+			},
+			Redis: RedisBroker{
+				Addr:   global.DefaultRedisAddr,
+				Stream: global.DefaultRedisStream,
+				MaxLen: global.DefaultRedisMaxLen,
+			},
+		},
 	}
 
 	compareEnv()
@@ -48,6 +328,157 @@ func compareEnv() {
 
 	config.Server.NCDir = getEnvString("NC_DIR", config.Server.NCDir)
 	config.Server.CSVDir = getEnvString("CSV_DIR", config.Server.CSVDir)
+
+	config.Regions = getEnvStrings("REGIONS", ";", config.Regions)
+
+	// 插值信息
+	config.Regrid.Method = getEnvString("REGRID_METHOD", config.Regrid.Method)
+	config.Regrid.Resolution = getEnvFloat("REGRID_RESOLUTION", config.Regrid.Resolution)
+	config.Regrid.Extent = getEnvFloats("REGRID_EXTENT", config.Regrid.Extent)
+	config.Regrid.LatOrder = getEnvString("REGRID_LAT_ORDER", config.Regrid.LatOrder)
+
+	// 合并输出信息
+	config.Combined.Enable = getEnvBool("COMBINED_ENABLE", config.Combined.Enable)
+	config.Combined.OutputLayout = getEnvString("COMBINED_OUTPUT_LAYOUT", config.Combined.OutputLayout)
+	config.Combined.Regions = getEnvStrings("COMBINED_REGIONS", ",", config.Combined.Regions)
+	config.Combined.Columns = getEnvStrings("COMBINED_COLUMNS", ",", config.Combined.Columns)
+	config.Combined.Units = getEnvString("COMBINED_UNITS", config.Combined.Units)
+	config.Combined.Formats = getEnvStrings("COMBINED_FORMATS", ",", config.Combined.Formats)
+	config.Combined.GeoJSONThin = getEnvInt("COMBINED_GEOJSON_THIN", config.Combined.GeoJSONThin)
+
+	// 输出格式信息
+	config.Output.ParquetCodec = getEnvString("PARQUET_CODEC", config.Output.ParquetCodec)
+	config.Output.ArchiveCodec = getEnvString("ARCHIVE_CODEC", config.Output.ArchiveCodec)
+	config.Output.ArchiveLevel = getEnvInt("ARCHIVE_LEVEL", config.Output.ArchiveLevel)
+	config.Output.TileMinZoom = getEnvInt("TILE_MIN_ZOOM", config.Output.TileMinZoom)
+	config.Output.TileMaxZoom = getEnvInt("TILE_MAX_ZOOM", config.Output.TileMaxZoom)
+
+	// 对象存储信息
+	config.S3.Enable = getEnvBool("S3_ENABLE", config.S3.Enable)
+	config.S3.Endpoint = getEnvString("S3_ENDPOINT", config.S3.Endpoint)
+	config.S3.AccessKey = getEnvString("S3_ACCESS_KEY", config.S3.AccessKey)
+	config.S3.SecretKey = getEnvString("S3_SECRET_KEY", config.S3.SecretKey)
+	config.S3.Region = getEnvString("S3_REGION", config.S3.Region)
+	config.S3.Secure = getEnvBool("S3_SECURE", config.S3.Secure)
+	config.S3.Bucket = getEnvString("S3_BUCKET", config.S3.Bucket)
+	config.S3.Prefix = getEnvString("S3_PREFIX", config.S3.Prefix)
+	config.S3.PartSize = getEnvInt("S3_PART_SIZE", config.S3.PartSize)
+	config.S3.Retries = getEnvInt("S3_RETRIES", config.S3.Retries)
+	config.S3.DeleteLocal = getEnvBool("S3_DELETE_LOCAL", config.S3.DeleteLocal)
+	config.S3.CacheDir = getEnvString("S3_CACHE_DIR", config.S3.CacheDir)
+	config.S3.CacheDays = getEnvInt("S3_CACHE_DAYS", config.S3.CacheDays)
+
+	// SFTP 投递信息
+	config.SFTP.Retries = getEnvInt("SFTP_RETRIES", config.SFTP.Retries)
+	config.SFTP.Timeout = getEnvInt("SFTP_TIMEOUT", config.SFTP.Timeout)
+	config.SFTP.StatusDir = getEnvString("SFTP_STATUS_DIR", config.SFTP.StatusDir)
+	config.SFTP.StatusDays = getEnvInt("SFTP_STATUS_DAYS", config.SFTP.StatusDays)
+	for _, name := range getEnvStrings("SFTP_TARGETS", ",", nil) {
+		config.SFTP.Targets = append(config.SFTP.Targets, compareSFTPTargetEnv(name))
+	}
+
+	// Webhook 通知信息
+	config.Webhook.Retries = getEnvInt("WEBHOOK_RETRIES", config.Webhook.Retries)
+	config.Webhook.Timeout = getEnvInt("WEBHOOK_TIMEOUT", config.Webhook.Timeout)
+	config.Webhook.DeadLetter = getEnvString("WEBHOOK_DEAD_LETTER", config.Webhook.DeadLetter)
+	for _, name := range getEnvStrings("WEBHOOKS", ",", nil) {
+		config.Webhook.Targets = append(config.Webhook.Targets, compareWebhookEnv(name))
+	}
+
+	// 消息中间件信息
+	config.Broker.Type = getEnvString("BROKER_TYPE", config.Broker.Type)
+	config.Broker.Timeout = getEnvInt("BROKER_TIMEOUT", config.Broker.Timeout)
+	config.Broker.NATS.URL = getEnvString("NATS_URL", config.Broker.NATS.URL)
+	config.Broker.NATS.Subject = getEnvString("NATS_SUBJECT", config.Broker.NATS.Subject)
+	config.Broker.Redis.Addr = getEnvString("REDIS_ADDR", config.Broker.Redis.Addr)
+	config.Broker.Redis.Password = getEnvString("REDIS_PASSWORD", config.Broker.Redis.Password)
+	config.Broker.Redis.DB = getEnvInt("REDIS_DB", config.Broker.Redis.DB)
+	config.Broker.Redis.Stream = getEnvString("REDIS_STREAM", config.Broker.Redis.Stream)
+	config.Broker.Redis.MaxLen = int64(getEnvInt("REDIS_MAX_LEN", int(config.Broker.Redis.MaxLen)))
+
+	// 输出方案信息
+	for _, name := range getEnvStrings("PROFILES", ",", nil) {
+		config.Profiles = append(config.Profiles, compareProfileEnv(name))
+	}
+
+	// 数据源信息
+	compareDatasetEnv("EC", &config.EC)
+	compareDatasetEnv("MFWAM", &config.MFWAM)
+	compareDatasetEnv("SMOC", &config.SMOC)
+}
+
+// 数据源的环境变量以数据源名称为前缀, 例如: MFWAM_LAT_STRIDE
+func compareDatasetEnv(prefix string, d *Dataset) {
+	d.InputLayout = getEnvString(prefix+"_INPUT_LAYOUT", d.InputLayout)
+	d.OutputLayout = getEnvString(prefix+"_OUTPUT_LAYOUT", d.OutputLayout)
+	d.LatStride = getEnvInt(prefix+"_LAT_STRIDE", d.LatStride)
+	d.LonStride = getEnvInt(prefix+"_LON_STRIDE", d.LonStride)
+	d.TimeStride = getEnvInt(prefix+"_TIME_STRIDE", d.TimeStride)
+	d.TimeInterval = getEnvString(prefix+"_TIME_INTERVAL", d.TimeInterval)
+	d.Regions = getEnvStrings(prefix+"_REGIONS", ",", d.Regions)
+	d.Derived = getEnvStrings(prefix+"_DERIVED", ",", d.Derived)
+	d.Columns = getEnvStrings(prefix+"_COLUMNS", ",", d.Columns)
+	d.Units = getEnvString(prefix+"_UNITS", d.Units)
+	d.Formats = getEnvStrings(prefix+"_FORMATS", ",", d.Formats)
+	d.GeoJSONThin = getEnvInt(prefix+"_GEOJSON_THIN", d.GeoJSONThin)
+	d.Tiles.Enable = getEnvBool(prefix+"_TILES_ENABLE", d.Tiles.Enable)
+	d.Tiles.Ramp = getEnvString(prefix+"_TILES_RAMP", d.Tiles.Ramp)
+	d.Tiles.Encoding = getEnvString(prefix+"_TILES_ENCODING", d.Tiles.Encoding)
+	d.GeoTIFF = getEnvStrings(prefix+"_GEOTIFF", ",", d.GeoTIFF)
+	d.Velocity.Enable = getEnvBool(prefix+"_VELOCITY_ENABLE", d.Velocity.Enable)
+	d.Velocity.Resolution = getEnvFloat(prefix+"_VELOCITY_RESOLUTION", d.Velocity.Resolution)
+	d.Velocity.Region = getEnvString(prefix+"_VELOCITY_REGION", d.Velocity.Region)
+}
+
+// 输出方案的环境变量以 PROFILE_{方案名称} 为前缀, 例如: PROFILE_COSCO_DIR
+func compareProfileEnv(name string) Profile {
+	prefix := "PROFILE_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+
+	return Profile{
+		Name:         name,
+		Dir:          getEnvString(prefix+"_DIR", ""),
+		Layout:       getEnvString(prefix+"_LAYOUT", ""),
+		Datasets:     getEnvStrings(prefix+"_DATASETS", ",", nil),
+		Regions:      getEnvStrings(prefix+"_REGIONS", ",", nil),
+		Columns:      getEnvStrings(prefix+"_COLUMNS", ",", nil),
+		Units:        getEnvString(prefix+"_UNITS", ""),
+		Formats:      getEnvStrings(prefix+"_FORMATS", ",", nil),
+		LatStride:    getEnvInt(prefix+"_LAT_STRIDE", 0),
+		LonStride:    getEnvInt(prefix+"_LON_STRIDE", 0),
+		TimeStride:   getEnvInt(prefix+"_TIME_STRIDE", 0),
+		ArchiveCodec: getEnvString(prefix+"_ARCHIVE_CODEC", ""),
+		ArchiveLevel: getEnvInt(prefix+"_ARCHIVE_LEVEL", 0),
+	}
+}
+
+// SFTP 投递目标的环境变量以 SFTP_{目标名称} 为前缀(大写, - 替换为 _), 例如: SFTP_COSCO_ADDR
+func compareSFTPTargetEnv(name string) SFTPTarget {
+	prefix := "SFTP_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+
+	return SFTPTarget{
+		Name:       name,
+		Addr:       getEnvString(prefix+"_ADDR", ""),
+		User:       getEnvString(prefix+"_USER", ""),
+		KeyFile:    getEnvString(prefix+"_KEY_FILE", ""),
+		Passphrase: getEnvString(prefix+"_PASSPHRASE", ""),
+		KnownHosts: getEnvString(prefix+"_KNOWN_HOSTS", ""),
+		Insecure:   getEnvBool(prefix+"_INSECURE", false),
+		Path:       getEnvString(prefix+"_PATH", global.DefaultSFTPPath),
+		Datasets:   getEnvStrings(prefix+"_DATASETS", ",", nil),
+		Profile:    getEnvString(prefix+"_PROFILE", ""),
+	}
+}
+
+// Webhook 的环境变量以 WEBHOOK_{名称} 为前缀(大写, - 替换为 _), 例如: WEBHOOK_ROUTING_URL
+func compareWebhookEnv(name string) WebhookTarget {
+	prefix := "WEBHOOK_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+
+	return WebhookTarget{
+		Name:     name,
+		URL:      getEnvString(prefix+"_URL", ""),
+		Secret:   getEnvString(prefix+"_SECRET", ""),
+		Datasets: getEnvStrings(prefix+"_DATASETS", ",", nil),
+	}
 }
 
 func (c *Conf) Show() {
@@ -82,3 +513,61 @@ func getEnvInt(key string, defaultValue int) int {
 
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		valueBool, err := strconv.ParseBool(value)
+		if err != nil {
+			return defaultValue
+		}
+
+		return valueBool
+	}
+
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, ok := os.LookupEnv(key); ok {
+		valueFloat, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return defaultValue
+		}
+
+		return valueFloat
+	}
+
+	return defaultValue
+}
+
+func getEnvFloats(key string, defaultValue []float64) []float64 {
+	if _, ok := os.LookupEnv(key); !ok {
+		return defaultValue
+	}
+
+	values := make([]float64, 0)
+	for _, v := range getEnvStrings(key, ",", nil) {
+		valueFloat, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return defaultValue
+		}
+		values = append(values, valueFloat)
+	}
+
+	return values
+}
+
+func getEnvStrings(key, sep string, defaultValue []string) []string {
+	if value, ok := os.LookupEnv(key); ok {
+		values := make([]string, 0)
+		for _, v := range strings.Split(value, sep) {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+
+		return values
+	}
+
+	return defaultValue
+}
@@ -2,14 +2,91 @@ package global
 
 import "fmt"
 
+// ProgramName 程序名称
+const ProgramName = "gen-meteo-file"
+
+// Version 程序版本, 编译时通过 -ldflags "-X gen-meteo-file/pkg/global.Version=v0.0.1" 注入
+var Version = "dev"
+
 const (
 	// 日志配置
 	DefaultLevel      = "info"
 	DefaultMaxLogSize = 20
 	DefaultMaxLogAge  = 10
 	DefaultMaxBackups = 5
+
+	// 抽样步长配置, 1 表示原始分辨率
+	DefaultECLatStride     = 1
+	DefaultECLonStride     = 1
+	DefaultECTimeStride    = 1
+	DefaultMFWAMLatStride  = 3
+	DefaultMFWAMLonStride  = 3
+	DefaultMFWAMTimeStride = 1
+	DefaultSMOCLatStride   = 3
+	DefaultSMOCLonStride   = 3
+	DefaultSMOCTimeStride  = 3
+
+	// 插值目标网格配置, 插值方法为空时不插值
+	DefaultRegridMethod     = ""
+	DefaultRegridResolution = 0.25
+	DefaultRegridLatOrder   = "desc"
+
+	// 输出格式配置
+	DefaultParquetCodec = "snappy"
+	DefaultArchiveCodec = "zip"
+
+	// 瓦片配置, 色带的值为国际单位: 风速(m/s), 有效波高(m), 流速(m/s)
+	DefaultTileMinZoom    = 0
+	DefaultTileMaxZoom    = 4
+	DefaultTilesEncoding  = "color"
+	DefaultECTilesRamp    = "0:#3288bd,5:#66c2a5,10:#abdda4,15:#fee08b,20:#fdae61,25:#f46d43,30:#d53e4f"
+	DefaultMFWAMTilesRamp = "0:#f7fbff,1:#c6dbef,2:#6baed6,4:#2171b5,6:#fdae61,9:#d7301f,14:#7f0000"
+	DefaultSMOCTilesRamp  = "0:#ffffcc,0.25:#c7e9b4,0.5:#7fcdbb,1:#41b6c4,1.5:#1d91c0,2:#225ea8,3:#0c2c84"
+
+	// 输入文件的路径模板, 相对 NC_DIR
+	DefaultECInputLayout    = "ec_0p25/{year}/{date}/oper-{run:15}/ec_0p25_oper_{run:2006010215}_{step}h.nc"
+	DefaultMFWAMInputLayout = "mfwam/{year}/{month}/*{cycle:2006010215}*"
+	DefaultSMOCInputLayout  = "smoc/{year}/{month}/*{cycle:20060102}*"
+
+	// 输出文件的路径模板, 相对 CSV_DIR 或输出方案的根目录, SMOC 每天一个文件
+	DefaultOutputLayout     = "{year}/{month}/{date}/{dataset}_{cycle:2006010215}.{ext}"
+	DefaultSMOCOutputLayout = "{year}/{month}/{date}/{dataset}_{cycle:20060102}.{ext}"
+
+	// velocity JSON 的分辨率(度), 0 表示使用原始网格
+	DefaultVelocityResolution = 1.0
+
+	// 对象存储配置, 分片大小单位为 MB
+	DefaultS3Prefix   = "{profile}/{dataset}/{year}/{month}/{date}"
+	DefaultS3PartSize = 16
+	DefaultS3Retries  = 3
+
+	// NC_DIR 为 s3:// 时输入文件的本地缓存保留天数
+	DefaultS3CacheDays = 7
+
+	// SFTP 投递配置, 远端目录模板相对登录目录, 超时单位为秒
+	DefaultSFTPPath    = "{dataset}/{year}/{month}/{date}"
+	DefaultSFTPRetries = 3
+	DefaultSFTPTimeout = 30
+
+	// 投递状态的保留天数, 需要大于重新检查起报时次的天数, 否则本地文件还在时会重复投递
+	DefaultSFTPStatusDays = 30
+
+	// Webhook 通知配置, 超时单位为秒
+	DefaultWebhookRetries = 3
+	DefaultWebhookTimeout = 10
+
+	// 消息中间件配置, 超时单位为秒
+	DefaultBrokerTimeout = 10
+	DefaultNATSURL       = "nats://127.0.0.1:4222"
+	DefaultNATSSubject   = "meteo"
+	DefaultRedisAddr     = "127.0.0.1:6379"
+	DefaultRedisStream   = "meteo:products"
+	DefaultRedisMaxLen   = 10000
 )
 
+// 插值目标网格的默认范围: west, south, east, north
+var DefaultRegridExtent = []float64{-180, -80, 179.75, 90}
+
 func ShowProgramInfo() {
 	fmt.Printf(`
 
@@ -23,10 +100,11 @@ func ShowProgramInfo() {
                                                                                  
 
    
-	ProgramName: gen-meteo-file
+	ProgramName: %s
+	Version: %s
 	Org: nav_green
 	OrgUrl: http://www.navgreen.cn
 
 
-`)
+`, ProgramName, Version)
 }
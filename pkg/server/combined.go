@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"gen-meteo-file/pkg/config"
+	"gen-meteo-file/pkg/global"
+	"gen-meteo-file/pkg/tools/layout"
+	"gen-meteo-file/pkg/tools/nc"
+	"gen-meteo-file/pkg/tools/notify"
+	"gen-meteo-file/pkg/tools/regrid"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CombinedServer 将同一时刻的 EC 风, MFWAM 浪, SMOC 流合并到一个文件
+type CombinedServer struct {
+	datasetOptions
+	ec    *ECServer
+	mfwam *MFWAMServer
+	smoc  *SMOCSever
+	cache *nc.CombinedCache // 一轮生成中共用解码后的 MFWAM/SMOC 文件
+}
+
+func NewCombinedServer(ec *ECServer, mfwam *MFWAMServer, smoc *SMOCSever, broker notify.Broker) (*CombinedServer, error) {
+	c := config.Get().Combined
+	opts, err := newDatasetOptions("combined", config.Dataset{
+		OutputLayout: c.OutputLayout,
+		Regions:      c.Regions,
+		Columns:      c.Columns,
+		Units:        c.Units,
+		Formats:      c.Formats,
+		GeoJSONThin:  c.GeoJSONThin,
+	}, broker)
+	if err != nil {
+		return nil, err
+	}
+
+	// 未配置插值时, 使用默认网格对齐
+	if opts.target == nil {
+		opts.target, err = newRegridTarget(config.Regrid{
+			Method:     string(regrid.Bilinear),
+			Resolution: global.DefaultRegridResolution,
+			Extent:     global.DefaultRegridExtent,
+			LatOrder:   global.DefaultRegridLatOrder,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("combined default regrid target failed: %v", err)
+		}
+	}
+
+	s := &CombinedServer{
+		datasetOptions: opts,
+		ec:             ec,
+		mfwam:          mfwam,
+		smoc:           smoc,
+		cache:          nc.NewCombinedCache(),
+	}
+	if s.profiles, err = s.outputProfiles("combined"); err != nil {
+		return nil, fmt.Errorf("combined output profiles failed: %v", err)
+	}
+
+	return s, nil
+}
+
+func (s *CombinedServer) Start(ctx context.Context) error {
+	ticker := time.NewTicker(time.Second)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			today, _ := time.Parse("20060102", time.Now().Format("20060102"))
+			for range 5 * 8 {
+				select {
+				case <-ctx.Done():
+					return nil
+				default:
+					if err := s.GenByDate(ctx, today); err != nil {
+						logrus.Errorf("generate combined file by date failed: %v, date: %v", err, today)
+					}
+
+					today = today.Add(-time.Hour * 3)
+				}
+			}
+
+			// 一轮生成结束后释放缓存, 避免在两轮之间占用内存
+			s.cache.Close()
+
+			ticker.Reset(time.Hour * 24)
+		}
+	}
+}
+
+// GenByDate 生成某一时刻的合并文件, MFWAM 每 12 小时一个文件, SMOC 每天一个文件
+func (s *CombinedServer) GenByDate(ctx context.Context, date time.Time) error {
+	mfwamDate := date.Truncate(time.Hour * 12)
+	smocDate := date.Truncate(time.Hour * 24)
+
+	inputs := []nc.CombinedInput{
+		{Name: nc.CombinedECName, DateTime: date, InputPath: s.ec.getECPath(ctx, date)},
+		{Name: nc.CombinedMFWAMName, DateTime: mfwamDate},
+		{Name: nc.CombinedSMOCName, DateTime: smocDate},
+	}
+
+	// 找不到输入文件时, 在合并时作为缺失的数据源处理
+	if path, err := s.mfwam.getMFWAMPath(ctx, mfwamDate); err == nil {
+		inputs[1].InputPath = path
+	}
+	if path, err := s.smoc.getSMOCPath(ctx, smocDate); err == nil {
+		inputs[2].InputPath = path
+	}
+
+	values := layout.Values{Dataset: "combined", Time: date}
+	outputPath, compressionPath := outputPaths(s.outputDir, s.output, values)
+
+	info := &nc.NCFile{
+		DateTime:        date,
+		OutputPath:      outputPath,
+		CompressionPath: compressionPath,
+		Regions:         s.regions,
+		Target:          s.target,
+		Columns:         s.columns,
+		Units:           s.units,
+		Formats:         s.formats,
+		ParquetCodec:    s.codec,
+		Archive:         s.archive,
+		ArchiveLevel:    s.level,
+		Profiles:        profilesAt(s.profiles, values, s.publish),
+		Uploaders:       s.publish.uploaders(values),
+		DeleteUploaded:  s.publish.deleteUploaded(),
+		Checksum:        s.notify.enabled(),
+		GeoJSONThin:     s.thin,
+	}
+
+	// 对象存储中的输入文件在输出文件还没有全部生成时才下载
+	paths := make([]*string, 0, len(inputs))
+	for i := range inputs {
+		paths = append(paths, &inputs[i].InputPath)
+	}
+	if err := s.ec.inputs.localInputs(ctx, info, paths...); err != nil {
+		return fmt.Errorf("fetch combined input files failed: %v", err)
+	}
+
+	nc, err := nc.NewCombined(ctx, info, inputs, s.cache)
+	if err != nil {
+		if generated(err) {
+			return nil
+		}
+		return fmt.Errorf("new combined failed: %v", err)
+	}
+	defer nc.Close()
+
+	if err := nc.Analysis(); err != nil {
+		notified := !noInputs(err)
+		err = fmt.Errorf("combined analysis failed: %v", err)
+		// 所有数据源都还没有到达时不通知
+		if notified {
+			s.notify.failed(ctx, date, err)
+		}
+		return err
+	}
+
+	// 缺失的数据源写入元数据和通知, 数据源到达后重新生成
+	missing := nc.Missing()
+	for name, reason := range missing {
+		logrus.Warnf("combined source: %s is missing: %s, date: %v", name, reason, date)
+	}
+
+	// 部分文件失败时, 已经完成的文件照常通知, 下一轮不会重新生成
+	err = nc.GenerateCSV(ctx)
+	s.notify.ready(ctx, date, info.Results(), missing)
+	if err != nil {
+		err = fmt.Errorf("combined generate csv failed: %v", err)
+		s.notify.failed(ctx, date, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *CombinedServer) Stop(ctx context.Context) error {
+	s.cache.Close()
+	return nil
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"gen-meteo-file/pkg/config"
+	"gen-meteo-file/pkg/tools/delivery"
+	"gen-meteo-file/pkg/tools/layout"
+	"gen-meteo-file/pkg/tools/nc"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sftpTarget 一个 SFTP 投递目标, 投递状态按照目标和数据源保存在 {StatusDir}/{target}_{dataset}.json
+type sftpTarget struct {
+	name    string
+	client  *delivery.Client
+	dir     *layout.Template
+	profile string
+	status  *delivery.Status
+}
+
+// sftpTargets 投递数据源输出文件的 SFTP 目标
+func sftpTargets(dataset string) ([]*sftpTarget, error) {
+	c := config.Get().SFTP
+
+	statusDir := c.StatusDir
+	if statusDir == "" {
+		statusDir = filepath.Join(config.Get().Server.CSVDir, ".delivery")
+	}
+
+	if c.StatusDays < 0 {
+		return nil, fmt.Errorf("sftp status days: %d must not be negative", c.StatusDays)
+	}
+
+	targets := make([]*sftpTarget, 0, len(c.Targets))
+	names := make(map[string]bool, len(c.Targets))
+	for _, t := range c.Targets {
+		if names[t.Name] {
+			return nil, fmt.Errorf("sftp target: %s is duplicated", t.Name)
+		}
+		names[t.Name] = true
+
+		if len(t.Datasets) > 0 && !slices.Contains(t.Datasets, dataset) {
+			continue
+		}
+
+		target, err := newSFTPTarget(t, dataset, statusDir)
+		if err != nil {
+			return nil, fmt.Errorf("sftp target: %s: %v", t.Name, err)
+		}
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+func newSFTPTarget(t config.SFTPTarget, dataset, statusDir string) (*sftpTarget, error) {
+	if t.Profile != "" && !slices.ContainsFunc(config.Get().Profiles, func(p config.Profile) bool { return p.Name == t.Profile }) {
+		return nil, fmt.Errorf("output profile: %s not found", t.Profile)
+	}
+
+	dir, err := layout.Parse(t.Path)
+	if err != nil {
+		return nil, fmt.Errorf("remote path failed: %v", err)
+	}
+
+	key, err := os.ReadFile(t.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %s failed: %v", t.KeyFile, err)
+	}
+
+	client, err := delivery.New(delivery.Target{
+		Name:       t.Name,
+		Addr:       t.Addr,
+		User:       t.User,
+		Key:        key,
+		Passphrase: t.Passphrase,
+		KnownHosts: t.KnownHosts,
+		Insecure:   t.Insecure,
+		Retries:    config.Get().SFTP.Retries,
+		Timeout:    time.Duration(config.Get().SFTP.Timeout) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	retention := time.Duration(config.Get().SFTP.StatusDays) * 24 * time.Hour
+	status, err := delivery.LoadStatus(filepath.Join(statusDir, t.Name+"_"+dataset+".json"), retention)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sftpTarget{name: t.Name, client: client, dir: dir, profile: t.Profile, status: status}, nil
+}
+
+// sftpUploader 将输出文件投递到 {dir}/{文件名}, 每次投递后记录状态
+type sftpUploader struct {
+	target *sftpTarget
+	dir    string
+}
+
+func (u *sftpUploader) remote(file string) string {
+	return path.Join(u.dir, filepath.Base(file))
+}
+
+// Upload 只投递文件本身, 元数据已经打包在压缩包中
+func (u *sftpUploader) Upload(ctx context.Context, file string, metadata []byte) error {
+	remote := u.remote(file)
+
+	record, _ := u.target.status.Get(file)
+	attempts, err := u.target.client.Deliver(ctx, file, remote)
+
+	record.Remote = remote
+	record.Delivered = err == nil
+	record.Attempts += attempts
+	record.Error = ""
+	record.UpdatedAt = time.Now()
+	if info, statErr := os.Stat(file); statErr == nil {
+		record.Size = info.Size()
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if err := u.target.status.Set(file, record); err != nil {
+		logrus.Warnf("save sftp target: %s delivery status failed: %v", u.target.name, err)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("deliver file: %s to sftp target: %s:%s success", file, u.target.name, remote)
+	return nil
+}
+
+// Location sftp://{目标名称}/{远端路径}, 远端路径相对登录目录
+func (u *sftpUploader) Location(file string) string {
+	return "sftp://" + path.Join(u.target.name, u.remote(file))
+}
+
+// Uploaded 以投递状态为准, 不查询远端, 客户取走文件后不会重复投递
+func (u *sftpUploader) Uploaded(ctx context.Context, file string) bool {
+	record, ok := u.target.status.Get(file)
+	return ok && record.Delivered
+}
+
+// sftpUploaders 起报时次输出方案的 SFTP Uploader
+func sftpUploaders(targets []*sftpTarget, v layout.Values) []nc.Uploader {
+	var uploaders []nc.Uploader
+	for _, t := range targets {
+		if t.profile != v.Profile {
+			continue
+		}
+
+		uploaders = append(uploaders, &sftpUploader{target: t, dir: t.dir.Execute(v)})
+	}
+
+	return uploaders
+}
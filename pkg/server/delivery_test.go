@@ -0,0 +1,182 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"gen-meteo-file/pkg/tools/delivery"
+	"gen-meteo-file/pkg/tools/layout"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// startSFTPServer 进程内的 SFTP 服务器, 文件保存在内存中, 只接受 clientKey 认证, 返回地址和主机密钥
+func startSFTPServer(t *testing.T, clientKey ssh.PublicKey) (string, ssh.PublicKey) {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("public key denied")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	handlers := sftp.InMemHandler()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+
+				for newChannel := range chans {
+					channel, requests, err := newChannel.Accept()
+					if err != nil {
+						continue
+					}
+
+					go func() {
+						for req := range requests {
+							ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+							req.Reply(ok, nil)
+							if ok {
+								server := sftp.NewRequestServer(channel, handlers)
+								server.Serve()
+								server.Close()
+							}
+						}
+					}()
+				}
+			}()
+		}
+	}()
+
+	return ln.Addr().String(), hostSigner.PublicKey()
+}
+
+func TestSFTPUploaderStatus(t *testing.T) {
+	_, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientSigner, err := ssh.NewSignerFromKey(clientPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, _ := startSFTPServer(t, clientSigner.PublicKey())
+
+	// 关闭的端口, 连接失败
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := ln.Addr().String()
+	ln.Close()
+
+	newClient := func(addr string) *delivery.Client {
+		c, err := delivery.New(delivery.Target{Name: "lab", Addr: addr, User: "meteo", Key: pem.EncodeToMemory(block), Insecure: true, Timeout: 5 * time.Second})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	dir, err := layout.Parse("upload/{dataset}/{date}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statusPath := filepath.Join(t.TempDir(), "lab_ec.json")
+	status, err := delivery.LoadStatus(statusPath, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := &sftpTarget{name: "lab", client: newClient(closed), dir: dir, status: status}
+	uploaders := sftpUploaders([]*sftpTarget{target}, layout.Values{Dataset: "ec", Time: time.Date(2025, 6, 13, 0, 0, 0, 0, time.UTC)})
+	if len(uploaders) != 1 {
+		t.Fatalf("uploaders = %d, want 1", len(uploaders))
+	}
+	u := uploaders[0]
+
+	local := filepath.Join(t.TempDir(), "ec_2025061300.zip")
+	if err := os.WriteFile(local, make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// 投递失败时记录错误, 不算已经投递
+	if err := u.Upload(ctx, local, nil); err == nil {
+		t.Fatalf("Upload() to closed port succeeded")
+	}
+	if u.Uploaded(ctx, local) {
+		t.Errorf("Uploaded() = true after failure")
+	}
+
+	record, ok := status.Get(local)
+	if !ok || record.Delivered || record.Error == "" || record.Attempts != 1 || record.Remote != "upload/ec/2025-06-13/ec_2025061300.zip" || record.Size != 100 {
+		t.Errorf("record after failure = %+v", record)
+	}
+
+	// 投递成功后清除错误, 尝试次数累计
+	target.client = newClient(addr)
+	if err := u.Upload(ctx, local, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !u.Uploaded(ctx, local) {
+		t.Errorf("Uploaded() = false after success")
+	}
+
+	// 状态写入文件, 重启后不会重复投递
+	status, err = delivery.LoadStatus(statusPath, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, ok = status.Get(local)
+	if !ok || !record.Delivered || record.Error != "" || record.Attempts != 2 || record.Remote != "upload/ec/2025-06-13/ec_2025061300.zip" || record.Size != 100 {
+		t.Errorf("record after success = %+v", record)
+	}
+	if time.Since(record.UpdatedAt) > time.Minute {
+		t.Errorf("record updated at = %v", record.UpdatedAt)
+	}
+}
@@ -4,23 +4,30 @@ import (
 	"context"
 	"fmt"
 	"gen-meteo-file/pkg/config"
+	"gen-meteo-file/pkg/tools/layout"
 	"gen-meteo-file/pkg/tools/nc"
-	"path/filepath"
+	"gen-meteo-file/pkg/tools/notify"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 type ECServer struct {
-	inputDir  string
-	outputDir string
+	datasetOptions
 }
 
-func NewECServer() *ECServer {
-	return &ECServer{
-		inputDir:  filepath.Join(config.Get().Server.NCDir, "ec_0p25"),
-		outputDir: filepath.Join(config.Get().Server.CSVDir),
+func NewECServer(broker notify.Broker) (*ECServer, error) {
+	opts, err := newDatasetOptions("ec", config.Get().EC, broker)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ECServer{datasetOptions: opts}
+	if s.profiles, err = s.outputProfiles("ec"); err != nil {
+		return nil, fmt.Errorf("ec output profiles failed: %v", err)
 	}
+
+	return s, nil
 }
 
 func (s *ECServer) Start(ctx context.Context) error {
@@ -51,31 +58,74 @@ func (s *ECServer) Start(ctx context.Context) error {
 }
 
 func (s *ECServer) GenByDate(ctx context.Context, date time.Time) error {
-	var hour = 0
-	if date.Hour() >= 12 {
-		hour = 12
-	}
+	values := layout.Values{Dataset: "ec", Time: date}
+	outputPath, compressionPath := outputPaths(s.outputDir, s.output, values)
 
-	// /data2/alist_share/nc-files/ec_0p25/2025/2025-01-01/oper-00/ec_0p25_oper_2025010100_0h.nc
 	info := &nc.NCFile{
 		DateTime:        date,
-		InputPath:       filepath.Join(s.inputDir, fmt.Sprintf("%d", date.Year()), date.Format(time.DateOnly), fmt.Sprintf("oper-%02d", hour), fmt.Sprintf("ec_0p25_oper_%s%02d_%dh.nc", date.Format("20060102"), hour, date.Hour()-hour)),
-		OutputPath:      filepath.Join(s.outputDir, fmt.Sprintf("%d", date.Year()), fmt.Sprintf("%02d", date.Month()), date.Format(time.DateOnly), fmt.Sprintf("ec_%s.csv", date.Format("2006010215"))),
-		CompressionPath: filepath.Join(s.outputDir, fmt.Sprintf("%d", date.Year()), fmt.Sprintf("%02d", date.Month()), date.Format(time.DateOnly), fmt.Sprintf("ec_%s.zip", date.Format("2006010215"))),
+		InputPath:       s.getECPath(ctx, date),
+		OutputPath:      outputPath,
+		CompressionPath: compressionPath,
+		Stride:          s.stride,
+		Regions:         s.regions,
+		Target:          s.target,
+		Interval:        s.interval,
+		Derived:         s.derived,
+		Columns:         s.columns,
+		Units:           s.units,
+		Formats:         s.formats,
+		ParquetCodec:    s.codec,
+		Archive:         s.archive,
+		ArchiveLevel:    s.level,
+		Profiles:        profilesAt(s.profiles, values, s.publish),
+		Uploaders:       s.publish.uploaders(values),
+		DeleteUploaded:  s.publish.deleteUploaded(),
+		Checksum:        s.notify.enabled(),
+		GeoJSONThin:     s.thin,
+		Tiles:           s.tiles,
+		GeoTIFF:         s.geotiff,
+		Velocity:        s.velocity,
+	}
+
+	// EC 每 3 小时一个文件, 插值时需要下一个文件
+	if s.interval > 0 {
+		next := date.Add(time.Hour * 3)
+		info.Next = &nc.NCFile{DateTime: next, InputPath: s.getECPath(ctx, next)}
 	}
 
-	nc, err := nc.NewECOper(info)
+	// 对象存储中的输入文件在输出文件还没有全部生成时才下载
+	paths := []*string{&info.InputPath}
+	if info.Next != nil {
+		paths = append(paths, &info.Next.InputPath)
+	}
+	if err := s.inputs.localInputs(ctx, info, paths...); err != nil {
+		return fmt.Errorf("fetch ec input files failed: %v", err)
+	}
+
+	nc, err := nc.NewECOper(ctx, info)
 	if err != nil {
-		return fmt.Errorf("new ec oper failed: %v", err)
+		if generated(err) {
+			return nil
+		}
+		err = fmt.Errorf("new ec oper failed: %v", err)
+		s.notify.inputFailed(ctx, date, info.InputPath, err)
+		return err
 	}
 	defer nc.Close()
 
 	if err := nc.Analysis(); err != nil {
-		return fmt.Errorf("ec oper analysis failed: %v", err)
+		err = fmt.Errorf("ec oper analysis failed: %v", err)
+		s.notify.failed(ctx, date, err)
+		return err
 	}
 
-	if err := nc.GenerateCSV(); err != nil {
-		return fmt.Errorf("ec oper generate csv failed: %v", err)
+	// 部分文件失败时, 已经完成的文件照常通知, 下一轮不会重新生成
+	err = nc.GenerateCSV(ctx)
+	s.notify.ready(ctx, date, info.Results(), nil)
+	if err != nil {
+		err = fmt.Errorf("ec oper generate csv failed: %v", err)
+		s.notify.failed(ctx, date, err)
+		return err
 	}
 
 	return nil
@@ -84,3 +134,10 @@ func (s *ECServer) GenByDate(ctx context.Context, date time.Time) error {
 func (s *ECServer) Stop(ctx context.Context) error {
 	return nil
 }
+
+// /data2/alist_share/nc-files/ec_0p25/2025/2025-01-01/oper-00/ec_0p25_oper_2025010100_0h.nc
+// EC 每 12 小时起报一次, 每 3 小时一个文件, 找不到文件时由调用方检查
+func (s *ECServer) getECPath(ctx context.Context, date time.Time) string {
+	path, _ := s.inputs.find(ctx, s.input, layout.Values{Dataset: "ec", Time: date, Run: date.Truncate(time.Hour * 12)})
+	return path
+}
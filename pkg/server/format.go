@@ -0,0 +1,99 @@
+package server
+
+import (
+	"fmt"
+	"gen-meteo-file/pkg/config"
+	"gen-meteo-file/pkg/tools/nc"
+	"gen-meteo-file/pkg/tools/tile"
+	"path/filepath"
+)
+
+// outputFormats 根据配置中的格式名称生成输出格式, 为空时只输出 csv
+func outputFormats(names []string) ([]nc.Format, error) {
+	formats := make([]nc.Format, 0, len(names))
+	for _, name := range names {
+		formats = append(formats, nc.Format(name))
+	}
+
+	if err := nc.CheckFormats(formats); err != nil {
+		return nil, err
+	}
+
+	return formats, nil
+}
+
+// parquetCodec parquet 的压缩方式, 所有数据源共用
+func parquetCodec() (nc.ParquetCodec, error) {
+	codec := nc.ParquetCodec(config.Get().Output.ParquetCodec)
+	if err := nc.CheckParquetCodec(codec); err != nil {
+		return "", fmt.Errorf("check parquet codec failed: %v", err)
+	}
+
+	return codec, nil
+}
+
+// archiveCodec csv 的打包方式和压缩级别, 所有数据源共用
+func archiveCodec() (nc.ArchiveCodec, int, error) {
+	codec, level := nc.ArchiveCodec(config.Get().Output.ArchiveCodec), config.Get().Output.ArchiveLevel
+	if err := nc.CheckArchive(codec, level); err != nil {
+		return "", 0, fmt.Errorf("check archive codec failed: %v", err)
+	}
+
+	return codec, level, nil
+}
+
+// tileOptions 数据源的瓦片配置, 未开启时返回空, 瓦片输出到 CSV_DIR/tiles/{dataset}
+func tileOptions(dataset string, c config.Tiles) (*nc.Tiles, error) {
+	if !c.Enable {
+		return nil, nil
+	}
+
+	ramp, err := tile.ParseRamp(c.Ramp)
+	if err != nil {
+		return nil, fmt.Errorf("parse tile ramp failed: %v", err)
+	}
+
+	encoding := tile.Encoding(c.Encoding)
+	if encoding != tile.ColorEncoding && encoding != tile.ValueEncoding {
+		return nil, fmt.Errorf("tile encoding: %s not supported", c.Encoding)
+	}
+
+	output := config.Get().Output
+	if output.TileMinZoom < 0 || output.TileMaxZoom < output.TileMinZoom {
+		return nil, fmt.Errorf("tile zoom: %d ~ %d is invalid", output.TileMinZoom, output.TileMaxZoom)
+	}
+
+	return &nc.Tiles{
+		Dir:      filepath.Join(config.Get().Server.CSVDir, "tiles", dataset),
+		MinZoom:  output.TileMinZoom,
+		MaxZoom:  output.TileMaxZoom,
+		Ramp:     ramp,
+		Encoding: encoding,
+	}, nil
+}
+
+// velocityOptions 数据源的 velocity JSON 配置, 未开启时返回空, 输出到 CSV_DIR/velocity/{dataset}
+func velocityOptions(dataset string, c config.Velocity) (*nc.Velocity, error) {
+	if !c.Enable {
+		return nil, nil
+	}
+
+	if c.Resolution < 0 {
+		return nil, fmt.Errorf("velocity resolution: %v must not be less than 0", c.Resolution)
+	}
+
+	var region nc.Region
+	if c.Region != "" {
+		regions, err := lookupRegions([]string{c.Region})
+		if err != nil {
+			return nil, fmt.Errorf("lookup velocity region failed: %v", err)
+		}
+		region = regions[0]
+	}
+
+	return &nc.Velocity{
+		Dir:        filepath.Join(config.Get().Server.CSVDir, "velocity", dataset),
+		Resolution: c.Resolution,
+		Region:     region,
+	}, nil
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"gen-meteo-file/pkg/config"
+	"gen-meteo-file/pkg/tools/layout"
+	"gen-meteo-file/pkg/tools/nc"
+	"gen-meteo-file/pkg/tools/objstore"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// s3Scheme NC_DIR 以 s3:// 开头时从对象存储读取输入文件
+const s3Scheme = "s3://"
+
+// inputSource 输入文件的根目录, 本地目录或者对象存储中的 s3://bucket/prefix
+// 对象存储中的文件下载到本地缓存后再解码, ETag 没有变化时使用缓存
+type inputSource struct {
+	dir       string
+	client    *objstore.Client // 本地目录时为空
+	bucket    string
+	prefix    string
+	cacheDir  string
+	cacheDays int
+}
+
+func newInputSource(dir string) (*inputSource, error) {
+	if !strings.HasPrefix(dir, s3Scheme) {
+		return &inputSource{dir: dir}, nil
+	}
+
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(dir, s3Scheme), "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("input dir: %s has no bucket", dir)
+	}
+
+	c := config.Get().S3
+	if c.CacheDays < 0 {
+		return nil, fmt.Errorf("s3 cache days: %d must not be negative", c.CacheDays)
+	}
+
+	client, err := objstore.New(objstore.Config{
+		Endpoint:  c.Endpoint,
+		AccessKey: c.AccessKey,
+		SecretKey: c.SecretKey,
+		Region:    c.Region,
+		Secure:    c.Secure,
+		Retries:   c.Retries,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cacheDir := c.CacheDir
+	if cacheDir == "" {
+		cacheDir = filepath.Join(os.TempDir(), "gen-meteo-file")
+	}
+
+	return &inputSource{
+		dir:       dir,
+		client:    client,
+		bucket:    bucket,
+		prefix:    strings.Trim(prefix, "/"),
+		cacheDir:  cacheDir,
+		cacheDays: c.CacheDays,
+	}, nil
+}
+
+// find 按照模板查找输入文件, 返回本地路径或者对象的位置 s3://bucket/key, 不下载对象
+// 输出文件还没有全部生成时再由 localInputs 下载, 找不到文件时同样返回路径, 由调用方检查文件是否存在
+func (s *inputSource) find(ctx context.Context, t *layout.Template, v layout.Values) (string, error) {
+	if s.client == nil {
+		return findInput(s.dir, t, v)
+	}
+
+	pattern := path.Join(s.prefix, t.Execute(v))
+	location := s3Scheme + path.Join(s.bucket, pattern)
+
+	key := pattern
+	if i := strings.IndexAny(pattern, "*?["); i >= 0 {
+		// 只列出通配符之前的前缀, 按照字典序使用第一个匹配的对象
+		keys, err := s.client.List(ctx, s.bucket, pattern[:i])
+		if err != nil {
+			return location, fmt.Errorf("list %s file: %s failed: %v", v.Dataset, location, err)
+		}
+
+		key = ""
+		for _, k := range keys {
+			if ok, err := path.Match(pattern, k); err != nil {
+				return location, fmt.Errorf("match %s file: %s failed: %v", v.Dataset, location, err)
+			} else if ok {
+				key = k
+				break
+			}
+		}
+
+		if key == "" {
+			return location, fmt.Errorf("%s file not found: %s", v.Dataset, location)
+		}
+	}
+
+	location = s3Scheme + path.Join(s.bucket, key)
+	if _, _, err := s.client.Stat(ctx, s.bucket, key); err == objstore.ErrNotFound {
+		return location, fmt.Errorf("%s file not found: %s", v.Dataset, location)
+	} else if err != nil {
+		return location, fmt.Errorf("stat %s file failed: %v", v.Dataset, err)
+	}
+
+	return location, nil
+}
+
+// localInputs 输出文件还没有全部生成时, 将 paths 中对象存储的输入文件下载到本地缓存, 并替换为本地路径
+// info.Locations 记录本地路径对应的对象位置, 找不到的对象保持原样, 由调用方检查文件是否存在
+func (s *inputSource) localInputs(ctx context.Context, info *nc.NCFile, paths ...*string) error {
+	if s.client == nil || info.Generated(ctx) {
+		return nil
+	}
+
+	for _, p := range paths {
+		if !strings.HasPrefix(*p, s3Scheme) {
+			continue
+		}
+
+		local, err := s.fetch(ctx, *p)
+		if err == objstore.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("fetch input file: %s failed: %v", *p, err)
+		}
+
+		if info.Locations == nil {
+			info.Locations = make(map[string]string)
+		}
+		info.Locations[local] = *p
+		*p = local
+	}
+
+	return nil
+}
+
+// fetch 下载 s3://bucket/key 到 {cacheDir}/{bucket}/{key}, 下载后在 .etag 文件中记录 ETag, ETag 没有变化时直接使用缓存
+func (s *inputSource) fetch(ctx context.Context, location string) (string, error) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(location, s3Scheme), "/")
+	if bucket != s.bucket {
+		return "", fmt.Errorf("bucket: %s does not match input bucket: %s", bucket, s.bucket)
+	}
+
+	etag, _, err := s.client.Stat(ctx, s.bucket, key)
+	if err != nil {
+		return "", err
+	}
+
+	local := filepath.Join(s.cacheDir, s.bucket, filepath.FromSlash(key))
+	if cached, err := os.ReadFile(local + ".etag"); err == nil && string(cached) == etag {
+		if _, err := os.Stat(local); err == nil {
+			now := time.Now()
+			os.Chtimes(local, now, now)
+			return local, nil
+		}
+	}
+
+	if etag, err = s.client.Download(ctx, s.bucket, key, local); err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(local+".etag", []byte(etag), os.FileMode(0644)); err != nil {
+		return "", fmt.Errorf("write etag file: %s failed: %v", local+".etag", err)
+	}
+
+	logrus.Infof("download input file: s3://%s/%s to %s success", s.bucket, key, local)
+	s.prune()
+
+	return local, nil
+}
+
+// prune 删除超过 cacheDays 天没有使用的缓存文件, 0 表示不删除
+func (s *inputSource) prune() {
+	if s.cacheDays == 0 {
+		return
+	}
+
+	expired := time.Now().AddDate(0, 0, -s.cacheDays)
+	filepath.Walk(filepath.Join(s.cacheDir, s.bucket), func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || strings.HasSuffix(path, ".etag") || info.ModTime().After(expired) {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			logrus.Warnf("remove expired cache file: %s failed: %v", path, err)
+			return nil
+		}
+		os.Remove(path + ".etag")
+
+		return nil
+	})
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"fmt"
+	"gen-meteo-file/pkg/tools/layout"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// inputLayout 解析输入文件的路径模板, 模板相对 NC_DIR, 可以使用通配符
+func inputLayout(raw string) (*layout.Template, error) {
+	t, err := layout.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.IsAbs(raw) {
+		return nil, fmt.Errorf("input layout: %s must be a relative path", raw)
+	}
+
+	return t, nil
+}
+
+// outputLayout 解析输出文件的路径模板, 模板相对输出的根目录, 必须以 .{ext} 结尾
+func outputLayout(raw string) (*layout.Template, error) {
+	t, err := layout.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.IsAbs(raw) {
+		return nil, fmt.Errorf("output layout: %s must be a relative path", raw)
+	}
+
+	if !t.HasSuffix(".", "ext") {
+		return nil, fmt.Errorf("output layout: %s must end with .{ext}", raw)
+	}
+
+	return t, nil
+}
+
+// outputPaths 按照模板生成 csv 和压缩包的路径, 压缩包的扩展名由打包方式决定
+func outputPaths(dir string, t *layout.Template, v layout.Values) (string, string) {
+	v.Ext = "csv"
+	output := filepath.Join(dir, t.Execute(v))
+
+	v.Ext = "zip"
+	compression := filepath.Join(dir, t.Execute(v))
+
+	return output, compression
+}
+
+// findInput 按照模板查找输入文件, 模板中有通配符时返回排序后第一个匹配的文件
+// 没有通配符时直接返回路径, 由调用方检查文件是否存在
+func findInput(dir string, t *layout.Template, v layout.Values) (string, error) {
+	path := filepath.Join(dir, t.Execute(v))
+	if !strings.ContainsAny(path, "*?[") {
+		return path, nil
+	}
+
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return path, fmt.Errorf("glob %s file: %s failed: %v", v.Dataset, path, err)
+	}
+
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && !info.IsDir() {
+			return match, nil
+		}
+	}
+
+	return path, fmt.Errorf("%s file not found: %s", v.Dataset, path)
+}
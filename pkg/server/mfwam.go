@@ -4,25 +4,30 @@ import (
 	"context"
 	"fmt"
 	"gen-meteo-file/pkg/config"
+	"gen-meteo-file/pkg/tools/layout"
 	"gen-meteo-file/pkg/tools/nc"
-	"os"
-	"path/filepath"
-	"strings"
+	"gen-meteo-file/pkg/tools/notify"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 type MFWAMServer struct {
-	inputDir  string
-	outputDir string
+	datasetOptions
 }
 
-func NewMFWAMServer() *MFWAMServer {
-	return &MFWAMServer{
-		inputDir:  filepath.Join(config.Get().Server.NCDir, "mfwam"),
-		outputDir: filepath.Join(config.Get().Server.CSVDir),
+func NewMFWAMServer(broker notify.Broker) (*MFWAMServer, error) {
+	opts, err := newDatasetOptions("mfwam", config.Get().MFWAM, broker)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &MFWAMServer{datasetOptions: opts}
+	if s.profiles, err = s.outputProfiles("mfwam"); err != nil {
+		return nil, fmt.Errorf("mfwam output profiles failed: %v", err)
 	}
+
+	return s, nil
 }
 
 func (s *MFWAMServer) Start(ctx context.Context) error {
@@ -53,30 +58,79 @@ func (s *MFWAMServer) Start(ctx context.Context) error {
 }
 
 func (s *MFWAMServer) GenByDate(ctx context.Context, date time.Time) error {
-	path, err := s.getMFWAMPath(date)
+	path, err := s.getMFWAMPath(ctx, date)
 	if err != nil {
 		return fmt.Errorf("get mfwam path failed: %v", err)
 	}
 
+	values := layout.Values{Dataset: "mfwam", Time: date}
+	outputPath, compressionPath := outputPaths(s.outputDir, s.output, values)
+
 	info := &nc.NCFile{
 		DateTime:        date,
 		InputPath:       path,
-		OutputPath:      filepath.Join(s.outputDir, fmt.Sprintf("%d", date.Year()), fmt.Sprintf("%02d", date.Month()), date.Format(time.DateOnly), fmt.Sprintf("mfwam_%s.csv", date.Format("2006010215"))),
-		CompressionPath: filepath.Join(s.outputDir, fmt.Sprintf("%d", date.Year()), fmt.Sprintf("%02d", date.Month()), date.Format(time.DateOnly), fmt.Sprintf("mfwam_%s.zip", date.Format("2006010215"))),
+		OutputPath:      outputPath,
+		CompressionPath: compressionPath,
+		Stride:          s.stride,
+		Regions:         s.regions,
+		Target:          s.target,
+		Interval:        s.interval,
+		Derived:         s.derived,
+		Columns:         s.columns,
+		Units:           s.units,
+		Formats:         s.formats,
+		ParquetCodec:    s.codec,
+		Archive:         s.archive,
+		ArchiveLevel:    s.level,
+		Profiles:        profilesAt(s.profiles, values, s.publish),
+		Uploaders:       s.publish.uploaders(values),
+		DeleteUploaded:  s.publish.deleteUploaded(),
+		Checksum:        s.notify.enabled(),
+		GeoJSONThin:     s.thin,
+		Tiles:           s.tiles,
+		GeoTIFF:         s.geotiff,
 	}
 
-	nc, err := nc.NewMFWAM(info)
+	// MFWAM 每 12 小时一个文件, 插值时需要下一个文件, 找不到时只在当前文件的时次之间插值, 文件到达后重新生成
+	if s.interval > 0 {
+		next := date.Add(time.Hour * 12)
+		path, _ := s.getMFWAMPath(ctx, next)
+		info.Next = &nc.NCFile{DateTime: next, InputPath: path}
+	}
+
+	// 对象存储中的输入文件在输出文件还没有全部生成时才下载
+	paths := []*string{&info.InputPath}
+	if info.Next != nil {
+		paths = append(paths, &info.Next.InputPath)
+	}
+	if err := s.inputs.localInputs(ctx, info, paths...); err != nil {
+		return fmt.Errorf("fetch mfwam input files failed: %v", err)
+	}
+
+	nc, err := nc.NewMFWAM(ctx, info)
 	if err != nil {
-		return fmt.Errorf("new mfwam failed: %v", err)
+		if generated(err) {
+			return nil
+		}
+		err = fmt.Errorf("new mfwam failed: %v", err)
+		s.notify.inputFailed(ctx, date, info.InputPath, err)
+		return err
 	}
 	defer nc.Close()
 
 	if err := nc.Analysis(); err != nil {
-		return fmt.Errorf("mfwam analysis failed: %v", err)
+		err = fmt.Errorf("mfwam analysis failed: %v", err)
+		s.notify.failed(ctx, date, err)
+		return err
 	}
 
-	if err := nc.GenerateCSV(); err != nil {
-		return fmt.Errorf("mfwam generate csv failed: %v", err)
+	// 部分文件失败时, 已经完成的文件照常通知, 下一轮不会重新生成
+	err = nc.GenerateCSV(ctx)
+	s.notify.ready(ctx, date, info.Results(), nil)
+	if err != nil {
+		err = fmt.Errorf("mfwam generate csv failed: %v", err)
+		s.notify.failed(ctx, date, err)
+		return err
 	}
 
 	return nil
@@ -86,22 +140,6 @@ func (s *MFWAMServer) Stop(ctx context.Context) error {
 	return nil
 }
 
-func (s *MFWAMServer) getMFWAMPath(date time.Time) (string, error) {
-	dir := filepath.Join(s.inputDir, fmt.Sprintf("%d", date.Year()), fmt.Sprintf("%02d", date.Month()))
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return "", fmt.Errorf("read mfwam dir: %s failed: %v", dir, err)
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		if strings.Contains(entry.Name(), date.Format("2006010215")) {
-			return filepath.Join(dir, entry.Name()), nil
-		}
-	}
-
-	return "", fmt.Errorf("mfwam file not found: %s", date.Format("2006010215"))
+func (s *MFWAMServer) getMFWAMPath(ctx context.Context, date time.Time) (string, error) {
+	return s.inputs.find(ctx, s.input, layout.Values{Dataset: "mfwam", Time: date})
 }
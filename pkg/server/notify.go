@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"gen-meteo-file/pkg/config"
+	"gen-meteo-file/pkg/tools/nc"
+	"gen-meteo-file/pkg/tools/notify"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// notifier 起报时次的输出文件完成或者生成失败后通知 Webhook 和消息中间件, 通知失败只记录日志, 不影响生成
+type notifier struct {
+	dataset    string
+	publishers []notify.Publisher
+}
+
+// newNotifier broker 为进程内共用的消息中间件客户端, 为空时只通知 Webhook
+func newNotifier(dataset string, broker notify.Broker) (*notifier, error) {
+	c := config.Get().Webhook
+
+	deadLetter := c.DeadLetter
+	if deadLetter == "" {
+		deadLetter = filepath.Join(config.Get().Server.CSVDir, ".webhook", "dead_letter.jsonl")
+	}
+
+	n := &notifier{dataset: dataset}
+	names := make(map[string]bool, len(c.Targets))
+	for _, t := range c.Targets {
+		if names[t.Name] {
+			return nil, fmt.Errorf("webhook: %s is duplicated", t.Name)
+		}
+		names[t.Name] = true
+
+		if len(t.Datasets) > 0 && !slices.Contains(t.Datasets, dataset) {
+			continue
+		}
+
+		client, err := notify.NewWebhook(notify.Webhook{
+			Name:       t.Name,
+			URL:        t.URL,
+			Secret:     t.Secret,
+			Retries:    c.Retries,
+			Timeout:    time.Duration(c.Timeout) * time.Second,
+			DeadLetter: deadLetter,
+		})
+		if err != nil {
+			return nil, err
+		}
+		n.publishers = append(n.publishers, client)
+	}
+
+	if broker != nil {
+		n.publishers = append(n.publishers, broker)
+	}
+
+	return n, nil
+}
+
+// NewBroker 消息中间件的客户端, 所有数据集共用一个连接, 未配置时返回空, 由调用方在所有服务停止后关闭
+func NewBroker() (notify.Broker, error) {
+	c := config.Get().Broker
+	timeout := time.Duration(c.Timeout) * time.Second
+
+	switch c.Type {
+	case "":
+		return nil, nil
+	case "nats":
+		client, err := notify.NewNATS(notify.NATS{URL: c.NATS.URL, Subject: c.NATS.Subject, Timeout: timeout})
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case "redis":
+		client, err := notify.NewRedis(notify.Redis{
+			Addr:     c.Redis.Addr,
+			Password: c.Redis.Password,
+			DB:       c.Redis.DB,
+			Stream:   c.Redis.Stream,
+			MaxLen:   c.Redis.MaxLen,
+			Timeout:  timeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	default:
+		return nil, fmt.Errorf("broker type: %s is not supported", c.Type)
+	}
+}
+
+// enabled 是否有通知目标, 没有时不需要计算输出文件的校验值
+func (n *notifier) enabled() bool {
+	return len(n.publishers) > 0
+}
+
+// ready 通知这次生成完成的输出文件, 没有新文件时不通知, missing 为合并输出缺失的数据源及原因
+// 上传失败的文件不在结果中, 下一轮补传成功后再通知
+func (n *notifier) ready(ctx context.Context, cycle time.Time, results []nc.Result, missing map[string]string) {
+	if len(n.publishers) == 0 || len(results) == 0 {
+		return
+	}
+
+	e := notify.NewEvent(notify.ProductReady, n.dataset, cycle)
+	e.Missing = missing
+	for _, r := range results {
+		e.Files = append(e.Files, notify.File{
+			Path:      r.Path,
+			Locations: r.Locations,
+			Profile:   r.Profile,
+			Format:    string(r.Format),
+			Region:    r.Region,
+			Size:      r.Size,
+			SHA256:    r.SHA256,
+		})
+
+		for _, t := range r.ValidTimes {
+			if !slices.ContainsFunc(e.ValidTimes, t.Equal) {
+				e.ValidTimes = append(e.ValidTimes, t.UTC())
+			}
+		}
+	}
+	slices.SortFunc(e.ValidTimes, time.Time.Compare)
+
+	n.publish(ctx, e)
+}
+
+// failed 通知生成失败, 只用于输入文件存在的起报时次, 还没有输入文件的起报时次不通知
+func (n *notifier) failed(ctx context.Context, cycle time.Time, cause error) {
+	if len(n.publishers) == 0 {
+		return
+	}
+
+	e := notify.NewEvent(notify.ProductFailed, n.dataset, cycle)
+	e.Error = cause.Error()
+
+	n.publish(ctx, e)
+}
+
+// generated 起报时次的输出文件已经全部生成, 跳过且不通知
+func generated(err error) bool {
+	return err == nc.ErrGenerated
+}
+
+// noInputs 合并输出的数据源都还没有到达, 与缺少输入文件的其他数据集一样不通知
+func noInputs(err error) bool {
+	return err == nc.ErrNoCombinedInputs
+}
+
+// inputFailed 打开输入文件失败时, 只有输入文件存在才通知, 例如: 文件损坏
+func (n *notifier) inputFailed(ctx context.Context, cycle time.Time, input string, cause error) {
+	if _, err := os.Stat(input); err == nil {
+		n.failed(ctx, cycle, cause)
+	}
+}
+
+func (n *notifier) publish(ctx context.Context, e notify.Event) {
+	for _, p := range n.publishers {
+		if err := p.Publish(ctx, e); err != nil {
+			logrus.Errorf("notify %s event: %s failed: %v", n.dataset, e.Type, err)
+			continue
+		}
+
+		logrus.Infof("notify %s event: %s, cycle: %v, files: %d success", n.dataset, e.Type, e.Cycle, len(e.Files))
+	}
+}
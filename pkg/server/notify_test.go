@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"gen-meteo-file/pkg/config"
+	"gen-meteo-file/pkg/tools/layout"
+	"gen-meteo-file/pkg/tools/notify"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// recordPublisher 记录发送的事件
+type recordPublisher struct {
+	events []notify.Event
+}
+
+func (p *recordPublisher) Publish(ctx context.Context, e notify.Event) error {
+	p.events = append(p.events, e)
+	return nil
+}
+
+// newTestECServer 输入和输出目录为临时目录, 事件发送到 recordPublisher
+func newTestECServer(t *testing.T) (*ECServer, *recordPublisher) {
+	t.Helper()
+
+	t.Setenv("NC_DIR", t.TempDir())
+	t.Setenv("CSV_DIR", t.TempDir())
+	if _, err := config.New(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewECServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &recordPublisher{}
+	s.notify.publishers = []notify.Publisher{p}
+
+	return s, p
+}
+
+func writeTestFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenByDateGeneratedNoEvent(t *testing.T) {
+	s, p := newTestECServer(t)
+	ctx := context.Background()
+	date := time.Date(2025, 6, 13, 0, 0, 0, 0, time.UTC)
+
+	// 输出文件已经生成, 输入文件不需要解码
+	writeTestFile(t, s.getECPath(ctx, date), []byte("not netcdf"))
+	_, compressionPath := outputPaths(s.outputDir, s.output, layout.Values{Dataset: "ec", Time: date})
+	writeTestFile(t, compressionPath, []byte("zip"))
+
+	if err := s.GenByDate(ctx, date); err != nil {
+		t.Fatalf("GenByDate() of generated cycle error = %v", err)
+	}
+	if len(p.events) != 0 {
+		t.Errorf("events = %+v, want none", p.events)
+	}
+}
+
+func TestGenByDateInputFailed(t *testing.T) {
+	s, p := newTestECServer(t)
+	ctx := context.Background()
+	date := time.Date(2025, 6, 13, 0, 0, 0, 0, time.UTC)
+
+	// 输入文件缺失时不通知
+	if err := s.GenByDate(ctx, date); err == nil {
+		t.Fatalf("GenByDate() without input file succeeded")
+	}
+	if len(p.events) != 0 {
+		t.Fatalf("events = %+v, want none", p.events)
+	}
+
+	// 输入文件损坏时通知失败
+	writeTestFile(t, s.getECPath(ctx, date), []byte("not netcdf"))
+
+	if err := s.GenByDate(ctx, date); err == nil {
+		t.Fatalf("GenByDate() of corrupt input file succeeded")
+	}
+	if len(p.events) != 1 || p.events[0].Type != notify.ProductFailed || !p.events[0].Cycle.Equal(date) || p.events[0].Error == "" {
+		t.Errorf("events = %+v, want one %s", p.events, notify.ProductFailed)
+	}
+}
+
+func TestSharedBroker(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	t.Setenv("NC_DIR", t.TempDir())
+	t.Setenv("CSV_DIR", t.TempDir())
+	t.Setenv("BROKER_TYPE", "redis")
+	t.Setenv("REDIS_ADDR", mr.Addr())
+	t.Setenv("REDIS_STREAM", "meteo:events")
+	if _, err := config.New(); err != nil {
+		t.Fatal(err)
+	}
+
+	broker, err := NewBroker()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ec, err := NewECServer(broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfwam, err := NewMFWAMServer(broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	date := time.Date(2025, 6, 13, 0, 0, 0, 0, time.UTC)
+	ec.notify.failed(ctx, date, fmt.Errorf("decode failed"))
+	mfwam.notify.failed(ctx, date, fmt.Errorf("decode failed"))
+
+	// 两个数据集的事件通过同一个连接发送
+	entries, err := mr.Stream("meteo:events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("stream entries = %d, want 2", len(entries))
+	}
+	if n := mr.TotalConnectionCount(); n != 1 {
+		t.Errorf("redis connections = %d, want 1", n)
+	}
+
+	// 服务停止时不关闭共用的连接
+	if err := ec.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mfwam.notify.failed(ctx, date, fmt.Errorf("decode failed"))
+	if entries, _ := mr.Stream("meteo:events"); len(entries) != 3 {
+		t.Errorf("stream entries after ec stopped = %d, want 3", len(entries))
+	}
+
+	if err := broker.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
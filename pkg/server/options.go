@@ -0,0 +1,144 @@
+package server
+
+import (
+	"fmt"
+	"gen-meteo-file/pkg/config"
+	"gen-meteo-file/pkg/tools/layout"
+	"gen-meteo-file/pkg/tools/nc"
+	"gen-meteo-file/pkg/tools/notify"
+	"gen-meteo-file/pkg/tools/regrid"
+	"path/filepath"
+	"time"
+)
+
+// datasetOptions 数据源的输入, 输出, 上传和通知配置, 启动时检查, ec, mfwam, smoc 和 combined 共用
+type datasetOptions struct {
+	inputs    *inputSource // combined 使用各个数据源的输入文件, 为空
+	outputDir string
+	input     *layout.Template // combined 为空
+	output    *layout.Template
+	stride    nc.Stride
+	regions   []nc.Region
+	target    *regrid.Target
+	interval  time.Duration
+	derived   []string
+	columns   []string
+	units     nc.UnitProfile
+	formats   []nc.Format
+	codec     nc.ParquetCodec
+	archive   nc.ArchiveCodec
+	level     int
+	thin      int
+	tiles     *nc.Tiles
+	geotiff   []string
+	velocity  *nc.Velocity
+	profiles  []outputProfile
+	publish   *publisher
+	notify    *notifier
+}
+
+// newDatasetOptions 检查数据源的配置并生成选项, broker 为进程内共用的消息中间件客户端
+func newDatasetOptions(dataset string, c config.Dataset, broker notify.Broker) (datasetOptions, error) {
+	o := datasetOptions{
+		outputDir: filepath.Join(config.Get().Server.CSVDir),
+		stride:    nc.Stride{Lat: c.LatStride, Lon: c.LonStride, Time: c.TimeStride},
+		derived:   c.Derived,
+		columns:   c.Columns,
+		units:     nc.UnitProfile(c.Units),
+		thin:      c.GeoJSONThin,
+		geotiff:   c.GeoTIFF,
+	}
+
+	var err error
+	if o.regions, err = lookupRegions(c.Regions); err != nil {
+		return o, fmt.Errorf("lookup %s regions failed: %v", dataset, err)
+	}
+
+	if o.target, err = regridTarget(); err != nil {
+		return o, fmt.Errorf("%s regrid target failed: %v", dataset, err)
+	}
+
+	if o.interval, err = timeInterval(c.TimeInterval); err != nil {
+		return o, fmt.Errorf("%s time interval failed: %v", dataset, err)
+	}
+
+	if len(c.Derived) > 0 {
+		if err := nc.CheckDerived(dataset, c.Derived); err != nil {
+			return o, fmt.Errorf("%s derived columns failed: %v", dataset, err)
+		}
+	}
+
+	// 输出列在启动时检查, 不在生成时才发现拼写错误
+	if len(c.Columns) > 0 {
+		if err := nc.CheckColumns(dataset, c.Derived, c.Columns); err != nil {
+			return o, fmt.Errorf("%s output columns failed: %v", dataset, err)
+		}
+	}
+
+	if len(c.GeoTIFF) > 0 {
+		if err := nc.CheckGeoTIFF(dataset, c.GeoTIFF); err != nil {
+			return o, fmt.Errorf("%s geotiff variables failed: %v", dataset, err)
+		}
+	}
+
+	if err := nc.CheckUnits(o.units); err != nil {
+		return o, fmt.Errorf("%s unit profile failed: %v", dataset, err)
+	}
+
+	if o.formats, err = outputFormats(c.Formats); err != nil {
+		return o, fmt.Errorf("%s output formats failed: %v", dataset, err)
+	}
+
+	if o.codec, err = parquetCodec(); err != nil {
+		return o, fmt.Errorf("%s parquet codec failed: %v", dataset, err)
+	}
+
+	if o.archive, o.level, err = archiveCodec(); err != nil {
+		return o, fmt.Errorf("%s archive codec failed: %v", dataset, err)
+	}
+
+	if o.tiles, err = tileOptions(dataset, c.Tiles); err != nil {
+		return o, fmt.Errorf("%s tiles failed: %v", dataset, err)
+	}
+
+	if o.velocity, err = velocityOptions(dataset, c.Velocity); err != nil {
+		return o, fmt.Errorf("%s velocity failed: %v", dataset, err)
+	}
+
+	if dataset != "combined" {
+		if o.inputs, err = newInputSource(config.Get().Server.NCDir); err != nil {
+			return o, fmt.Errorf("%s input source failed: %v", dataset, err)
+		}
+
+		if o.input, err = inputLayout(c.InputLayout); err != nil {
+			return o, fmt.Errorf("%s input layout failed: %v", dataset, err)
+		}
+	}
+
+	if o.output, err = outputLayout(c.OutputLayout); err != nil {
+		return o, fmt.Errorf("%s output layout failed: %v", dataset, err)
+	}
+
+	if o.publish, err = newPublisher(dataset); err != nil {
+		return o, fmt.Errorf("%s publisher failed: %v", dataset, err)
+	}
+
+	if o.notify, err = newNotifier(dataset, broker); err != nil {
+		return o, fmt.Errorf("%s notifier failed: %v", dataset, err)
+	}
+
+	return o, nil
+}
+
+// outputProfiles 数据源适用的输出方案, 方案中为空的配置使用数据源自身的配置
+func (o *datasetOptions) outputProfiles(dataset string) ([]outputProfile, error) {
+	return outputProfiles(dataset, o.derived, nc.Profile{
+		Stride:       o.stride,
+		Regions:      o.regions,
+		Columns:      o.columns,
+		Units:        o.units,
+		Formats:      o.formats,
+		Archive:      o.archive,
+		ArchiveLevel: o.level,
+	}, o.output)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"gen-meteo-file/pkg/config"
+	"strings"
+	"testing"
+)
+
+func TestDatasetOptionsColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "dataset columns", env: map[string]string{"EC_COLUMNS": "wind10mU,wind10mV"}},
+		{name: "derived column", env: map[string]string{"EC_DERIVED": "windSpeed", "EC_COLUMNS": "windSpeed,temperature2m"}},
+		{name: "typo", env: map[string]string{"EC_COLUMNS": "wind10mU,temperature"}, wantErr: "ec output columns failed: output column: temperature not found in ec"},
+		{name: "derived column not configured", env: map[string]string{"EC_COLUMNS": "windSpeed"}, wantErr: "windSpeed is a derived column"},
+		{name: "column of another dataset", env: map[string]string{"EC_COLUMNS": "seaWaveHeight"}, wantErr: "not found in ec"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NC_DIR", t.TempDir())
+			t.Setenv("CSV_DIR", t.TempDir())
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if _, err := config.New(); err != nil {
+				t.Fatal(err)
+			}
+
+			// 启动时检查数据源的输出列, 不用等到生成时
+			_, err := NewECServer(nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewECServer() error = %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewECServer() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCombinedOptions(t *testing.T) {
+	t.Setenv("NC_DIR", t.TempDir())
+	t.Setenv("CSV_DIR", t.TempDir())
+	t.Setenv("COMBINED_COLUMNS", "wind10mU,seaWaveHeight")
+	if _, err := config.New(); err != nil {
+		t.Fatal(err)
+	}
+
+	ec, err := NewECServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfwam, err := NewMFWAMServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smoc, err := NewSMOCSever(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 合并输出可以使用所有数据源的列, 未配置插值时使用默认网格, 没有自己的输入文件
+	s, err := NewCombinedServer(ec, mfwam, smoc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.target == nil || s.inputs != nil || s.input != nil {
+		t.Errorf("combined target = %v, inputs = %v, input = %v", s.target, s.inputs, s.input)
+	}
+
+	t.Setenv("COMBINED_COLUMNS", "wind10mU,waveHeight")
+	if _, err := config.New(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCombinedServer(ec, mfwam, smoc, nil); err == nil || !strings.Contains(err.Error(), "combined output columns failed") {
+		t.Errorf("NewCombinedServer() error = %v, want output columns failed", err)
+	}
+}
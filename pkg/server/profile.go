@@ -0,0 +1,124 @@
+package server
+
+import (
+	"fmt"
+	"gen-meteo-file/pkg/config"
+	"gen-meteo-file/pkg/tools/layout"
+	"gen-meteo-file/pkg/tools/nc"
+	"slices"
+)
+
+// outputProfile 数据源的一个输出方案, 输出路径按照起报时次生成
+type outputProfile struct {
+	name    string
+	dir     string
+	layout  *layout.Template
+	profile nc.Profile
+}
+
+// outputProfiles 适用于数据源的输出方案, 方案中为空的配置使用 base 和 output 中数据源自身的配置
+// derived 为数据源配置的派生列, 方案中的输出列只能是数据源的列或者这些派生列
+func outputProfiles(dataset string, derived []string, base nc.Profile, output *layout.Template) ([]outputProfile, error) {
+	profiles := make([]outputProfile, 0, len(config.Get().Profiles))
+	names := make(map[string]bool, len(config.Get().Profiles))
+	for _, c := range config.Get().Profiles {
+		if names[c.Name] {
+			return nil, fmt.Errorf("output profile: %s is duplicated", c.Name)
+		}
+		names[c.Name] = true
+
+		if len(c.Datasets) > 0 && !slices.Contains(c.Datasets, dataset) {
+			continue
+		}
+
+		profile, err := newOutputProfile(c, dataset, derived, base, output)
+		if err != nil {
+			return nil, fmt.Errorf("output profile: %s: %v", c.Name, err)
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, nil
+}
+
+func newOutputProfile(c config.Profile, dataset string, derived []string, base nc.Profile, output *layout.Template) (outputProfile, error) {
+	if c.Dir == "" {
+		return outputProfile{}, fmt.Errorf("dir is empty")
+	}
+
+	if c.Layout != "" {
+		t, err := outputLayout(c.Layout)
+		if err != nil {
+			return outputProfile{}, err
+		}
+		output = t
+	}
+
+	profile := base
+
+	if len(c.Regions) > 0 {
+		regions, err := lookupRegions(c.Regions)
+		if err != nil {
+			return outputProfile{}, fmt.Errorf("lookup regions failed: %v", err)
+		}
+		profile.Regions = regions
+	}
+
+	if len(c.Columns) > 0 {
+		if err := nc.CheckColumns(dataset, derived, c.Columns); err != nil {
+			return outputProfile{}, fmt.Errorf("output columns failed: %v", err)
+		}
+		profile.Columns = c.Columns
+	}
+
+	if c.Units != "" {
+		profile.Units = nc.UnitProfile(c.Units)
+		if err := nc.CheckUnits(profile.Units); err != nil {
+			return outputProfile{}, fmt.Errorf("unit profile failed: %v", err)
+		}
+	}
+
+	if len(c.Formats) > 0 {
+		formats, err := outputFormats(c.Formats)
+		if err != nil {
+			return outputProfile{}, fmt.Errorf("output formats failed: %v", err)
+		}
+		profile.Formats = formats
+	}
+
+	if c.LatStride > 0 {
+		profile.Stride.Lat = c.LatStride
+	}
+	if c.LonStride > 0 {
+		profile.Stride.Lon = c.LonStride
+	}
+	if c.TimeStride > 0 {
+		profile.Stride.Time = c.TimeStride
+	}
+
+	if c.ArchiveCodec != "" {
+		profile.Archive, profile.ArchiveLevel = nc.ArchiveCodec(c.ArchiveCodec), c.ArchiveLevel
+		if err := nc.CheckArchive(profile.Archive, profile.ArchiveLevel); err != nil {
+			return outputProfile{}, fmt.Errorf("archive codec failed: %v", err)
+		}
+	}
+
+	return outputProfile{name: c.Name, dir: c.Dir, layout: output, profile: profile}, nil
+}
+
+// profilesAt 起报时次的输出方案, 上传和投递时模板中的 {profile} 为方案名称
+func profilesAt(profiles []outputProfile, v layout.Values, publish *publisher) []nc.Profile {
+	result := make([]nc.Profile, 0, len(profiles))
+	for _, p := range profiles {
+		profile := p.profile
+		profile.Name = p.name
+		profile.OutputPath, profile.CompressionPath = outputPaths(p.dir, p.layout, v)
+
+		pv := v
+		pv.Profile = p.name
+		profile.Uploaders = publish.uploaders(pv)
+		result = append(result, profile)
+	}
+
+	return result
+}
@@ -0,0 +1,21 @@
+package server
+
+import (
+	"fmt"
+	"gen-meteo-file/pkg/config"
+	"gen-meteo-file/pkg/tools/nc"
+)
+
+// lookupRegions 根据配置中的区域名称查找区域, 支持预置区域和自定义区域
+func lookupRegions(names []string) ([]nc.Region, error) {
+	custom := make([]nc.Region, 0, len(config.Get().Regions))
+	for _, spec := range config.Get().Regions {
+		region, err := nc.ParseRegion(spec)
+		if err != nil {
+			return nil, fmt.Errorf("parse custom region failed: %v", err)
+		}
+		custom = append(custom, region)
+	}
+
+	return nc.LookupRegions(names, custom)
+}
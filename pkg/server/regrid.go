@@ -0,0 +1,54 @@
+package server
+
+import (
+	"fmt"
+	"gen-meteo-file/pkg/config"
+	"gen-meteo-file/pkg/tools/regrid"
+	"time"
+)
+
+// regridTarget 根据配置生成插值目标网格, 未配置插值方法时返回空
+func regridTarget() (*regrid.Target, error) {
+	c := config.Get().Regrid
+	if c.Method == "" {
+		return nil, nil
+	}
+
+	return newRegridTarget(c)
+}
+
+func newRegridTarget(c config.Regrid) (*regrid.Target, error) {
+	if len(c.Extent) != 4 {
+		return nil, fmt.Errorf("regrid extent must be west,south,east,north")
+	}
+
+	grid, err := regrid.NewGrid(c.Resolution, c.Extent[0], c.Extent[1], c.Extent[2], c.Extent[3], regrid.LatOrder(c.LatOrder))
+	if err != nil {
+		return nil, fmt.Errorf("new regrid grid failed: %v", err)
+	}
+
+	method := regrid.Method(c.Method)
+	if method != regrid.Bilinear && method != regrid.Nearest {
+		return nil, fmt.Errorf("regrid method: %s not supported", c.Method)
+	}
+
+	return &regrid.Target{Grid: grid, Method: method}, nil
+}
+
+// timeInterval 解析时间插值的步长, 为空时不插值
+func timeInterval(interval string) (time.Duration, error) {
+	if interval == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return 0, fmt.Errorf("parse time interval: %s failed: %v", interval, err)
+	}
+
+	if d <= 0 {
+		return 0, fmt.Errorf("time interval: %s must be greater than 0", interval)
+	}
+
+	return d, nil
+}
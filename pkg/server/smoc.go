@@ -4,25 +4,30 @@ import (
 	"context"
 	"fmt"
 	"gen-meteo-file/pkg/config"
+	"gen-meteo-file/pkg/tools/layout"
 	"gen-meteo-file/pkg/tools/nc"
-	"os"
-	"path/filepath"
-	"strings"
+	"gen-meteo-file/pkg/tools/notify"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 type SMOCSever struct {
-	inputDir  string
-	outputDir string
+	datasetOptions
 }
 
-func NewSMOCSever() *SMOCSever {
-	return &SMOCSever{
-		inputDir:  filepath.Join(config.Get().Server.NCDir, "smoc"),
-		outputDir: filepath.Join(config.Get().Server.CSVDir),
+func NewSMOCSever(broker notify.Broker) (*SMOCSever, error) {
+	opts, err := newDatasetOptions("smoc", config.Get().SMOC, broker)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SMOCSever{datasetOptions: opts}
+	if s.profiles, err = s.outputProfiles("smoc"); err != nil {
+		return nil, fmt.Errorf("smoc output profiles failed: %v", err)
 	}
+
+	return s, nil
 }
 
 func (s *SMOCSever) Start(ctx context.Context) error {
@@ -53,30 +58,80 @@ func (s *SMOCSever) Start(ctx context.Context) error {
 }
 
 func (s *SMOCSever) GenByDate(ctx context.Context, date time.Time) error {
-	path, err := s.getSMOCPath(date)
+	path, err := s.getSMOCPath(ctx, date)
 	if err != nil {
 		return fmt.Errorf("get smoc path failed: %v", err)
 	}
 
+	values := layout.Values{Dataset: "smoc", Time: date}
+	outputPath, compressionPath := outputPaths(s.outputDir, s.output, values)
+
 	info := &nc.NCFile{
 		DateTime:        date,
 		InputPath:       path,
-		OutputPath:      filepath.Join(s.outputDir, fmt.Sprintf("%d", date.Year()), fmt.Sprintf("%02d", date.Month()), date.Format(time.DateOnly), fmt.Sprintf("smoc_%s.csv", date.Format("20060102"))),
-		CompressionPath: filepath.Join(s.outputDir, fmt.Sprintf("%d", date.Year()), fmt.Sprintf("%02d", date.Month()), date.Format(time.DateOnly), fmt.Sprintf("smoc_%s.zip", date.Format("20060102"))),
+		OutputPath:      outputPath,
+		CompressionPath: compressionPath,
+		Stride:          s.stride,
+		Regions:         s.regions,
+		Target:          s.target,
+		Interval:        s.interval,
+		Derived:         s.derived,
+		Columns:         s.columns,
+		Units:           s.units,
+		Formats:         s.formats,
+		ParquetCodec:    s.codec,
+		Archive:         s.archive,
+		ArchiveLevel:    s.level,
+		Profiles:        profilesAt(s.profiles, values, s.publish),
+		Uploaders:       s.publish.uploaders(values),
+		DeleteUploaded:  s.publish.deleteUploaded(),
+		Checksum:        s.notify.enabled(),
+		GeoJSONThin:     s.thin,
+		Tiles:           s.tiles,
+		GeoTIFF:         s.geotiff,
+		Velocity:        s.velocity,
 	}
 
-	nc, err := nc.NewSMOC(info)
+	// SMOC 每天一个文件, 插值时需要下一个文件, 找不到时只在当前文件的时次之间插值, 文件到达后重新生成
+	if s.interval > 0 {
+		next := date.Add(time.Hour * 24)
+		path, _ := s.getSMOCPath(ctx, next)
+		info.Next = &nc.NCFile{DateTime: next, InputPath: path}
+	}
+
+	// 对象存储中的输入文件在输出文件还没有全部生成时才下载
+	paths := []*string{&info.InputPath}
+	if info.Next != nil {
+		paths = append(paths, &info.Next.InputPath)
+	}
+	if err := s.inputs.localInputs(ctx, info, paths...); err != nil {
+		return fmt.Errorf("fetch smoc input files failed: %v", err)
+	}
+
+	nc, err := nc.NewSMOC(ctx, info)
 	if err != nil {
-		return fmt.Errorf("new smoc failed: %v", err)
+		if generated(err) {
+			return nil
+		}
+		err = fmt.Errorf("new smoc failed: %v", err)
+		s.notify.inputFailed(ctx, date, info.InputPath, err)
+		return err
 	}
 	defer nc.Close()
 
 	if err := nc.Analysis(); err != nil {
-		return fmt.Errorf("smoc analysis failed: %v", err)
+		err = fmt.Errorf("smoc analysis failed: %v", err)
+		s.notify.failed(ctx, date, err)
+		return err
 	}
 
-	if err := nc.GenerateCSV(); err != nil {
-		return fmt.Errorf("smoc generate csv failed: %v", err)
+	// 部分文件失败时, 已经完成的文件照常通知, 下一轮不会重新生成
+	err = nc.GenerateCSV(ctx)
+	s.notify.ready(ctx, date, info.Results(), nil)
+	if err != nil {
+		err = fmt.Errorf("smoc generate csv failed: %v", err)
+		s.notify.failed(ctx, date, err)
+		return err
 	}
 
 	return nil
@@ -86,22 +141,6 @@ func (s *SMOCSever) Stop(ctx context.Context) error {
 	return nil
 }
 
-func (s *SMOCSever) getSMOCPath(date time.Time) (string, error) {
-	dir := filepath.Join(s.inputDir, fmt.Sprintf("%d", date.Year()), fmt.Sprintf("%02d", date.Month()))
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return "", fmt.Errorf("read smoc dir: %s failed: %v", dir, err)
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		if strings.Contains(entry.Name(), date.Format("20060102")) {
-			return filepath.Join(dir, entry.Name()), nil
-		}
-	}
-
-	return "", fmt.Errorf("mfwam file not found: %s", date.Format("20060102"))
+func (s *SMOCSever) getSMOCPath(ctx context.Context, date time.Time) (string, error) {
+	return s.inputs.find(ctx, s.input, layout.Values{Dataset: "smoc", Time: date})
 }
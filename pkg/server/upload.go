@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"gen-meteo-file/pkg/config"
+	"gen-meteo-file/pkg/tools/layout"
+	"gen-meteo-file/pkg/tools/nc"
+	"gen-meteo-file/pkg/tools/objstore"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// publisher 输出文件生成后上传到对象存储和投递到 SFTP 目标, 全部成功后按照 S3_DELETE_LOCAL 删除本地文件
+type publisher struct {
+	store   *objectStore
+	targets []*sftpTarget
+}
+
+func newPublisher(dataset string) (*publisher, error) {
+	store, err := newObjectStore()
+	if err != nil {
+		return nil, fmt.Errorf("object store failed: %v", err)
+	}
+
+	targets, err := sftpTargets(dataset)
+	if err != nil {
+		return nil, fmt.Errorf("sftp targets failed: %v", err)
+	}
+
+	return &publisher{store: store, targets: targets}, nil
+}
+
+// uploaders 起报时次输出文件的 Uploader, v.Profile 为输出方案名称
+func (p *publisher) uploaders(v layout.Values) []nc.Uploader {
+	return append(p.store.uploaders(v), sftpUploaders(p.targets, v)...)
+}
+
+// deleteUploaded 上传成功后是否删除本地文件
+func (p *publisher) deleteUploaded() bool {
+	return p.store.deleteUploaded()
+}
+
+// objectStore 输出文件上传到 S3 兼容的对象存储, 所有数据源共用一个配置
+type objectStore struct {
+	client      *objstore.Client
+	bucket      string
+	prefix      *layout.Template
+	deleteLocal bool
+}
+
+// newObjectStore 对象存储的配置, 未开启时返回空
+func newObjectStore() (*objectStore, error) {
+	c := config.Get().S3
+	if !c.Enable {
+		return nil, nil
+	}
+
+	if c.Bucket == "" {
+		return nil, fmt.Errorf("s3 bucket is empty")
+	}
+
+	if c.PartSize < 0 {
+		return nil, fmt.Errorf("s3 part size: %d must not be negative", c.PartSize)
+	}
+
+	prefix, err := layout.Parse(c.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("s3 prefix failed: %v", err)
+	}
+
+	client, err := objstore.New(objstore.Config{
+		Endpoint:  c.Endpoint,
+		AccessKey: c.AccessKey,
+		SecretKey: c.SecretKey,
+		Region:    c.Region,
+		Secure:    c.Secure,
+		PartSize:  uint64(c.PartSize) << 20,
+		Retries:   c.Retries,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &objectStore{client: client, bucket: c.Bucket, prefix: prefix, deleteLocal: c.DeleteLocal}, nil
+}
+
+// uploaders 起报时次输出文件的 Uploader, 未开启时返回空
+func (o *objectStore) uploaders(v layout.Values) []nc.Uploader {
+	if o == nil {
+		return nil
+	}
+
+	// 输出方案名称等变量为空时去掉多余的 /
+	prefix := strings.Trim(path.Clean("/"+o.prefix.Execute(v)), "/")
+
+	return []nc.Uploader{&objectUploader{store: o, prefix: prefix}}
+}
+
+// deleteUploaded 上传成功后是否删除本地文件
+func (o *objectStore) deleteUploaded() bool {
+	return o != nil && o.deleteLocal
+}
+
+// objectUploader 将输出文件上传到 {bucket}/{prefix}/{文件名}, 元数据上传到 {prefix}/{文件名去掉扩展名}.metadata.json
+type objectUploader struct {
+	store  *objectStore
+	prefix string
+}
+
+func (u *objectUploader) key(file string) string {
+	return path.Join(u.prefix, filepath.Base(file))
+}
+
+// Upload 先上传元数据再上传文件, 文件上传成功才算完成
+func (u *objectUploader) Upload(ctx context.Context, file string, metadata []byte) error {
+	key := u.key(file)
+
+	if metadata != nil {
+		name, _, _ := strings.Cut(path.Base(key), ".")
+		if err := u.store.client.Put(ctx, u.store.bucket, path.Join(u.prefix, name+"."+nc.MetadataName), metadata); err != nil {
+			return err
+		}
+	}
+
+	if err := u.store.client.Upload(ctx, u.store.bucket, key, file); err != nil {
+		return err
+	}
+
+	logrus.Infof("upload file: %s to s3://%s/%s success", file, u.store.bucket, key)
+	return nil
+}
+
+// Location s3://{bucket}/{key}
+func (u *objectUploader) Location(file string) string {
+	return "s3://" + path.Join(u.store.bucket, u.key(file))
+}
+
+// Uploaded 查询失败时当作没有上传, 重新上传
+func (u *objectUploader) Uploaded(ctx context.Context, file string) bool {
+	ok, err := u.store.client.Exists(ctx, u.store.bucket, u.key(file))
+	if err != nil {
+		logrus.Warnf("check uploaded file: %s failed: %v", file, err)
+		return false
+	}
+
+	return ok
+}
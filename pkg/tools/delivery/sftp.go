@@ -0,0 +1,224 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// DefaultTimeout 连接超时为 0 时使用的超时
+const DefaultTimeout = 30 * time.Second
+
+// Target SFTP 投递目标的连接配置, 只支持密钥认证
+type Target struct {
+	Name       string
+	Addr       string // host:port, 没有端口时使用 22
+	User       string
+	Key        []byte // PEM 格式的私钥
+	Passphrase string // 私钥的密码, 为空时私钥没有加密
+	KnownHosts string // known_hosts 文件, 用于校验服务器的主机密钥
+	Insecure   bool   // 不校验主机密钥, 只用于测试环境
+	Retries    int    // 失败后的重试次数
+	Timeout    time.Duration
+}
+
+// Client SFTP 投递客户端, 每次投递建立一个连接, 先写入临时文件再重命名, 失败后按照指数退避重试
+type Client struct {
+	name    string
+	addr    string
+	config  *ssh.ClientConfig
+	retries int
+}
+
+func New(t Target) (*Client, error) {
+	if t.Addr == "" {
+		return nil, fmt.Errorf("delivery: %s addr is empty", t.Name)
+	}
+
+	if _, _, err := net.SplitHostPort(t.Addr); err != nil {
+		t.Addr = net.JoinHostPort(t.Addr, "22")
+	}
+
+	if t.Retries < 0 {
+		return nil, fmt.Errorf("delivery: %s retries: %d must not be negative", t.Name, t.Retries)
+	}
+
+	var (
+		signer ssh.Signer
+		err    error
+	)
+	if t.Passphrase == "" {
+		signer, err = ssh.ParsePrivateKey(t.Key)
+	} else {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(t.Key, []byte(t.Passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("delivery: %s parse private key failed: %v", t.Name, err)
+	}
+
+	hostKey := ssh.InsecureIgnoreHostKey()
+	if !t.Insecure {
+		if t.KnownHosts == "" {
+			return nil, fmt.Errorf("delivery: %s known hosts is empty", t.Name)
+		}
+
+		if hostKey, err = knownhosts.New(t.KnownHosts); err != nil {
+			return nil, fmt.Errorf("delivery: %s load known hosts: %s failed: %v", t.Name, t.KnownHosts, err)
+		}
+	}
+
+	if t.Timeout == 0 {
+		t.Timeout = DefaultTimeout
+	}
+
+	return &Client{
+		name: t.Name,
+		addr: t.Addr,
+		config: &ssh.ClientConfig{
+			User:            t.User,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback: hostKey,
+			Timeout:         t.Timeout,
+		},
+		retries: t.Retries,
+	}, nil
+}
+
+// Deliver 投递本地文件到 remote, 返回尝试的次数
+func (c *Client) Deliver(ctx context.Context, local, remote string) (int, error) {
+	wait := time.Second
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = c.deliver(ctx, local, remote); err == nil {
+			return attempt, nil
+		}
+
+		if attempt > c.retries {
+			return attempt, fmt.Errorf("deliver %s to %s:%s failed after %d attempts: %v", local, c.name, remote, attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempt, fmt.Errorf("deliver %s to %s:%s failed: %v", local, c.name, remote, ctx.Err())
+		case <-time.After(wait):
+		}
+
+		wait = min(wait*2, time.Minute)
+	}
+}
+
+// deliver 写入 remote.part, 大小一致后重命名为 remote, 服务器支持 posix-rename 时覆盖已经存在的文件
+func (c *Client) deliver(ctx context.Context, local, remote string) error {
+	file, err := os.Open(local)
+	if err != nil {
+		return fmt.Errorf("open file: %s failed: %v", local, err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("get file info: %s failed: %v", local, err)
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// ctx 取消时关闭连接, 中断正在进行的写入
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		return fmt.Errorf("new sftp client failed: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.MkdirAll(path.Dir(remote)); err != nil {
+		return fmt.Errorf("create remote dir: %s failed: %v", path.Dir(remote), err)
+	}
+
+	tmp := remote + ".part"
+	dst, err := client.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("create remote file: %s failed: %v", tmp, err)
+	}
+
+	if _, err := io.Copy(dst, file); err != nil {
+		dst.Close()
+		client.Remove(tmp)
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return fmt.Errorf("write remote file: %s failed: %v", tmp, err)
+	}
+
+	if err := dst.Close(); err != nil {
+		client.Remove(tmp)
+		return fmt.Errorf("close remote file: %s failed: %v", tmp, err)
+	}
+
+	info, err := client.Stat(tmp)
+	if err != nil {
+		return fmt.Errorf("stat remote file: %s failed: %v", tmp, err)
+	}
+
+	if info.Size() != stat.Size() {
+		client.Remove(tmp)
+		return fmt.Errorf("remote file: %s size: %d does not match local size: %d", tmp, info.Size(), stat.Size())
+	}
+
+	if _, ok := client.HasExtension("posix-rename@openssh.com"); ok {
+		err = client.PosixRename(tmp, remote)
+	} else {
+		client.Remove(remote)
+		err = client.Rename(tmp, remote)
+	}
+	if err != nil {
+		return fmt.Errorf("rename remote file: %s failed: %v", tmp, err)
+	}
+
+	return nil
+}
+
+// dial 建立 SSH 连接, ctx 取消或者超时后停止连接和握手
+func (c *Client) dial(ctx context.Context) (*ssh.Client, error) {
+	dialer := net.Dialer{Timeout: c.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s failed: %v", c.addr, err)
+	}
+
+	// 握手时同样使用连接超时, 握手完成后清除
+	conn.SetDeadline(time.Now().Add(c.config.Timeout))
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, c.addr, c.config)
+	if !stop() || err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, fmt.Errorf("ssh handshake with %s failed: %v", c.addr, err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
@@ -0,0 +1,361 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testServer 进程内的 SFTP 服务器, 文件保存在内存中, 记录写入的文件和文件操作
+type testServer struct {
+	addr    string
+	hostKey ssh.PublicKey
+	handler sftp.Handlers
+
+	mu     sync.Mutex
+	writes []string
+	cmds   []string
+}
+
+func (s *testServer) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	s.mu.Lock()
+	s.writes = append(s.writes, r.Filepath)
+	s.mu.Unlock()
+
+	return s.handler.FilePut.Filewrite(r)
+}
+
+func (s *testServer) Filecmd(r *sftp.Request) error {
+	s.mu.Lock()
+	s.cmds = append(s.cmds, fmt.Sprintf("%s %s %s", r.Method, r.Filepath, r.Target))
+	s.mu.Unlock()
+
+	return s.handler.FileCmd.Filecmd(r)
+}
+
+// read 读取服务器上的文件, 文件不存在时返回错误
+func (s *testServer) read(path string) ([]byte, error) {
+	req := sftp.NewRequest("Get", path)
+	req.Flags = 0x1 // SSH_FXF_READ
+	r, err := s.handler.FileGet.Fileread(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.NewSectionReader(r, 0, 1<<20)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type limitedWriter struct {
+	io.WriterAt
+	limit int64
+}
+
+// WriteAt 只写入 limit 之前的数据, 返回完整的长度
+func (w limitedWriter) WriteAt(p []byte, off int64) (int, error) {
+	if n := w.limit - off; n < int64(len(p)) {
+		if n > 0 {
+			if _, err := w.WriterAt.WriteAt(p[:n], off); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	}
+
+	return w.WriterAt.WriteAt(p, off)
+}
+
+// startTestServer 启动 SFTP 服务器, 只接受 clientKey 认证, limit 大于 0 时每个文件只保存前 limit 个字节
+func startTestServer(t *testing.T, clientKey ssh.PublicKey, limit int64) *testServer {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("public key denied")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &testServer{addr: ln.Addr().String(), hostKey: hostSigner.PublicKey(), handler: sftp.InMemHandler()}
+	handlers := sftp.Handlers{FileGet: s.handler.FileGet, FilePut: s, FileCmd: s, FileList: s.handler.FileList}
+	if limit > 0 {
+		handlers.FilePut = putFunc(func(r *sftp.Request) (io.WriterAt, error) {
+			w, err := s.Filewrite(r)
+			return limitedWriter{WriterAt: w, limit: limit}, err
+		})
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveConn(conn, config, handlers)
+		}
+	}()
+
+	return s
+}
+
+type putFunc func(r *sftp.Request) (io.WriterAt, error)
+
+func (f putFunc) Filewrite(r *sftp.Request) (io.WriterAt, error) { return f(r) }
+
+func serveConn(conn net.Conn, config *ssh.ServerConfig, handlers sftp.Handlers) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					server := sftp.NewRequestServer(channel, handlers)
+					server.Serve()
+					server.Close()
+				}
+			}
+		}()
+	}
+}
+
+// newTestTarget 生成客户端密钥, known_hosts 中记录 hostKey
+func newTestTarget(t *testing.T, hostKey func(s *testServer) ssh.PublicKey, limit int64) (Target, *testServer) {
+	t.Helper()
+
+	_, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientSigner, err := ssh.NewSignerFromKey(clientPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := startTestServer(t, clientSigner.PublicKey(), limit)
+
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{s.addr}, hostKey(s)) + "\n"
+	if err := os.WriteFile(knownHosts, []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return Target{
+		Name:       "test",
+		Addr:       s.addr,
+		User:       "meteo",
+		Key:        pem.EncodeToMemory(block),
+		KnownHosts: knownHosts,
+		Timeout:    5 * time.Second,
+	}, s
+}
+
+func serverHostKey(s *testServer) ssh.PublicKey { return s.hostKey }
+
+func writeLocalFile(t *testing.T, size int) (string, []byte) {
+	t.Helper()
+
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ec_2025061300.zip")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, data
+}
+
+func TestDeliver(t *testing.T) {
+	target, s := newTestTarget(t, serverHostKey, 0)
+
+	client, err := New(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local, data := writeLocalFile(t, 100<<10)
+
+	attempts, err := client.Deliver(context.Background(), local, "upload/ec/ec_2025061300.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+
+	// 先写入 .part 再重命名, 内存中的服务器把 posix-rename 当作 rename 处理
+	if want := []string{"/upload/ec/ec_2025061300.zip.part"}; fmt.Sprint(s.writes) != fmt.Sprint(want) {
+		t.Errorf("writes = %v, want %v", s.writes, want)
+	}
+	if rename := "Rename /upload/ec/ec_2025061300.zip.part /upload/ec/ec_2025061300.zip"; !strings.Contains(strings.Join(s.cmds, "\n"), rename) {
+		t.Errorf("cmds = %v, want %s", s.cmds, rename)
+	}
+
+	got, err := s.read("/upload/ec/ec_2025061300.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("remote file size = %d, want %d", len(got), len(data))
+	}
+
+	if _, err := s.read("/upload/ec/ec_2025061300.zip.part"); err == nil {
+		t.Errorf("remote .part file still exists")
+	}
+}
+
+func TestDeliverSizeMismatch(t *testing.T) {
+	target, s := newTestTarget(t, serverHostKey, 10)
+
+	client, err := New(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local, _ := writeLocalFile(t, 100)
+
+	attempts, err := client.Deliver(context.Background(), local, "upload/ec_2025061300.zip")
+	if err == nil || !strings.Contains(err.Error(), "size: 10 does not match local size: 100") {
+		t.Fatalf("Deliver() error = %v, want size mismatch", err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+
+	// 大小不一致时删除 .part, 不重命名
+	for _, path := range []string{"/upload/ec_2025061300.zip", "/upload/ec_2025061300.zip.part"} {
+		if _, err := s.read(path); err == nil {
+			t.Errorf("remote file: %s exists", path)
+		}
+	}
+}
+
+func TestDeliverHostKeyMismatch(t *testing.T) {
+	// known_hosts 中记录的是另一个主机密钥
+	target, s := newTestTarget(t, func(s *testServer) ssh.PublicKey {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer, err := ssh.NewSignerFromKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signer.PublicKey()
+	}, 0)
+
+	client, err := New(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local, _ := writeLocalFile(t, 100)
+
+	_, err = client.Deliver(context.Background(), local, "upload/ec_2025061300.zip")
+	if err == nil || !strings.Contains(err.Error(), "key mismatch") {
+		t.Fatalf("Deliver() error = %v, want host key mismatch", err)
+	}
+	if len(s.writes) != 0 {
+		t.Errorf("writes = %v, want none", s.writes)
+	}
+}
+
+func TestDeliverContextCanceled(t *testing.T) {
+	// 服务器接受连接后不响应, 握手一直等待
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+	defer func() {
+		select {
+		case conn := <-accepted:
+			conn.Close()
+		default:
+		}
+	}()
+
+	target, _ := newTestTarget(t, serverHostKey, 0)
+	target.Addr = ln.Addr().String()
+	target.Timeout = time.Minute
+
+	client, err := New(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local, _ := writeLocalFile(t, 100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.Deliver(ctx, local, "upload/ec_2025061300.zip")
+	if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Fatalf("Deliver() error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Deliver() returned after %v", elapsed)
+	}
+}
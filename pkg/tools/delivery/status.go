@@ -0,0 +1,100 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Record 单个文件的投递状态
+type Record struct {
+	Remote    string    `json:"remote"`
+	Size      int64     `json:"size"`
+	Delivered bool      `json:"delivered"`
+	Attempts  int       `json:"attempts"` // 累计尝试的次数
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Status 投递状态, 以本地文件路径为 key 保存在 JSON 文件中
+// retention 不为 0 时, 超过 retention 没有更新的记录在读取和更新时删除, 避免状态文件无限增长
+type Status struct {
+	path      string
+	retention time.Duration
+	mu        sync.Mutex
+	records   map[string]Record
+}
+
+// LoadStatus 读取投递状态, 文件不存在时返回空的状态
+func LoadStatus(path string, retention time.Duration) (*Status, error) {
+	s := &Status{path: path, retention: retention, records: make(map[string]Record)}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read delivery status: %s failed: %v", path, err)
+	}
+
+	if err := json.Unmarshal(b, &s.records); err != nil {
+		return nil, fmt.Errorf("parse delivery status: %s failed: %v", path, err)
+	}
+	s.prune()
+
+	return s, nil
+}
+
+func (s *Status) Get(local string) (Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[local]
+	return r, ok
+}
+
+// Set 更新文件的投递状态, 写入临时文件后重命名
+func (s *Status) Set(local string, r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[local] = r
+	s.prune()
+
+	b, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal delivery status failed: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), os.FileMode(0755)); err != nil {
+		return fmt.Errorf("create delivery status dir: %s failed: %v", filepath.Dir(s.path), err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, os.FileMode(0644)); err != nil {
+		return fmt.Errorf("write delivery status: %s failed: %v", tmp, err)
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("rename delivery status: %s failed: %v", tmp, err)
+	}
+
+	return nil
+}
+
+// prune 删除超过 retention 没有更新的记录, 调用方持有锁
+func (s *Status) prune() {
+	if s.retention <= 0 {
+		return
+	}
+
+	expired := time.Now().Add(-s.retention)
+	for local, r := range s.records {
+		if r.UpdatedAt.Before(expired) {
+			delete(s.records, local)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package delivery
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStatus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status", "test_ec.json")
+
+	s, err := LoadStatus(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := Record{Remote: "upload/ec_2025061300.zip", Size: 100, Delivered: true, Attempts: 2, UpdatedAt: time.Now().UTC().Truncate(time.Second)}
+	if err := s.Set("/data/ec_2025061300.zip", record); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新读取后记录不变
+	s, err = LoadStatus(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := s.Get("/data/ec_2025061300.zip")
+	if !ok || got != record {
+		t.Errorf("Get() = %+v, %v, want %+v, true", got, ok, record)
+	}
+
+	if _, ok := s.Get("/data/ec_2025061303.zip"); ok {
+		t.Errorf("Get() of unknown file found a record")
+	}
+}
+
+func TestStatusPrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test_ec.json")
+	now := time.Now()
+
+	s, err := LoadStatus(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// retention 为 0 时不删除
+	if err := s.Set("/data/old.zip", Record{Delivered: true, UpdatedAt: now.AddDate(0, 0, -40)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("/data/new.zip", Record{Delivered: true, UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Get("/data/old.zip"); !ok {
+		t.Fatalf("record removed without retention")
+	}
+
+	// 读取时删除过期的记录
+	s, err = LoadStatus(path, 30*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Get("/data/old.zip"); ok {
+		t.Errorf("expired record not pruned on load")
+	}
+	if _, ok := s.Get("/data/new.zip"); !ok {
+		t.Errorf("recent record pruned on load")
+	}
+
+	// 更新时删除过期的记录并写入文件
+	s.records["/data/stale.zip"] = Record{UpdatedAt: now.AddDate(0, 0, -31)}
+	if err := s.Set("/data/next.zip", Record{UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = LoadStatus(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Get("/data/stale.zip"); ok {
+		t.Errorf("expired record not pruned on set")
+	}
+	if len(s.records) != 2 {
+		t.Errorf("records = %d, want 2", len(s.records))
+	}
+}
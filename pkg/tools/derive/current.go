@@ -0,0 +1,24 @@
+package derive
+
+// CurrentDirection 海洋学流向: 流的去向, 正北为 0 度顺时针(0~360 度)
+// 例如: u > 0, v = 0 为向东的流, 流向为 90 度
+func CurrentDirection(u, v float32) float32 {
+	if isNaN(u, v) {
+		return nan
+	}
+
+	if u == 0 && v == 0 {
+		return 0
+	}
+
+	return degree(float64(u), float64(v))
+}
+
+// Residual 余流的一个分量: 总流减去潮流, 任意一个缺测时为 NaN
+func Residual(total, tide float32) float32 {
+	if isNaN(total, tide) {
+		return nan
+	}
+
+	return total - tide
+}
@@ -0,0 +1,51 @@
+package derive
+
+import "testing"
+
+func TestCurrentDirection(t *testing.T) {
+	tests := []struct {
+		name string
+		u, v float32
+		want float32
+	}{
+		// 流向为去向, 与风向相反
+		{name: "east", u: 1, v: 0, want: 90},
+		{name: "north", u: 0, v: 1, want: 0},
+		{name: "west", u: -1, v: 0, want: 270},
+		{name: "south", u: 0, v: -1, want: 180},
+		{name: "north east", u: 1, v: 1, want: 45},
+		// 转换为 float32 后进位为 360 度时归为 0 度
+		{name: "almost north", u: -1e-7, v: 1, want: 0},
+		{name: "still", u: 0, v: 0, want: 0},
+		{name: "nan u", u: nan, v: 1, want: nan},
+		{name: "nan v", u: 1, v: nan, want: nan},
+	}
+
+	for _, tt := range tests {
+		got := CurrentDirection(tt.u, tt.v)
+		if !equal(got, tt.want) {
+			t.Errorf("CurrentDirection(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+		if got < 0 || got >= 360 {
+			t.Errorf("CurrentDirection(%s) = %v out of range", tt.name, got)
+		}
+	}
+}
+
+func TestResidual(t *testing.T) {
+	tests := []struct {
+		total, tide float32
+		want        float32
+	}{
+		{total: 0.5, tide: 0.2, want: 0.3},
+		{total: -0.5, tide: 0.2, want: -0.7},
+		{total: nan, tide: 0.2, want: nan},
+		{total: 0.5, tide: nan, want: nan},
+	}
+
+	for _, tt := range tests {
+		if got := Residual(tt.total, tt.tide); !equal(got, tt.want) {
+			t.Errorf("Residual(%v, %v) = %v, want %v", tt.total, tt.tide, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package derive
+
+import "math"
+
+// MSToKnots 1 m/s 对应的节数
+const MSToKnots = 1.943844
+
+var nan = float32(math.NaN())
+
+func isNaN(values ...float32) bool {
+	for _, value := range values {
+		if math.IsNaN(float64(value)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Speed 矢量的模长, 任意分量缺测时为 NaN
+func Speed(u, v float32) float32 {
+	if isNaN(u, v) {
+		return nan
+	}
+
+	return float32(math.Hypot(float64(u), float64(v)))
+}
+
+// Knots 米每秒转换为节
+func Knots(speed float32) float32 {
+	if isNaN(speed) {
+		return nan
+	}
+
+	return speed * MSToKnots
+}
+
+// degree 由 x(东), y(北) 计算以正北为 0 度顺时针的方位角(0~360 度)
+func degree(x, y float64) float32 {
+	d := math.Atan2(x, y) * 180 / math.Pi
+	if d < 0 {
+		d += 360
+	}
+
+	// 接近 360 度的值转换为 float32 时可能进位为 360
+	f := float32(d)
+	if f >= 360 {
+		f -= 360
+	}
+
+	return f
+}
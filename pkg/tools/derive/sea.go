@@ -0,0 +1,74 @@
+package derive
+
+import "math"
+
+// 重力加速度(m/s²)
+const gravity = 9.80665
+
+// 海况等级 1~9 对应的有效波高下限(m), 0 级为 0 m(无浪)
+var seaStateLimits = []float32{0, 0.1, 0.5, 1.25, 2.5, 4, 6, 9, 14}
+
+// 波陡等级 0~3 的上限(不包含), 0: 平缓(以涌浪为主), 1: 一般, 2: 陡, 3: 很陡
+var steepnessLimits = []float32{0.02, 0.04, 0.06}
+
+// DouglasSeaState 道格拉斯风浪等级(0~9), 有效波高单位为 m, 区间包含下限, 缺测时返回 -1
+func DouglasSeaState(height float32) int {
+	if isNaN(height) {
+		return -1
+	}
+
+	if height <= 0 {
+		return 0
+	}
+
+	state := 1
+	for i := 1; i < len(seaStateLimits); i++ {
+		if height >= seaStateLimits[i] {
+			state = i + 1
+		}
+	}
+
+	return state
+}
+
+// WMOSeaState WMO 海况代码(代码表 3700, 0~9), 有效波高单位为 m
+// 与道格拉斯等级的区间一致, 但边界上的波高归入较低的等级, 缺测时返回 -1
+func WMOSeaState(height float32) int {
+	if isNaN(height) {
+		return -1
+	}
+
+	state := 0
+	for i, limit := range seaStateLimits {
+		if height > limit {
+			state = i + 1
+		}
+	}
+
+	return state
+}
+
+// Steepness 波陡: 有效波高与深水波长之比, 波长 L = g * T² / 2π, 周期单位为 s, 周期不大于 0 时为 NaN
+func Steepness(height, period float32) float32 {
+	if isNaN(height, period) || period <= 0 {
+		return nan
+	}
+
+	length := gravity * float64(period) * float64(period) / (2 * math.Pi)
+	return float32(float64(height) / length)
+}
+
+// SteepnessClass 波陡等级(0~3), 缺测时返回 -1
+func SteepnessClass(steepness float32) int {
+	if isNaN(steepness) {
+		return -1
+	}
+
+	for class, limit := range steepnessLimits {
+		if steepness < limit {
+			return class
+		}
+	}
+
+	return len(steepnessLimits)
+}
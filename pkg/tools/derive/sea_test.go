@@ -0,0 +1,87 @@
+package derive
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSeaState(t *testing.T) {
+	tests := []struct {
+		height  float32
+		douglas int
+		wmo     int
+	}{
+		{height: -0.5, douglas: 0, wmo: 0},
+		{height: 0, douglas: 0, wmo: 0},
+		{height: 0.05, douglas: 1, wmo: 1},
+		// 边界上的波高: 道格拉斯等级归入较高的等级, WMO 归入较低的等级
+		{height: 0.1, douglas: 2, wmo: 1},
+		{height: 0.3, douglas: 2, wmo: 2},
+		{height: 0.5, douglas: 3, wmo: 2},
+		{height: 1.25, douglas: 4, wmo: 3},
+		{height: 2.5, douglas: 5, wmo: 4},
+		{height: 4, douglas: 6, wmo: 5},
+		{height: 6, douglas: 7, wmo: 6},
+		{height: 9, douglas: 8, wmo: 7},
+		{height: 13.99, douglas: 8, wmo: 8},
+		{height: 14, douglas: 9, wmo: 8},
+		{height: 14.01, douglas: 9, wmo: 9},
+		{height: 20, douglas: 9, wmo: 9},
+		{height: nan, douglas: -1, wmo: -1},
+	}
+
+	for _, tt := range tests {
+		if got := DouglasSeaState(tt.height); got != tt.douglas {
+			t.Errorf("DouglasSeaState(%v) = %d, want %d", tt.height, got, tt.douglas)
+		}
+		if got := WMOSeaState(tt.height); got != tt.wmo {
+			t.Errorf("WMOSeaState(%v) = %d, want %d", tt.height, got, tt.wmo)
+		}
+	}
+}
+
+func TestSteepness(t *testing.T) {
+	// 周期 8s 的深水波长约 99.9m
+	length := float32(gravity * 64 / (2 * math.Pi))
+
+	tests := []struct {
+		height, period float32
+		want           float32
+	}{
+		{height: 2, period: 8, want: 2 / length},
+		{height: 0, period: 8, want: 0},
+		{height: 2, period: 0, want: nan},
+		{height: 2, period: -8, want: nan},
+		{height: nan, period: 8, want: nan},
+		{height: 2, period: nan, want: nan},
+	}
+
+	for _, tt := range tests {
+		if got := Steepness(tt.height, tt.period); !equal(got, tt.want) {
+			t.Errorf("Steepness(%v, %v) = %v, want %v", tt.height, tt.period, got, tt.want)
+		}
+	}
+}
+
+func TestSteepnessClass(t *testing.T) {
+	tests := []struct {
+		steepness float32
+		want      int
+	}{
+		{steepness: 0, want: 0},
+		{steepness: 0.0199, want: 0},
+		// 区间包含下限, 不包含上限
+		{steepness: 0.02, want: 1},
+		{steepness: 0.0399, want: 1},
+		{steepness: 0.04, want: 2},
+		{steepness: 0.06, want: 3},
+		{steepness: 0.14, want: 3},
+		{steepness: nan, want: -1},
+	}
+
+	for _, tt := range tests {
+		if got := SteepnessClass(tt.steepness); got != tt.want {
+			t.Errorf("SteepnessClass(%v) = %d, want %d", tt.steepness, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package derive
+
+// 蒲福风级每一级风速的上限(m/s, 不包含), 超过最后一级为 12 级
+var beaufortLimits = []float32{0.3, 1.6, 3.4, 5.5, 8.0, 10.8, 13.9, 17.2, 20.8, 24.5, 28.5, 32.7}
+
+// WindDirection 气象学风向: 风的来向, 正北为 0 度顺时针(0~360 度)
+// 例如: u > 0, v = 0 为西风, 风向为 270 度
+func WindDirection(u, v float32) float32 {
+	if isNaN(u, v) {
+		return nan
+	}
+
+	if u == 0 && v == 0 {
+		return 0
+	}
+
+	return degree(-float64(u), -float64(v))
+}
+
+// Beaufort 蒲福风级(0~12), 风速单位为 m/s, 风速缺测时返回 -1
+func Beaufort(speed float32) int {
+	if isNaN(speed) {
+		return -1
+	}
+
+	for force, limit := range beaufortLimits {
+		if speed < limit {
+			return force
+		}
+	}
+
+	return len(beaufortLimits)
+}
@@ -0,0 +1,97 @@
+package derive
+
+import (
+	"math"
+	"testing"
+)
+
+func equal(a, b float32) bool {
+	if math.IsNaN(float64(a)) || math.IsNaN(float64(b)) {
+		return math.IsNaN(float64(a)) && math.IsNaN(float64(b))
+	}
+
+	return math.Abs(float64(a-b)) < 1e-4
+}
+
+func TestBeaufort(t *testing.T) {
+	tests := []struct {
+		speed float32
+		want  int
+	}{
+		{speed: 0, want: 0},
+		{speed: 0.29, want: 0},
+		// 区间包含下限, 不包含上限
+		{speed: 0.3, want: 1},
+		{speed: 1.59, want: 1},
+		{speed: 1.6, want: 2},
+		{speed: 3.4, want: 3},
+		{speed: 5.5, want: 4},
+		{speed: 8.0, want: 5},
+		{speed: 10.8, want: 6},
+		{speed: 13.9, want: 7},
+		{speed: 17.2, want: 8},
+		{speed: 20.8, want: 9},
+		{speed: 24.5, want: 10},
+		{speed: 28.5, want: 11},
+		{speed: 32.69, want: 11},
+		{speed: 32.7, want: 12},
+		{speed: 60, want: 12},
+		{speed: nan, want: -1},
+	}
+
+	for _, tt := range tests {
+		if got := Beaufort(tt.speed); got != tt.want {
+			t.Errorf("Beaufort(%v) = %d, want %d", tt.speed, got, tt.want)
+		}
+	}
+}
+
+func TestWindDirection(t *testing.T) {
+	tests := []struct {
+		name string
+		u, v float32
+		want float32
+	}{
+		// 风向为来向
+		{name: "west wind", u: 1, v: 0, want: 270},
+		{name: "south wind", u: 0, v: 1, want: 180},
+		{name: "east wind", u: -1, v: 0, want: 90},
+		{name: "north wind", u: 0, v: -1, want: 0},
+		{name: "south west wind", u: 1, v: 1, want: 225},
+		{name: "calm", u: 0, v: 0, want: 0},
+		{name: "nan u", u: nan, v: 1, want: nan},
+		{name: "nan v", u: 1, v: nan, want: nan},
+	}
+
+	for _, tt := range tests {
+		if got := WindDirection(tt.u, tt.v); !equal(got, tt.want) {
+			t.Errorf("WindDirection(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSpeed(t *testing.T) {
+	tests := []struct {
+		u, v float32
+		want float32
+	}{
+		{u: 3, v: 4, want: 5},
+		{u: -3, v: -4, want: 5},
+		{u: 0, v: 0, want: 0},
+		{u: nan, v: 4, want: nan},
+		{u: 3, v: nan, want: nan},
+	}
+
+	for _, tt := range tests {
+		if got := Speed(tt.u, tt.v); !equal(got, tt.want) {
+			t.Errorf("Speed(%v, %v) = %v, want %v", tt.u, tt.v, got, tt.want)
+		}
+	}
+
+	if got := Knots(10); !equal(got, 19.43844) {
+		t.Errorf("Knots(10) = %v, want 19.43844", got)
+	}
+	if got := Knots(nan); !equal(got, nan) {
+		t.Errorf("Knots(NaN) = %v, want NaN", got)
+	}
+}
@@ -0,0 +1,55 @@
+package geo
+
+import "math"
+
+// Point 经纬度坐标, 与 GeoJSON 保持一致: [lon, lat]
+type Point struct {
+	Lon float64
+	Lat float64
+}
+
+// Ring 闭合的线环, 首尾点可以重复也可以不重复
+type Ring []Point
+
+// Polygon 多边形, 第一个线环为外环, 其余为内环(洞)
+type Polygon []Ring
+
+// BBox 外包矩形
+type BBox struct {
+	West  float64
+	South float64
+	East  float64
+	North float64
+}
+
+func emptyBBox() BBox {
+	return BBox{West: math.Inf(1), South: math.Inf(1), East: math.Inf(-1), North: math.Inf(-1)}
+}
+
+func (b BBox) extend(p Point) BBox {
+	return BBox{
+		West:  math.Min(b.West, p.Lon),
+		South: math.Min(b.South, p.Lat),
+		East:  math.Max(b.East, p.Lon),
+		North: math.Max(b.North, p.Lat),
+	}
+}
+
+// Contains 点是否在外包矩形内(包含边界)
+func (b BBox) Contains(lon, lat float64) bool {
+	return lon >= b.West && lon <= b.East && lat >= b.South && lat <= b.North
+}
+
+// BBox 多边形外环的外包矩形
+func (p Polygon) BBox() BBox {
+	bbox := emptyBBox()
+	if len(p) == 0 {
+		return bbox
+	}
+
+	for _, point := range p[0] {
+		bbox = bbox.extend(point)
+	}
+
+	return bbox
+}
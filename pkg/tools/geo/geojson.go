@@ -0,0 +1,137 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// geoJSON 兼容 FeatureCollection / Feature / GeometryCollection / Polygon / MultiPolygon
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Features    []geoJSON       `json:"features"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Geometries  []geoJSON       `json:"geometries"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// LoadGeoJSON 读取 GeoJSON 文件中的所有多边形, 其他类型的几何体会被忽略
+func LoadGeoJSON(path string) ([]Polygon, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read geojson file: %s failed: %v", path, err)
+	}
+
+	polygons, err := ParseGeoJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse geojson file: %s failed: %v", path, err)
+	}
+
+	return polygons, nil
+}
+
+// ParseGeoJSON 解析 GeoJSON 中的所有多边形
+func ParseGeoJSON(data []byte) ([]Polygon, error) {
+	var root geoJSON
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("unmarshal geojson failed: %v", err)
+	}
+
+	polygons, err := root.polygons()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(polygons) == 0 {
+		return nil, fmt.Errorf("geojson has no polygon")
+	}
+
+	return polygons, nil
+}
+
+func (g *geoJSON) polygons() ([]Polygon, error) {
+	switch g.Type {
+	case "FeatureCollection":
+		polygons := make([]Polygon, 0, len(g.Features))
+		for i := range g.Features {
+			ps, err := g.Features[i].polygons()
+			if err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, ps...)
+		}
+		return polygons, nil
+
+	case "Feature":
+		if g.Geometry == nil {
+			return nil, nil
+		}
+		return g.Geometry.polygons()
+
+	case "GeometryCollection":
+		polygons := make([]Polygon, 0, len(g.Geometries))
+		for i := range g.Geometries {
+			ps, err := g.Geometries[i].polygons()
+			if err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, ps...)
+		}
+		return polygons, nil
+
+	case "Polygon":
+		var coordinates [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &coordinates); err != nil {
+			return nil, fmt.Errorf("unmarshal polygon coordinates failed: %v", err)
+		}
+
+		polygon, err := toPolygon(coordinates)
+		if err != nil {
+			return nil, err
+		}
+		return []Polygon{polygon}, nil
+
+	case "MultiPolygon":
+		var coordinates [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &coordinates); err != nil {
+			return nil, fmt.Errorf("unmarshal multipolygon coordinates failed: %v", err)
+		}
+
+		polygons := make([]Polygon, 0, len(coordinates))
+		for _, c := range coordinates {
+			polygon, err := toPolygon(c)
+			if err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, polygon)
+		}
+		return polygons, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func toPolygon(coordinates [][][]float64) (Polygon, error) {
+	polygon := make(Polygon, 0, len(coordinates))
+	for _, c := range coordinates {
+		if len(c) < 3 {
+			return nil, fmt.Errorf("polygon ring must have at least 3 points")
+		}
+
+		ring := make(Ring, 0, len(c))
+		for _, position := range c {
+			if len(position) < 2 {
+				return nil, fmt.Errorf("polygon position must have lon and lat")
+			}
+			ring = append(ring, Point{Lon: position[0], Lat: position[1]})
+		}
+		polygon = append(polygon, ring)
+	}
+
+	if len(polygon) == 0 {
+		return nil, fmt.Errorf("polygon has no ring")
+	}
+
+	return polygon, nil
+}
@@ -0,0 +1,117 @@
+package geo
+
+import "math"
+
+const (
+	// 纬度带的默认宽度(度)
+	defaultBandSize = 0.25
+	// 纬度带的最大数量
+	maxBandCount = 4096
+)
+
+// Index 多边形的点包含索引
+// 按纬度将所有的边划分到纬度带中, 射线法判断时只需要遍历点所在纬度带中的边
+type Index struct {
+	bbox     BBox
+	bandSize float64
+	bands    [][]polygonEdges
+}
+
+// polygonEdges 同一个多边形落在某个纬度带中的边
+type polygonEdges struct {
+	polygon int
+	edges   []edge
+}
+
+type edge struct {
+	lon1, lat1 float64
+	lon2, lat2 float64
+}
+
+// NewIndex 为多边形建立索引, 多个多边形之间为并集
+func NewIndex(polygons []Polygon) *Index {
+	index := &Index{bbox: emptyBBox()}
+	for _, polygon := range polygons {
+		b := polygon.BBox()
+		index.bbox = index.bbox.extend(Point{Lon: b.West, Lat: b.South}).extend(Point{Lon: b.East, Lat: b.North})
+	}
+
+	height := index.bbox.North - index.bbox.South
+	if len(polygons) == 0 || height < 0 {
+		return index
+	}
+
+	count := int(math.Ceil(height/defaultBandSize)) + 1
+	count = min(count, maxBandCount)
+	index.bandSize = height / float64(count)
+	if index.bandSize == 0 {
+		index.bandSize = defaultBandSize
+	}
+	index.bands = make([][]polygonEdges, count)
+
+	for id, polygon := range polygons {
+		for _, ring := range polygon {
+			for i := range ring {
+				e := edge{
+					lon1: ring[i].Lon, lat1: ring[i].Lat,
+					lon2: ring[(i+1)%len(ring)].Lon, lat2: ring[(i+1)%len(ring)].Lat,
+				}
+				if e.lat1 == e.lat2 {
+					// 水平边对射线法没有影响
+					continue
+				}
+
+				from := index.band(math.Min(e.lat1, e.lat2))
+				to := index.band(math.Max(e.lat1, e.lat2))
+				for b := from; b <= to; b++ {
+					index.add(b, id, e)
+				}
+			}
+		}
+	}
+
+	return index
+}
+
+// BBox 所有多边形的外包矩形
+func (index *Index) BBox() BBox {
+	return index.bbox
+}
+
+// Contains 点是否在任意一个多边形内
+func (index *Index) Contains(lon, lat float64) bool {
+	if len(index.bands) == 0 || !index.bbox.Contains(lon, lat) {
+		return false
+	}
+
+	for _, group := range index.bands[index.band(lat)] {
+		inside := false
+		for _, e := range group.edges {
+			if (e.lat1 > lat) != (e.lat2 > lat) &&
+				lon < (e.lon2-e.lon1)*(lat-e.lat1)/(e.lat2-e.lat1)+e.lon1 {
+				inside = !inside
+			}
+		}
+
+		if inside {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (index *Index) band(lat float64) int {
+	b := int((lat - index.bbox.South) / index.bandSize)
+	return max(0, min(b, len(index.bands)-1))
+}
+
+func (index *Index) add(band, polygon int, e edge) {
+	groups := index.bands[band]
+	if n := len(groups); n > 0 && groups[n-1].polygon == polygon {
+		groups[n-1].edges = append(groups[n-1].edges, e)
+		return
+	}
+
+	index.bands[band] = append(groups, polygonEdges{polygon: polygon, edges: []edge{e}})
+}
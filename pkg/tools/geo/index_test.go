@@ -0,0 +1,167 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func square(west, south, east, north float64) Ring {
+	return Ring{{west, south}, {east, south}, {east, north}, {west, north}, {west, south}}
+}
+
+// rayContains 不使用纬度带, 遍历所有边的射线法, 用于对比索引的结果
+func rayContains(polygons []Polygon, lon, lat float64) bool {
+	for _, polygon := range polygons {
+		inside := false
+		for _, ring := range polygon {
+			for i := range ring {
+				p1, p2 := ring[i], ring[(i+1)%len(ring)]
+				if (p1.Lat > lat) != (p2.Lat > lat) &&
+					lon < (p2.Lon-p1.Lon)*(lat-p1.Lat)/(p2.Lat-p1.Lat)+p1.Lon {
+					inside = !inside
+				}
+			}
+		}
+
+		if inside {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestIndexContains(t *testing.T) {
+	// 带洞的正方形, U 形的凹多边形, 三角形
+	withHole := Polygon{square(0, 0, 10, 10), square(4, 4, 6, 6)}
+	concave := Polygon{{{20, 0}, {30, 0}, {30, 10}, {27, 10}, {27, 3}, {23, 3}, {23, 10}, {20, 10}}}
+	triangle := Polygon{{{40, 0}, {50, 0}, {45, 10}}}
+	index := NewIndex([]Polygon{withHole, concave, triangle})
+
+	tests := []struct {
+		name     string
+		lon, lat float64
+		want     bool
+	}{
+		{name: "inside", lon: 2, lat: 2, want: true},
+		{name: "inside near hole", lon: 3.99, lat: 5, want: true},
+		{name: "outside bbox", lon: -1, lat: 5, want: false},
+		{name: "outside bbox north", lon: 5, lat: 11, want: false},
+		{name: "inside hole", lon: 5, lat: 5, want: false},
+		{name: "inside hole near edge", lon: 4.01, lat: 5.99, want: false},
+		// 边界上的点: 西侧和南侧的边在多边形内, 东侧和北侧的边在多边形外, 相邻的多边形共用的边只属于一个多边形
+		{name: "west edge", lon: 0, lat: 5, want: true},
+		{name: "south edge", lon: 5, lat: 0, want: true},
+		{name: "east edge", lon: 10, lat: 5, want: false},
+		{name: "north edge", lon: 5, lat: 10, want: false},
+		{name: "south west vertex", lon: 0, lat: 0, want: true},
+		{name: "north east vertex", lon: 10, lat: 10, want: false},
+		{name: "hole west edge", lon: 4, lat: 5, want: false},
+		{name: "hole east edge", lon: 6, lat: 5, want: true},
+		{name: "concave left arm", lon: 21, lat: 8, want: true},
+		{name: "concave right arm", lon: 29, lat: 8, want: true},
+		{name: "concave notch", lon: 25, lat: 8, want: false},
+		{name: "concave base", lon: 25, lat: 1, want: true},
+		{name: "between polygons", lon: 15, lat: 5, want: false},
+		{name: "triangle", lon: 45, lat: 5, want: true},
+		{name: "triangle outside slope", lon: 41, lat: 9, want: false},
+		{name: "triangle slope", lon: 42.5, lat: 5, want: true},
+		{name: "triangle apex", lon: 45, lat: 10, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := index.Contains(tt.lon, tt.lat); got != tt.want {
+			t.Errorf("Contains(%s: %v, %v) = %v, want %v", tt.name, tt.lon, tt.lat, got, tt.want)
+		}
+	}
+}
+
+func TestIndexSharedEdge(t *testing.T) {
+	west := NewIndex([]Polygon{{square(0, 0, 10, 10)}})
+	east := NewIndex([]Polygon{{square(10, 0, 20, 10)}})
+
+	// 共用的边上的点只属于一个多边形, 不会重复输出, 也不会遗漏
+	for _, lat := range []float64{0, 2.5, 5, 9.75} {
+		if west.Contains(10, lat) == east.Contains(10, lat) {
+			t.Errorf("Contains(10, %v) west = %v, east = %v", lat, west.Contains(10, lat), east.Contains(10, lat))
+		}
+	}
+}
+
+func TestIndexMultiPolygon(t *testing.T) {
+	polygons, err := ParseGeoJSON([]byte(`{
+		"type": "Feature",
+		"geometry": {
+			"type": "MultiPolygon",
+			"coordinates": [
+				[[[100, 0], [101, 0], [101, 1], [100, 1], [100, 0]]],
+				[[[102, 2], [103, 2], [103, 3], [102, 3], [102, 2]], [[102.2, 2.2], [102.8, 2.2], [102.8, 2.8], [102.2, 2.8], [102.2, 2.2]]]
+			]
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(polygons) != 2 {
+		t.Fatalf("polygons = %d, want 2", len(polygons))
+	}
+
+	index := NewIndex(polygons)
+	if bbox := index.BBox(); bbox != (BBox{West: 100, South: 0, East: 103, North: 3}) {
+		t.Errorf("BBox() = %+v", bbox)
+	}
+
+	tests := []struct {
+		lon, lat float64
+		want     bool
+	}{
+		{lon: 100.5, lat: 0.5, want: true},
+		{lon: 102.1, lat: 2.5, want: true},
+		{lon: 102.5, lat: 2.5, want: false}, // 第二个多边形的洞
+		{lon: 101.5, lat: 1.5, want: false}, // 两个多边形之间, 在外包矩形内
+		{lon: 100.5, lat: 2.5, want: false},
+		{lon: 104, lat: 2.5, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := index.Contains(tt.lon, tt.lat); got != tt.want {
+			t.Errorf("Contains(%v, %v) = %v, want %v", tt.lon, tt.lat, got, tt.want)
+		}
+	}
+}
+
+func TestIndexBands(t *testing.T) {
+	// 跨越多个纬度带的斜边和凹多边形, 纬度带的边界上也要与遍历所有边的结果一致
+	polygons := []Polygon{
+		{{{0, -40}, {30, 0}, {0, 40}, {-30, 0}}, {{0, -10}, {10, 0}, {0, 10}, {-10, 0}}},
+		{{{40, -30}, {60, -30}, {60, 30}, {50, -10}, {40, 30}}},
+	}
+	index := NewIndex(polygons)
+	if len(index.bands) < 2 {
+		t.Fatalf("bands = %d, want more than 1", len(index.bands))
+	}
+
+	for lat := -41.0; lat <= 41; lat += index.bandSize / 2 {
+		for lon := -31.0; lon <= 61; lon += 0.5 {
+			if got, want := index.Contains(lon, lat), rayContains(polygons, lon, lat); got != want {
+				t.Fatalf("Contains(%v, %v) = %v, want %v", lon, lat, got, want)
+			}
+		}
+	}
+}
+
+func TestIndexEmpty(t *testing.T) {
+	index := NewIndex(nil)
+	if index.Contains(0, 0) {
+		t.Errorf("Contains() of empty index = true")
+	}
+	if bbox := index.BBox(); !math.IsInf(bbox.West, 1) {
+		t.Errorf("BBox() of empty index = %+v", bbox)
+	}
+
+	// 只有一个纬度的退化多边形
+	flat := NewIndex([]Polygon{{{{0, 5}, {10, 5}, {5, 5}}}})
+	if flat.Contains(5, 5) {
+		t.Errorf("Contains() of flat polygon = true")
+	}
+}
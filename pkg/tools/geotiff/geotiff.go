@@ -0,0 +1,241 @@
+package geotiff
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"strconv"
+)
+
+const (
+	// TileSize 内部瓦片的边长(像素)
+	TileSize = 256
+
+	// NoData 缺测值, 写入 GDAL_NODATA 标签
+	NoData = -9999
+)
+
+// Grid EPSG:4326 规则网格, 像素从西北角开始按行从北到南排列
+// West, North 为左上角像素的外边界, DX, DY 为像素的经纬度跨度
+type Grid struct {
+	Width  int
+	Height int
+	West   float64
+	North  float64
+	DX     float64
+	DY     float64
+}
+
+// Band 一个波段, Values 按照网格的行排列, NaN 表示缺测
+type Band struct {
+	Description string
+	Unit        string
+	Values      []float32
+}
+
+// level 原始分辨率或者一级概视图
+type level struct {
+	width, height int
+	bands         [][]float32
+	tiles         [][]byte // 压缩后的瓦片, 按照波段排列, 每个波段按行排列
+}
+
+// WriteFile 写入 Cloud Optimized GeoTIFF
+// 每个 Band 一个波段, float32, deflate 压缩, 内部按 TileSize 分块, 逐级按 2 倍降采样生成概视图直到一个瓦片可以容纳
+// 文件布局: 文件头, 所有 IFD, 从最小的概视图到原始分辨率的瓦片数据
+func WriteFile(path string, g Grid, bands []Band) error {
+	if g.Width <= 0 || g.Height <= 0 {
+		return fmt.Errorf("geotiff size: %dx%d must be greater than 0", g.Width, g.Height)
+	}
+
+	if len(bands) == 0 {
+		return fmt.Errorf("geotiff must have at least 1 band")
+	}
+
+	values := make([][]float32, len(bands))
+	for i, band := range bands {
+		if len(band.Values) != g.Width*g.Height {
+			return fmt.Errorf("geotiff band: %d has %d values, expected: %d", i, len(band.Values), g.Width*g.Height)
+		}
+		values[i] = band.Values
+	}
+
+	levels := []*level{{width: g.Width, height: g.Height, bands: values}}
+	for last := levels[0]; last.width > TileSize || last.height > TileSize; last = levels[len(levels)-1] {
+		levels = append(levels, last.overview())
+	}
+
+	for _, l := range levels {
+		if err := l.compress(); err != nil {
+			return err
+		}
+	}
+
+	ifds := make([]*ifd, len(levels))
+	for i, l := range levels {
+		ifds[i] = newIFD(l, i > 0, len(bands))
+		if i == 0 {
+			ifds[i].geoKeys(g, bands)
+		}
+	}
+
+	// IFD 紧跟在文件头之后, 瓦片数据从最小的概视图开始
+	offset := uint64(8)
+	for _, d := range ifds {
+		offset += d.size()
+	}
+
+	for i := len(levels) - 1; i >= 0; i-- {
+		offsets := make([]uint32, len(levels[i].tiles))
+		counts := make([]uint32, len(levels[i].tiles))
+		for j, tile := range levels[i].tiles {
+			offsets[j], counts[j] = uint32(offset), uint32(len(tile))
+			offset += uint64(len(tile))
+		}
+
+		if offset > math.MaxUint32 {
+			return fmt.Errorf("geotiff size: %d exceeds 4GB", offset)
+		}
+
+		ifds[i].longs(tagTileOffsets, offsets...)
+		ifds[i].longs(tagTileByteCounts, counts...)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create geotiff file: %s failed: %v", path, err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+
+	// 小端序的经典 TIFF 文件头, 第一个 IFD 在文件头之后
+	w.Write([]byte{'I', 'I', 42, 0, 8, 0, 0, 0})
+
+	offset = 8
+	for i, d := range ifds {
+		next := uint32(0)
+		if i+1 < len(ifds) {
+			next = uint32(offset + d.size())
+		}
+		w.Write(d.encode(uint32(offset), next))
+		offset += d.size()
+	}
+
+	for i := len(levels) - 1; i >= 0; i-- {
+		for _, tile := range levels[i].tiles {
+			w.Write(tile)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("write geotiff file: %s failed: %v", path, err)
+	}
+
+	return nil
+}
+
+// overview 按 2x2 取平均生成下一级概视图, 缺测值不参与平均
+func (l *level) overview() *level {
+	width, height := (l.width+1)/2, (l.height+1)/2
+
+	bands := make([][]float32, len(l.bands))
+	for b, values := range l.bands {
+		bands[b] = make([]float32, width*height)
+		for y := range height {
+			for x := range width {
+				var (
+					sum   float64
+					count int
+				)
+				for dy := range 2 {
+					for dx := range 2 {
+						sy, sx := y*2+dy, x*2+dx
+						if sy >= l.height || sx >= l.width {
+							continue
+						}
+
+						v := values[sy*l.width+sx]
+						if math.IsNaN(float64(v)) {
+							continue
+						}
+						sum += float64(v)
+						count++
+					}
+				}
+
+				bands[b][y*width+x] = float32(math.NaN())
+				if count > 0 {
+					bands[b][y*width+x] = float32(sum / float64(count))
+				}
+			}
+		}
+	}
+
+	return &level{width: width, height: height, bands: bands}
+}
+
+// compress 将每个波段切分为瓦片后使用 deflate 压缩, 边缘不足的部分填充缺测值
+func (l *level) compress() error {
+	across, down := tiles(l.width), tiles(l.height)
+
+	raw := make([]byte, TileSize*TileSize*4)
+	for _, values := range l.bands {
+		for ty := range down {
+			for tx := range across {
+				for y := range TileSize {
+					for x := range TileSize {
+						v := float32(NoData)
+						sy, sx := ty*TileSize+y, tx*TileSize+x
+						if sy < l.height && sx < l.width && !math.IsNaN(float64(values[sy*l.width+sx])) {
+							v = values[sy*l.width+sx]
+						}
+						binary.LittleEndian.PutUint32(raw[(y*TileSize+x)*4:], math.Float32bits(v))
+					}
+				}
+
+				var buf bytes.Buffer
+				zw := zlib.NewWriter(&buf)
+				if _, err := zw.Write(raw); err != nil {
+					return fmt.Errorf("compress geotiff tile failed: %v", err)
+				}
+				if err := zw.Close(); err != nil {
+					return fmt.Errorf("compress geotiff tile failed: %v", err)
+				}
+				l.tiles = append(l.tiles, buf.Bytes())
+			}
+		}
+	}
+
+	return nil
+}
+
+func tiles(pixels int) int {
+	return (pixels + TileSize - 1) / TileSize
+}
+
+// metadata GDAL_METADATA 标签, 记录每个波段的描述和单位
+func metadata(bands []Band) string {
+	var buf bytes.Buffer
+	buf.WriteString("<GDALMetadata>")
+	for i, band := range bands {
+		if band.Description != "" {
+			fmt.Fprintf(&buf, `<Item name="DESCRIPTION" sample="%d" role="description">%s</Item>`, i, html.EscapeString(band.Description))
+		}
+		if band.Unit != "" {
+			fmt.Fprintf(&buf, `<Item name="UNITTYPE" sample="%d" role="unittype">%s</Item>`, i, html.EscapeString(band.Unit))
+		}
+	}
+	buf.WriteString("</GDALMetadata>")
+
+	return buf.String()
+}
+
+func noData() string {
+	return strconv.Itoa(NoData)
+}
@@ -0,0 +1,202 @@
+package geotiff
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// TIFF 标签
+const (
+	tagNewSubfileType       = 254
+	tagImageWidth           = 256
+	tagImageLength          = 257
+	tagBitsPerSample        = 258
+	tagCompression          = 259
+	tagPhotometric          = 262
+	tagSamplesPerPixel      = 277
+	tagPlanarConfiguration  = 284
+	tagTileWidth            = 322
+	tagTileLength           = 323
+	tagTileOffsets          = 324
+	tagTileByteCounts       = 325
+	tagExtraSamples         = 338
+	tagSampleFormat         = 339
+	tagModelPixelScale      = 33550
+	tagModelTiepoint        = 33922
+	tagGeoKeyDirectory      = 34735
+	tagGDALMetadata         = 42112
+	tagGDALNoData           = 42113
+	compressionDeflate      = 8
+	photometricMinIsBlack   = 1
+	planarContig            = 1
+	planarSeparate          = 2
+	sampleFormatIEEEFP      = 3
+	subfileReducedImage     = 1
+	extraSampleUnspecified  = 0
+	modelTypeGeographic     = 2
+	rasterPixelIsArea       = 1
+	geographicTypeWGS84     = 4326
+	geoKeyModelType         = 1024
+	geoKeyRasterType        = 1025
+	geoKeyGeographicType    = 2048
+	geoKeyDirectoryVersion  = 1
+	geoKeyDirectoryRevision = 1
+)
+
+// TIFF 字段类型
+const (
+	typeASCII  = 2
+	typeShort  = 3
+	typeLong   = 4
+	typeDouble = 12
+)
+
+type entry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+// ifd 一个图像的标签目录, 超过 4 字节的值写在目录之后
+type ifd struct {
+	entries []entry
+}
+
+func newIFD(l *level, reduced bool, samples int) *ifd {
+	d := &ifd{}
+	if reduced {
+		d.longs(tagNewSubfileType, subfileReducedImage)
+	}
+	d.longs(tagImageWidth, uint32(l.width))
+	d.longs(tagImageLength, uint32(l.height))
+	d.shorts(tagBitsPerSample, repeat(32, samples)...)
+	d.shorts(tagCompression, compressionDeflate)
+	d.shorts(tagPhotometric, photometricMinIsBlack)
+	d.shorts(tagSamplesPerPixel, uint16(samples))
+	if samples > 1 {
+		d.shorts(tagPlanarConfiguration, planarSeparate)
+		d.shorts(tagExtraSamples, repeat(extraSampleUnspecified, samples-1)...)
+	} else {
+		d.shorts(tagPlanarConfiguration, planarContig)
+	}
+	d.longs(tagTileWidth, TileSize)
+	d.longs(tagTileLength, TileSize)
+	d.shorts(tagSampleFormat, repeat(sampleFormatIEEEFP, samples)...)
+	d.ascii(tagGDALNoData, noData())
+
+	// 占位, 写入前替换为实际的偏移
+	count := tiles(l.width) * tiles(l.height) * samples
+	d.longs(tagTileOffsets, make([]uint32, count)...)
+	d.longs(tagTileByteCounts, make([]uint32, count)...)
+
+	return d
+}
+
+// geoKeys 写入 EPSG:4326 的地理参考和波段描述, 只写在原始分辨率的 IFD 中
+func (d *ifd) geoKeys(g Grid, bands []Band) {
+	d.doubles(tagModelPixelScale, g.DX, g.DY, 0)
+	d.doubles(tagModelTiepoint, 0, 0, 0, g.West, g.North, 0)
+	d.shorts(tagGeoKeyDirectory,
+		geoKeyDirectoryVersion, geoKeyDirectoryRevision, 0, 3,
+		geoKeyModelType, 0, 1, modelTypeGeographic,
+		geoKeyRasterType, 0, 1, rasterPixelIsArea,
+		geoKeyGeographicType, 0, 1, geographicTypeWGS84,
+	)
+	d.ascii(tagGDALMetadata, metadata(bands))
+}
+
+func (d *ifd) shorts(tag uint16, values ...uint16) {
+	data := make([]byte, len(values)*2)
+	for i, v := range values {
+		binary.LittleEndian.PutUint16(data[i*2:], v)
+	}
+	d.set(entry{tag: tag, typ: typeShort, count: uint32(len(values)), data: data})
+}
+
+func (d *ifd) longs(tag uint16, values ...uint32) {
+	data := make([]byte, len(values)*4)
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[i*4:], v)
+	}
+	d.set(entry{tag: tag, typ: typeLong, count: uint32(len(values)), data: data})
+}
+
+func (d *ifd) doubles(tag uint16, values ...float64) {
+	data := make([]byte, len(values)*8)
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(data[i*8:], math.Float64bits(v))
+	}
+	d.set(entry{tag: tag, typ: typeDouble, count: uint32(len(values)), data: data})
+}
+
+func (d *ifd) ascii(tag uint16, value string) {
+	data := append([]byte(value), 0)
+	d.set(entry{tag: tag, typ: typeASCII, count: uint32(len(data)), data: data})
+}
+
+// set 添加或替换标签, 标签按照升序排列
+func (d *ifd) set(e entry) {
+	for i := range d.entries {
+		if d.entries[i].tag == e.tag {
+			d.entries[i] = e
+			return
+		}
+	}
+
+	d.entries = append(d.entries, e)
+	sort.Slice(d.entries, func(i, j int) bool { return d.entries[i].tag < d.entries[j].tag })
+}
+
+// size 目录和目录之后的值占用的字节数
+func (d *ifd) size() uint64 {
+	size := uint64(2 + len(d.entries)*12 + 4)
+	for _, e := range d.entries {
+		if len(e.data) > 4 {
+			size += uint64(align(len(e.data)))
+		}
+	}
+
+	return size
+}
+
+// encode 按照目录在文件中的偏移编码, next 为下一个目录的偏移, 0 表示最后一个
+func (d *ifd) encode(offset, next uint32) []byte {
+	buf := make([]byte, 2+len(d.entries)*12+4)
+	binary.LittleEndian.PutUint16(buf, uint16(len(d.entries)))
+
+	external := offset + uint32(len(buf))
+	var values []byte
+	for i, e := range d.entries {
+		p := buf[2+i*12:]
+		binary.LittleEndian.PutUint16(p, e.tag)
+		binary.LittleEndian.PutUint16(p[2:], e.typ)
+		binary.LittleEndian.PutUint32(p[4:], e.count)
+		if len(e.data) <= 4 {
+			copy(p[8:12], e.data)
+			continue
+		}
+
+		binary.LittleEndian.PutUint32(p[8:], external+uint32(len(values)))
+		values = append(values, e.data...)
+		values = append(values, make([]byte, align(len(e.data))-len(e.data))...)
+	}
+	binary.LittleEndian.PutUint32(buf[2+len(d.entries)*12:], next)
+
+	return append(buf, values...)
+}
+
+// align 值的偏移需要按字对齐
+func align(n int) int {
+	return (n + 1) &^ 1
+}
+
+func repeat(v uint16, n int) []uint16 {
+	values := make([]uint16, n)
+	for i := range values {
+		values[i] = v
+	}
+
+	return values
+}
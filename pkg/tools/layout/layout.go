@@ -0,0 +1,150 @@
+package layout
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeLayout {cycle} 和 {run} 没有指定格式时使用的时间格式
+const DefaultTimeLayout = "2006010215"
+
+// Values 模板中变量的值
+type Values struct {
+	Dataset string
+	Time    time.Time // 文件对应的起报时次
+	Run     time.Time // 模式的起报时刻, 为空时与 Time 相同, 例如: EC 每 12 小时起报一次, 每 3 小时一个文件
+	Ext     string    // 扩展名, 不含 .
+	Profile string    // 输出方案名称, 默认输出为空
+}
+
+// part 模板的一段, name 为空时为字面值
+type part struct {
+	literal string
+	name    string
+	layout  string
+}
+
+// Template 文件路径模板, 变量格式: {name} 或 {name:layout}, layout 为 Go 的时间格式
+// 支持的变量:
+//
+//	{year}, {month}, {day}, {hour}: 起报时次的年, 月(01), 日(01), 时(00)
+//	{date}: 起报时次的日期, 2006-01-02
+//	{cycle:layout}: 起报时次, 默认格式 2006010215
+//	{run:layout}: 模式的起报时刻, 默认格式 2006010215
+//	{step}: 起报时次相对模式起报时刻的小时数, 不补零, 起报时次早于模式起报时刻时为负数
+//	{dataset}: 数据源名称, 例如: ec, mfwam, smoc, combined
+//	{ext}: 扩展名, 不含 .
+//	{profile}: 输出方案名称, 默认输出为空
+type Template struct {
+	raw   string
+	parts []part
+}
+
+var timeVariables = map[string]bool{"cycle": true, "run": true}
+
+var variables = map[string]bool{
+	"year": true, "month": true, "day": true, "hour": true, "date": true,
+	"cycle": true, "run": true, "step": true, "dataset": true, "ext": true,
+	"profile": true,
+}
+
+// Parse 解析模板, 未知的变量和不成对的括号会返回错误
+func Parse(raw string) (*Template, error) {
+	t := &Template{raw: raw}
+
+	rest := raw
+	for rest != "" {
+		start := strings.IndexAny(rest, "{}")
+		if start < 0 {
+			t.parts = append(t.parts, part{literal: rest})
+			break
+		}
+
+		if rest[start] == '}' {
+			return nil, fmt.Errorf("layout: %s has unmatched }", raw)
+		}
+
+		if start > 0 {
+			t.parts = append(t.parts, part{literal: rest[:start]})
+		}
+
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("layout: %s has unmatched {", raw)
+		}
+
+		name, layout, _ := strings.Cut(rest[start+1:start+end], ":")
+		if !variables[name] {
+			return nil, fmt.Errorf("layout: %s has unknown variable: %s", raw, name)
+		}
+
+		if layout != "" && !timeVariables[name] {
+			return nil, fmt.Errorf("layout: %s variable: %s does not accept a format", raw, name)
+		}
+
+		if layout == "" && timeVariables[name] {
+			layout = DefaultTimeLayout
+		}
+
+		t.parts = append(t.parts, part{name: name, layout: layout})
+		rest = rest[start+end+1:]
+	}
+
+	if len(t.parts) == 0 {
+		return nil, fmt.Errorf("layout is empty")
+	}
+
+	return t, nil
+}
+
+// HasSuffix 模板是否以字面值 literal 加上变量 name 结尾, 例如: ".", "ext"
+func (t *Template) HasSuffix(literal, name string) bool {
+	n := len(t.parts)
+	return n >= 2 && t.parts[n-1].name == name && strings.HasSuffix(t.parts[n-2].literal, literal)
+}
+
+func (t *Template) String() string {
+	return t.raw
+}
+
+// Execute 按照 values 替换模板中的变量
+func (t *Template) Execute(v Values) string {
+	run := v.Run
+	if run.IsZero() {
+		run = v.Time
+	}
+
+	var b strings.Builder
+	for _, p := range t.parts {
+		switch p.name {
+		case "":
+			b.WriteString(p.literal)
+		case "year":
+			b.WriteString(strconv.Itoa(v.Time.Year()))
+		case "month":
+			fmt.Fprintf(&b, "%02d", v.Time.Month())
+		case "day":
+			fmt.Fprintf(&b, "%02d", v.Time.Day())
+		case "hour":
+			fmt.Fprintf(&b, "%02d", v.Time.Hour())
+		case "date":
+			b.WriteString(v.Time.Format(time.DateOnly))
+		case "cycle":
+			b.WriteString(v.Time.Format(p.layout))
+		case "run":
+			b.WriteString(run.Format(p.layout))
+		case "step":
+			b.WriteString(strconv.Itoa(int(v.Time.Sub(run).Hours())))
+		case "dataset":
+			b.WriteString(v.Dataset)
+		case "ext":
+			b.WriteString(v.Ext)
+		case "profile":
+			b.WriteString(v.Profile)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package layout
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{
+		{name: "literal", raw: "ec.csv"},
+		{name: "variables", raw: "{dataset}/{year}/{month}/{day}/{hour}/{date}/{step}/{profile}.{ext}"},
+		{name: "time format", raw: "{run:20060102}/{cycle:2006010215}.{ext}"},
+		{name: "empty format", raw: "{cycle:}.{ext}"},
+		{name: "empty", raw: "", wantErr: "layout is empty"},
+		{name: "unmatched open", raw: "{dataset/{cycle}", wantErr: "unknown variable: dataset/{cycle"},
+		{name: "unclosed", raw: "ec_{cycle", wantErr: "unmatched {"},
+		{name: "unmatched close", raw: "ec_cycle}.csv", wantErr: "unmatched }"},
+		{name: "unmatched close after variable", raw: "{cycle}}", wantErr: "unmatched }"},
+		{name: "empty variable", raw: "ec_{}.csv", wantErr: "unknown variable: "},
+		{name: "unknown variable", raw: "{dataset}_{time}.csv", wantErr: "unknown variable: time"},
+		{name: "case sensitive", raw: "{Dataset}.csv", wantErr: "unknown variable: Dataset"},
+		{name: "format on non-time variable", raw: "{year:2006}/{cycle}.csv", wantErr: "variable: year does not accept a format"},
+		{name: "format on step", raw: "{step:03}.csv", wantErr: "variable: step does not accept a format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := Parse(tt.raw)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Parse(%q) error = %v", tt.raw, err)
+				}
+				if template.String() != tt.raw {
+					t.Errorf("String() = %q, want %q", template.String(), tt.raw)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse(%q) error = %v, want %s", tt.raw, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecute(t *testing.T) {
+	cycle := time.Date(2025, 6, 3, 15, 0, 0, 0, time.UTC)
+	run := time.Date(2025, 6, 3, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		raw    string
+		values Values
+		want   string
+	}{
+		{name: "default cycle format", raw: "{dataset}_{cycle}.{ext}", values: Values{Dataset: "ec", Time: cycle, Ext: "csv"}, want: "ec_2025060315.csv"},
+		{name: "empty format uses default", raw: "{cycle:}", values: Values{Time: cycle}, want: "2025060315"},
+		{name: "cycle format", raw: "{cycle:2006-01-02T15}", values: Values{Time: cycle}, want: "2025-06-03T15"},
+		{name: "date parts", raw: "{year}/{month}/{day}/{hour}/{date}", values: Values{Time: cycle}, want: "2025/06/03/15/2025-06-03"},
+		{name: "run and step", raw: "{run}/{dataset}_{step}", values: Values{Dataset: "ec", Time: cycle, Run: run}, want: "2025060312/ec_3"},
+		{name: "run format", raw: "{run:20060102}_{hour}", values: Values{Time: cycle, Run: run}, want: "20250603_15"},
+		// 模式起报时刻为空时与起报时次相同, 步长为 0
+		{name: "zero step", raw: "{run}_{step}", values: Values{Time: cycle}, want: "2025060315_0"},
+		{name: "same run", raw: "{step}", values: Values{Time: run, Run: run}, want: "0"},
+		// 起报时次早于模式起报时刻时步长为负数
+		{name: "negative step", raw: "{step}", values: Values{Time: run, Run: cycle}, want: "-3"},
+		{name: "step over days", raw: "{step}", values: Values{Time: run.Add(240 * time.Hour), Run: run}, want: "240"},
+		{name: "profile", raw: "{profile}/{dataset}.{ext}", values: Values{Dataset: "smoc", Profile: "coast", Ext: "parquet"}, want: "coast/smoc.parquet"},
+		{name: "empty profile", raw: "{profile}/{dataset}.{ext}", values: Values{Dataset: "smoc", Ext: "parquet"}, want: "/smoc.parquet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := Parse(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := template.Execute(tt.values); got != tt.want {
+				t.Errorf("Execute() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasSuffix(t *testing.T) {
+	tests := []struct {
+		raw           string
+		literal, name string
+		want          bool
+	}{
+		{raw: "{dataset}_{cycle}.{ext}", literal: ".", name: "ext", want: true},
+		{raw: "{dataset}/{cycle}.{ext}", literal: "/{cycle}.", name: "ext", want: false},
+		{raw: "{cycle}_data.{ext}", literal: "_data.", name: "ext", want: true},
+		{raw: "{dataset}_{cycle}.csv", literal: ".", name: "ext", want: false},
+		{raw: "{dataset}_{cycle}{ext}", literal: ".", name: "ext", want: false},
+		{raw: "{dataset}_{cycle}.{ext}.bak", literal: ".", name: "ext", want: false},
+		{raw: "{ext}", literal: ".", name: "ext", want: false},
+		{raw: ".{ext}", literal: ".", name: "ext", want: true},
+		{raw: "{dataset}_{cycle}.{ext}", literal: "_", name: "cycle", want: false},
+	}
+
+	for _, tt := range tests {
+		template, err := Parse(tt.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := template.HasSuffix(tt.literal, tt.name); got != tt.want {
+			t.Errorf("Parse(%q).HasSuffix(%q, %q) = %v, want %v", tt.raw, tt.literal, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package nc
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// ArchiveCodec csv 与元数据的打包方式
+type ArchiveCodec string
+
+const (
+	ArchiveZip  ArchiveCodec = "zip"  // zip, deflate 压缩
+	ArchiveGzip ArchiveCodec = "gzip" // tar.gz
+	ArchiveZstd ArchiveCodec = "zstd" // tar.zst, 解压比 gzip 快
+	ArchiveXz   ArchiveCodec = "xz"   // tar.xz, 压缩率最高, 压缩最慢
+	ArchiveNone ArchiveCodec = "none" // 不压缩的 tar
+)
+
+// archiver 打包方式对应的扩展名和写入方式
+type archiver struct {
+	ext      string
+	compress func(w io.Writer, level int) (io.WriteCloser, error) // 为空时写入 zip
+}
+
+var archivers = map[ArchiveCodec]archiver{
+	"":          {ext: ".zip"},
+	ArchiveZip:  {ext: ".zip"},
+	ArchiveGzip: {ext: ".tar.gz", compress: gzipWriter},
+	ArchiveZstd: {ext: ".tar.zst", compress: zstdWriter},
+	ArchiveXz:   {ext: ".tar.xz", compress: xzWriter},
+	ArchiveNone: {ext: ".tar", compress: func(w io.Writer, level int) (io.WriteCloser, error) { return nopCloser{w}, nil }},
+}
+
+// CheckArchive 检查打包方式和压缩级别是否支持
+func CheckArchive(codec ArchiveCodec, level int) error {
+	if _, ok := archivers[codec]; !ok {
+		return fmt.Errorf("archive codec: %s not supported", codec)
+	}
+
+	if level < 0 || level > 9 {
+		return fmt.Errorf("archive level: %d must be between 0 and 9", level)
+	}
+
+	if codec == ArchiveNone && level != 0 {
+		return fmt.Errorf("archive level: %d is not supported by archive codec: %s", level, codec)
+	}
+
+	return nil
+}
+
+// archiveExt 打包后的扩展名, 例如: .zip, .tar.zst
+func archiveExt(codec ArchiveCodec) string {
+	return archivers[codec].ext
+}
+
+// archiveFile 将 src 与元数据一起打包到 dst, 完成后删除 src
+// 先写入 {dst}.tmp, 全部写入并关闭成功后重命名, 中断时不会留下不完整的 dst
+func archiveFile(src, dst string, metadata []byte, codec ArchiveCodec, level int) error {
+	a, ok := archivers[codec]
+	if !ok {
+		return fmt.Errorf("archive codec: %s not supported", codec)
+	}
+
+	tmp := dst + ".tmp"
+
+	var err error
+	if a.compress == nil {
+		err = zipFile(src, tmp, metadata, level)
+	} else {
+		err = tarFile(src, tmp, metadata, func(w io.Writer) (io.WriteCloser, error) { return a.compress(w, level) })
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rename archive file: %s failed: %v", tmp, err)
+	}
+
+	return nil
+}
+
+// src: /data1/cosco-generate-files/2025/06/2025-06-13/ec_2025061315.csv
+// dst: /data1/cosco-generate-files/2025/06/2025-06-13/ec_2025061315.zip
+// metadata 不为空时同时写入 metadata.json
+func zipFile(src, dst string, metadata []byte, level int) error {
+	defer os.Remove(src)
+
+	// 创建目标 zip 文件
+	zipFile, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("create zip file: %s failed: %v", dst, err)
+	}
+	defer zipFile.Close()
+
+	// 创建 zip writer, 目录在 Close 时写入, 需要检查 Close 的错误
+	zipWriter := zip.NewWriter(zipFile)
+
+	// 设置压缩级别
+	zipWriter.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, deflateLevel(level))
+	})
+
+	// 打开源文件
+	fileToZip, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open source file: %s failed: %v", src, err)
+	}
+	defer fileToZip.Close()
+
+	// 获取源文件信息
+	info, err := fileToZip.Stat()
+	if err != nil {
+		return fmt.Errorf("get file info failed: %v", err)
+	}
+
+	// 创建 zip 文件头
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return fmt.Errorf("create zip header failed: %v", err)
+	}
+
+	// 设置压缩方法
+	header.Method = zip.Deflate
+
+	// 创建 zip 文件写入器
+	writer, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("create zip writer failed: %v", err)
+	}
+
+	// 将源文件内容复制到 zip 文件中
+	_, err = io.Copy(writer, fileToZip)
+	if err != nil {
+		return fmt.Errorf("copy file content failed: %v", err)
+	}
+
+	// 写入元数据文件
+	if metadata != nil {
+		metaWriter, err := zipWriter.CreateHeader(&zip.FileHeader{Name: MetadataName, Method: zip.Deflate, Modified: info.ModTime()})
+		if err != nil {
+			return fmt.Errorf("create metadata writer failed: %v", err)
+		}
+
+		if _, err := metaWriter.Write(metadata); err != nil {
+			return fmt.Errorf("write metadata failed: %v", err)
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("close zip writer failed: %v", err)
+	}
+
+	if err := zipFile.Close(); err != nil {
+		return fmt.Errorf("close zip file: %s failed: %v", dst, err)
+	}
+
+	return nil
+}
+
+// tarFile 将 src 与 metadata.json 写入 tar 后按照 compress 压缩
+func tarFile(src, dst string, metadata []byte, compress func(w io.Writer) (io.WriteCloser, error)) error {
+	defer os.Remove(src)
+
+	fileToTar, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open source file: %s failed: %v", src, err)
+	}
+	defer fileToTar.Close()
+
+	info, err := fileToTar.Stat()
+	if err != nil {
+		return fmt.Errorf("get file info failed: %v", err)
+	}
+
+	file, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("create archive file: %s failed: %v", dst, err)
+	}
+	defer file.Close()
+
+	cw, err := compress(file)
+	if err != nil {
+		return fmt.Errorf("create compressor failed: %v", err)
+	}
+
+	tw := tar.NewWriter(cw)
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return fmt.Errorf("create tar header failed: %v", err)
+	}
+	header.Name = filepath.Base(src)
+
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("write tar header failed: %v", err)
+	}
+
+	if _, err := io.Copy(tw, fileToTar); err != nil {
+		return fmt.Errorf("copy file content failed: %v", err)
+	}
+
+	if metadata != nil {
+		if err := tw.WriteHeader(&tar.Header{Name: MetadataName, Mode: 0644, Size: int64(len(metadata)), ModTime: info.ModTime()}); err != nil {
+			return fmt.Errorf("write metadata header failed: %v", err)
+		}
+
+		if _, err := tw.Write(metadata); err != nil {
+			return fmt.Errorf("write metadata failed: %v", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("close tar writer failed: %v", err)
+	}
+
+	if err := cw.Close(); err != nil {
+		return fmt.Errorf("close compressor failed: %v", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("close archive file: %s failed: %v", dst, err)
+	}
+
+	return nil
+}
+
+// deflateLevel 0 表示默认级别
+func deflateLevel(level int) int {
+	if level == 0 {
+		return flate.DefaultCompression
+	}
+
+	return level
+}
+
+func gzipWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, deflateLevel(level))
+}
+
+// zstdWriter 级别按照 zstd 命令行的级别映射: 1 最快, 2~4 默认, 5~7 较好, 8~9 最好, 0 表示默认级别
+func zstdWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	if level == 0 {
+		return zstd.NewWriter(w)
+	}
+
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+}
+
+// xzDictCaps xz 命令行每个预设级别的字典大小, 级别越高压缩率越高, 占用的内存越多
+var xzDictCaps = [...]int{256 << 10, 1 << 20, 2 << 20, 4 << 20, 4 << 20, 8 << 20, 8 << 20, 16 << 20, 32 << 20, 64 << 20}
+
+// xzWriter 级别按照 xz 命令行的预设映射字典大小, 0 表示默认级别
+func xzWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	if level == 0 {
+		return xz.NewWriter(w)
+	}
+
+	return xz.WriterConfig{DictCap: xzDictCaps[level]}.NewWriter(w)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
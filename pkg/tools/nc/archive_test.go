@@ -0,0 +1,182 @@
+package nc
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+func TestCheckArchive(t *testing.T) {
+	tests := []struct {
+		codec   ArchiveCodec
+		level   int
+		wantErr bool
+	}{
+		{codec: "", level: 0},
+		{codec: ArchiveZip, level: 9},
+		{codec: ArchiveGzip, level: 1},
+		{codec: ArchiveZstd, level: 9},
+		{codec: ArchiveXz, level: 9},
+		{codec: ArchiveNone, level: 0},
+		{codec: ArchiveNone, level: 1, wantErr: true},
+		{codec: ArchiveZip, level: 10, wantErr: true},
+		{codec: ArchiveZip, level: -1, wantErr: true},
+		{codec: "bzip2", level: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := CheckArchive(tt.codec, tt.level); (err != nil) != tt.wantErr {
+			t.Errorf("CheckArchive(%q, %d) error = %v, want error %v", tt.codec, tt.level, err, tt.wantErr)
+		}
+	}
+}
+
+// readTar 解压 tar 包, 返回每个文件的内容
+func readTar(t *testing.T, path string, codec ArchiveCodec) map[string]string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	switch codec {
+	case ArchiveGzip:
+		gr, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gr
+	case ArchiveZstd:
+		zr, err := zstd.NewReader(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	case ArchiveXz:
+		xr, err := xz.NewReader(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = xr
+	}
+
+	files := make(map[string]string)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = string(data)
+	}
+
+	return files
+}
+
+func TestArchiveFileLevels(t *testing.T) {
+	var b strings.Builder
+	for i := range 20000 {
+		fmt.Fprintf(&b, "2025-06-13 00:00:00,%.2f,%.2f,%d\n", float64(i%180)-90, float64(i%360)-180, i%7)
+	}
+	csv := b.String()
+
+	for _, codec := range []ArchiveCodec{ArchiveGzip, ArchiveZstd, ArchiveXz, ArchiveNone} {
+		sizes := make(map[int]int64)
+		for _, level := range []int{0, 1, 9} {
+			if CheckArchive(codec, level) != nil {
+				continue
+			}
+
+			t.Run(fmt.Sprintf("%s_%d", codec, level), func(t *testing.T) {
+				dir := t.TempDir()
+				src := filepath.Join(dir, "ec_2025061300.csv")
+				dst := filepath.Join(dir, "ec_2025061300"+archiveExt(codec))
+				if err := os.WriteFile(src, []byte(csv), 0644); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := archiveFile(src, dst, []byte(`{}`), codec, level); err != nil {
+					t.Fatal(err)
+				}
+
+				// 打包后删除 csv, 不留下临时文件
+				for _, path := range []string{src, dst + ".tmp"} {
+					if _, err := os.Stat(path); !os.IsNotExist(err) {
+						t.Errorf("file: %s exists after archive", path)
+					}
+				}
+
+				files := readTar(t, dst, codec)
+				if files["ec_2025061300.csv"] != csv || files[MetadataName] != "{}" {
+					t.Errorf("archive files = %d, csv size = %d, metadata = %q", len(files), len(files["ec_2025061300.csv"]), files[MetadataName])
+				}
+
+				stat, err := os.Stat(dst)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sizes[level] = stat.Size()
+			})
+		}
+
+		// 压缩级别生效: 最高级别比最低级别压缩得更小, xz 的级别只影响字典大小, 在 TestXzLevel 中检查
+		if (codec == ArchiveGzip || codec == ArchiveZstd) && sizes[9] >= sizes[1] {
+			t.Errorf("%s level 9 size: %d, level 1 size: %d", codec, sizes[9], sizes[1])
+		}
+	}
+}
+
+func TestXzLevel(t *testing.T) {
+	if testing.Short() {
+		t.Skip("xz compression is slow")
+	}
+
+	// 相隔超过 1MiB 的重复内容, 字典足够大时才能压缩
+	block := make([]byte, 1100<<10)
+	if _, err := rand.Read(block); err != nil {
+		t.Fatal(err)
+	}
+	data := append(block, block...)
+
+	sizes := make(map[int]int)
+	for _, level := range []int{1, 9} {
+		var buf bytes.Buffer
+		w, err := xzWriter(&buf, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		sizes[level] = buf.Len()
+	}
+
+	if sizes[9] >= len(block)+len(block)/2 || sizes[1] < len(data) {
+		t.Errorf("xz level 1 size: %d, level 9 size: %d, data size: %d", sizes[1], sizes[9], len(data))
+	}
+}
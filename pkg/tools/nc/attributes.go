@@ -0,0 +1,54 @@
+package nc
+
+// columnAttribute 列的原始单位和说明, 写入元数据
+type columnAttribute struct {
+	unit        string // 原始单位(国际单位), 为空表示没有单位, 例如分类值
+	description string
+}
+
+// 所有数据源和派生列的属性, 键为列名
+var columnAttributes = map[string]columnAttribute{
+	// EC
+	"wind10mU":        {"m/s", "10 米风的东西分量, 向东为正"},
+	"wind10mV":        {"m/s", "10 米风的南北分量, 向北为正"},
+	"temperature2m":   {"K", "2 米气温"},
+	"surfacePressure": {"Pa", "地面气压"},
+
+	// MFWAM
+	"seaWaveHeight":      {"m", "海浪有效波高(VHM0)"},
+	"seaWaveDirection":   {"deg", "海浪平均方向(VMDR), 浪的来向, 正北为 0 度顺时针"},
+	"seaWavePeriod":      {"s", "海浪平均周期(VTM10)"},
+	"swellWaveHeight":    {"m", "涌浪有效波高(VHM0_SW1)"},
+	"swellWaveDirection": {"deg", "涌浪平均方向(VMDR_SW1), 浪的来向, 正北为 0 度顺时针"},
+	"swellWavePeriod":    {"s", "涌浪平均周期(VTM01_SW1)"},
+	"windWaveHeight":     {"m", "风浪有效波高(VHM0_WW)"},
+	"windWaveDirection":  {"deg", "风浪平均方向(VMDR_WW), 浪的来向, 正北为 0 度顺时针"},
+	"windWavePeriod":     {"s", "风浪平均周期(VTM01_WW)"},
+
+	// SMOC
+	"uCurrent":     {"m/s", "表层流的东西分量(总流), 向东为正"},
+	"vCurrent":     {"m/s", "表层流的南北分量(总流), 向北为正"},
+	"uTideCurrent": {"m/s", "表层潮流的东西分量, 向东为正"},
+	"vTideCurrent": {"m/s", "表层潮流的南北分量, 向北为正"},
+
+	// 派生列
+	"windSpeed":                 {"m/s", "10 米风速"},
+	"windSpeedKnots":            {"kn", "10 米风速(节)"},
+	"windDirection":             {"deg", "10 米风向, 风的来向, 正北为 0 度顺时针"},
+	"beaufort":                  {"", "蒲福风级(0~12)"},
+	"douglasSeaState":           {"", "道格拉斯风浪等级(0~9)"},
+	"wmoSeaState":               {"", "WMO 海况代码(代码表 3700, 0~9)"},
+	"waveSteepness":             {"", "波陡, 有效波高与深水波长之比"},
+	"waveSteepnessClass":        {"", "波陡等级, 0: 平缓, 1: 一般, 2: 陡, 3: 很陡"},
+	"currentSpeed":              {"m/s", "总流流速"},
+	"currentSpeedKnots":         {"kn", "总流流速(节)"},
+	"currentDirection":          {"deg", "总流流向, 流的去向, 正北为 0 度顺时针"},
+	"tideCurrentSpeed":          {"m/s", "潮流流速"},
+	"tideCurrentSpeedKnots":     {"kn", "潮流流速(节)"},
+	"tideCurrentDirection":      {"deg", "潮流流向, 流的去向, 正北为 0 度顺时针"},
+	"uResidualCurrent":          {"m/s", "余流(总流减去潮流)的东西分量, 向东为正"},
+	"vResidualCurrent":          {"m/s", "余流(总流减去潮流)的南北分量, 向北为正"},
+	"residualCurrentSpeed":      {"m/s", "余流流速"},
+	"residualCurrentSpeedKnots": {"kn", "余流流速(节)"},
+	"residualCurrentDirection":  {"deg", "余流流向, 流的去向, 正北为 0 度顺时针"},
+}
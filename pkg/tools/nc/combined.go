@@ -0,0 +1,406 @@
+package nc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gen-meteo-file/pkg/tools/regrid"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CombinedECName    = "ec"
+	CombinedMFWAMName = "mfwam"
+	CombinedSMOCName  = "smoc"
+)
+
+// ErrNoCombinedInputs 所有数据源的输入文件都还没有到达, 与缺少输入文件的其他数据集一样不需要通知
+var ErrNoCombinedInputs = errors.New("nc: no combined input files")
+
+// CombinedInput 合并输出的单个数据源
+type CombinedInput struct {
+	Name      string    // ec, mfwam, smoc
+	DateTime  time.Time // 数据源文件的起报时间
+	InputPath string
+}
+
+// 按照名称打开数据源, 只打开输入文件, 不检查输出文件
+var combinedOpeners = map[string]opener{
+	CombinedECName:    func(info *NCFile) (dataset, error) { return openECOper(info) },
+	CombinedMFWAMName: func(info *NCFile) (dataset, error) { return openMFWAM(info) },
+	CombinedSMOCName:  func(info *NCFile) (dataset, error) { return openSMOC(info) },
+}
+
+// 数据源缺失时用来生成 NaN 列, 启动时用来检查列名, 只使用列名和类型
+var combinedPlaceholders = map[string]source{
+	CombinedECName:    &ECOper{info: &NCFile{}},
+	CombinedMFWAMName: &MFWAM{info: &NCFile{}},
+	CombinedSMOCName:  &SMOC{info: &NCFile{}},
+}
+
+// Combined 将同一时刻的风(EC), 浪(MFWAM), 流(SMOC)插值到同一网格后输出到一个文件
+// info.DateTime 为输出的时刻, info.Target 为对齐的目标网格, 不能为空
+type Combined struct {
+	info    *NCFile
+	inputs  []CombinedInput
+	cache   *CombinedCache
+	missing map[string]error
+	grid    regrid.Grid
+	cols    []column
+}
+
+// NewCombined cache 不为空时, 解码后的数据源在多个时刻之间共用
+func NewCombined(ctx context.Context, info *NCFile, inputs []CombinedInput, cache *CombinedCache) (*Combined, error) {
+	if info.Target == nil {
+		return nil, fmt.Errorf("combined target grid is required")
+	}
+
+	for _, input := range inputs {
+		if _, ok := combinedOpeners[input.Name]; !ok {
+			return nil, fmt.Errorf("combined source: %s not supported", input.Name)
+		}
+	}
+
+	// 日期已经离开缓存的起报时次时先释放, 已经生成而跳过的时刻也不会继续占用内存
+	cache.evict(inputs)
+
+	// 上一次缺失的数据源到达后重新生成
+	info.sources = make(map[string]string, len(inputs))
+	for _, input := range inputs {
+		info.sources[input.Name] = input.InputPath
+	}
+
+	if err := info.checkOutputs(ctx, "combined"); err != nil {
+		return nil, err
+	}
+
+	if err := info.createOutputDirs("combined"); err != nil {
+		return nil, err
+	}
+
+	return &Combined{
+		info:    info,
+		inputs:  inputs,
+		cache:   cache,
+		missing: make(map[string]error),
+		grid:    info.Target.Grid,
+	}, nil
+}
+
+// Analysis 解析所有的数据源并插值到目标网格, 单个数据源缺失不会返回错误, 全部缺失时返回错误
+// 所有数据源的输入文件都不存在时返回 ErrNoCombinedInputs
+func (nc *Combined) Analysis() error {
+	found := 0
+	for _, input := range nc.inputs {
+		if input.InputPath != "" {
+			if _, err := os.Stat(input.InputPath); err == nil {
+				found++
+			}
+		}
+
+		cols, err := nc.load(input)
+		if err != nil {
+			nc.missing[input.Name] = err
+			cols = missingColumns(combinedPlaceholders[input.Name])
+		}
+		nc.cols = append(nc.cols, cols...)
+	}
+
+	if found == 0 {
+		return ErrNoCombinedInputs
+	}
+
+	if len(nc.missing) == len(nc.inputs) {
+		errs := make([]error, 0, len(nc.missing))
+		for name, err := range nc.missing {
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+		}
+		return fmt.Errorf("all combined sources are missing: %v", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// Missing 缺失的数据源及原因, 写入元数据并通知下游
+func (nc *Combined) Missing() map[string]string {
+	if len(nc.missing) == 0 {
+		return nil
+	}
+
+	missing := make(map[string]string, len(nc.missing))
+	for name, err := range nc.missing {
+		missing[name] = err.Error()
+	}
+
+	return missing
+}
+
+func (nc *Combined) GenerateCSV(ctx context.Context) error {
+	// 已经插值到目标网格, 直接按照目标网格输出
+	info := *nc.info
+	info.Target = nil
+	info.Stride = Stride{}
+	info.Interval = 0
+	info.Profiles = make([]Profile, len(nc.info.Profiles))
+	for i, profile := range nc.info.Profiles {
+		profile.Stride = Stride{}
+		info.Profiles[i] = profile
+	}
+
+	inputs := make([]string, 0, len(nc.inputs))
+	for _, input := range nc.inputs {
+		if _, ok := nc.missing[input.Name]; !ok {
+			inputs = append(inputs, input.InputPath)
+		}
+	}
+
+	err := generate(ctx, &info, nc, product{
+		name:      "combined",
+		precision: 2,
+		inputs:    inputs,
+		regridded: string(nc.info.Target.Method),
+		missing:   nc.Missing(),
+	})
+	nc.info.results = info.results
+
+	return err
+}
+
+// Close 数据源在插值之后已经关闭或者由缓存管理, 这里只是为了和其他数据源保持一致
+func (nc *Combined) Close() {}
+
+func (nc *Combined) load(input CombinedInput) ([]column, error) {
+	if input.InputPath == "" {
+		return nil, fmt.Errorf("input file not found")
+	}
+
+	if _, err := os.Stat(input.InputPath); err != nil {
+		return nil, fmt.Errorf("input file: %s not exists", input.InputPath)
+	}
+
+	entry, err := nc.cache.open(input, nc.grid, nc.info.Target.Method)
+	if err != nil {
+		return nil, err
+	}
+	// 没有缓存时, 插值之后只保留目标网格上的数据, 释放原始数据
+	if nc.cache == nil {
+		defer entry.src.Close()
+	}
+	src, regridder := entry.src, entry.regridder
+
+	timeIndex := -1
+	for i, t := range src.times() {
+		if t.Equal(nc.info.DateTime) {
+			timeIndex = i
+			break
+		}
+	}
+	if timeIndex < 0 {
+		return nil, fmt.Errorf("time: %s not found in input file: %s", nc.info.DateTime.Format(time.DateTime), input.InputPath)
+	}
+
+	cols := make([]column, 0)
+	for _, c := range src.columns() {
+		value := func(lat, lon int) float32 { return c.value(timeIndex, lat, lon) }
+
+		var layer [][]float32
+		if c.kind == directionKind {
+			layer = regridder.RegridDirection(value)
+		} else {
+			layer = regridder.Regrid(value)
+		}
+
+		cols = append(cols, column{
+			name:  c.name,
+			kind:  c.kind,
+			pair:  c.pair,
+			value: func(t, lat, lon int) float32 { return layer[lat][lon] },
+		})
+	}
+
+	return cols, nil
+}
+
+// CombinedCache 缓存解码后的数据源和插值权重, 同一个 MFWAM/SMOC 文件在多个时刻之间只解码一次
+// 每个数据源只保留当前时刻所在的起报时次, 日期离开这个起报时次或者输入文件的路径, 大小, 修改时间变化时释放
+type CombinedCache struct {
+	mu      sync.Mutex
+	entries map[string]*combinedEntry
+}
+
+type combinedEntry struct {
+	key       string
+	input     CombinedInput
+	src       dataset
+	regridder *regrid.Regridder
+}
+
+func NewCombinedCache() *CombinedCache {
+	return &CombinedCache{entries: make(map[string]*combinedEntry)}
+}
+
+// open 打开并解析数据源, c 为空时不缓存, 由调用方关闭
+func (c *CombinedCache) open(input CombinedInput, grid regrid.Grid, method regrid.Method) (*combinedEntry, error) {
+	if c == nil {
+		return openCombinedEntry(input, grid, method)
+	}
+
+	stat, err := os.Stat(input.InputPath)
+	if err != nil {
+		return nil, fmt.Errorf("input file: %s not exists", input.InputPath)
+	}
+	key := fmt.Sprintf("%s|%d|%d", input.InputPath, stat.Size(), stat.ModTime().UnixNano())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[input.Name]; ok {
+		if entry.key == key {
+			return entry, nil
+		}
+
+		entry.src.Close()
+		delete(c.entries, input.Name)
+	}
+
+	entry, err := openCombinedEntry(input, grid, method)
+	if err != nil {
+		return nil, err
+	}
+	entry.key = key
+	entry.input = input
+	c.entries[input.Name] = entry
+
+	return entry, nil
+}
+
+// evict 释放不属于当前时刻的数据源, 例如: 按照时间倒序生成时日期已经早于缓存的起报时次, c 为空时不处理
+func (c *CombinedCache) evict(inputs []CombinedInput) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, entry := range c.entries {
+		current := slices.ContainsFunc(inputs, func(input CombinedInput) bool {
+			return input.Name == name && input.DateTime.Equal(entry.input.DateTime) && input.InputPath == entry.input.InputPath
+		})
+		if !current {
+			entry.src.Close()
+			delete(c.entries, name)
+		}
+	}
+}
+
+// Close 释放所有缓存的数据源, 例如: 一轮生成结束后
+func (c *CombinedCache) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, entry := range c.entries {
+		entry.src.Close()
+		delete(c.entries, name)
+	}
+}
+
+func openCombinedEntry(input CombinedInput, grid regrid.Grid, method regrid.Method) (*combinedEntry, error) {
+	src, err := combinedOpeners[input.Name](&NCFile{DateTime: input.DateTime, InputPath: input.InputPath})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := src.Analysis(); err != nil {
+		src.Close()
+		return nil, err
+	}
+
+	regridder, err := regrid.New(regrid.Grid{Lats: src.latitudes(), Lons: src.longitudes()}, grid, method)
+	if err != nil {
+		src.Close()
+		return nil, fmt.Errorf("new regridder failed: %v", err)
+	}
+
+	return &combinedEntry{src: src, regridder: regridder}, nil
+}
+
+// missingPartial 上一次生成时缺失的数据源, 现在输入文件已经到达
+// 标记文件中每行为数据源名称和当时的输入文件, 输入文件存在但是解析失败时, 只有文件变化后才重新生成
+func (info *NCFile) missingPartial() bool {
+	data, err := os.ReadFile(info.partialPath())
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		name, path, _ := strings.Cut(line, "\t")
+		current := info.sources[name]
+		if current == "" || current == path {
+			continue
+		}
+
+		if _, err := os.Stat(current); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// markMissing 记录或者清除合并输出缺失数据源的标记
+func (info *NCFile) markMissing(missing map[string]string) error {
+	if len(missing) == 0 {
+		if err := os.Remove(info.partialPath()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove partial mark: %s failed: %v", info.partialPath(), err)
+		}
+		return nil
+	}
+
+	names := slices.Sorted(maps.Keys(missing))
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, name+"\t"+info.sources[name])
+	}
+
+	if err := os.WriteFile(info.partialPath(), []byte(strings.Join(lines, "\n")+"\n"), os.FileMode(0644)); err != nil {
+		return fmt.Errorf("write partial mark: %s failed: %v", info.partialPath(), err)
+	}
+
+	return nil
+}
+
+func missingColumns(placeholder source) []column {
+	cols := make([]column, 0)
+	for _, c := range placeholder.columns() {
+		cols = append(cols, column{
+			name:  c.name,
+			kind:  c.kind,
+			pair:  c.pair,
+			value: func(t, lat, lon int) float32 { return nan },
+		})
+	}
+
+	return cols
+}
+
+func (nc *Combined) latitudes() []float64 {
+	return nc.grid.Lats
+}
+
+func (nc *Combined) longitudes() []float64 {
+	return nc.grid.Lons
+}
+
+func (nc *Combined) times() []time.Time {
+	return []time.Time{nc.info.DateTime}
+}
+
+func (nc *Combined) columns() []column {
+	return nc.cols
+}
@@ -0,0 +1,99 @@
+package nc
+
+import (
+	"gen-meteo-file/pkg/tools/regrid"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMissingPartial(t *testing.T) {
+	dir := t.TempDir()
+	mfwam := filepath.Join(dir, "mfwam_2025061312.nc")
+	smoc := filepath.Join(dir, "smoc_20250613.nc")
+	if err := os.WriteFile(smoc, []byte("corrupt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info := &NCFile{
+		OutputPath: filepath.Join(dir, "combined_2025061315.csv"),
+		sources:    map[string]string{CombinedECName: filepath.Join(dir, "ec.nc"), CombinedMFWAMName: "", CombinedSMOCName: smoc},
+	}
+
+	// MFWAM 没有找到, SMOC 解析失败
+	missing := map[string]string{CombinedMFWAMName: "input file not found", CombinedSMOCName: "analysis failed"}
+	if err := info.markMissing(missing); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(info.partialPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "mfwam\t\nsmoc\t" + smoc + "\n"; string(data) != want {
+		t.Errorf("partial mark = %q, want %q", data, want)
+	}
+
+	// 数据源没有变化时不重新生成, 解析失败的文件不变时也不重新生成
+	if info.partialReady() {
+		t.Errorf("partialReady() = true before source arrived")
+	}
+
+	// 找到文件路径但是文件不存在
+	info.sources[CombinedMFWAMName] = mfwam
+	if info.partialReady() {
+		t.Errorf("partialReady() = true before file exists")
+	}
+
+	// 缺失的数据源到达后重新生成
+	if err := os.WriteFile(mfwam, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !info.partialReady() {
+		t.Errorf("partialReady() = false after source arrived")
+	}
+
+	// 所有数据源都存在时清除标记
+	if err := info.markMissing(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(info.partialPath()); !os.IsNotExist(err) {
+		t.Errorf("partial mark not removed: %v", err)
+	}
+	if info.partialReady() {
+		t.Errorf("partialReady() = true without partial mark")
+	}
+}
+
+func TestCombinedNoInputs(t *testing.T) {
+	dir := t.TempDir()
+	smoc := filepath.Join(dir, "smoc_20250613.nc")
+
+	nc := &Combined{
+		info: &NCFile{Target: &regrid.Target{Method: regrid.Bilinear}},
+		inputs: []CombinedInput{
+			{Name: CombinedECName},
+			{Name: CombinedMFWAMName, InputPath: filepath.Join(dir, "mfwam_2025061312.nc")},
+			{Name: CombinedSMOCName, InputPath: smoc},
+		},
+		missing: make(map[string]error),
+	}
+
+	// 输入文件都不存在
+	if err := nc.Analysis(); err != ErrNoCombinedInputs {
+		t.Errorf("Analysis() error = %v, want %v", err, ErrNoCombinedInputs)
+	}
+	if missing := nc.Missing(); len(missing) != 3 || missing[CombinedECName] != "input file not found" {
+		t.Errorf("Missing() = %v", missing)
+	}
+
+	// 输入文件存在但是都无法解析时返回错误
+	if err := os.WriteFile(smoc, []byte("corrupt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	nc.missing = make(map[string]error)
+	nc.cols = nil
+	if err := nc.Analysis(); err == nil || err == ErrNoCombinedInputs {
+		t.Errorf("Analysis() error = %v, want all sources missing", err)
+	}
+}
@@ -0,0 +1,61 @@
+package nc
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+func writeCSV(path string, f *frame, precision int) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, os.FileMode(0664))
+	if err != nil {
+		return fmt.Errorf("output file: %s create failed: %v", path, err)
+	}
+	defer file.Close()
+
+	names := make([]string, 0, len(f.columns))
+	for _, c := range f.columns {
+		if c.unit != "" {
+			names = append(names, fmt.Sprintf("%s[%s]", c.name, c.unit))
+		} else {
+			names = append(names, c.name)
+		}
+	}
+
+	buf := bufio.NewWriter(file)
+	buf.WriteString("lat,lon,dateTime," + strings.Join(names, ",") + "\n")
+	for timeIndex, dateTime := range f.times {
+		for latIndex, lat := range f.lats {
+			for lonIndex, lon := range f.lons {
+				if !f.contains(latIndex, lonIndex) {
+					continue
+				}
+
+				buf.WriteString(fmt.Sprintf("%.*f,%.*f,%s", precision, lat, precision, lon, dateTime.UTC().Format(time.DateTime)))
+
+				for _, c := range f.columns {
+					value := c.value(timeIndex, latIndex, lonIndex)
+					if math.IsNaN(float64(value)) {
+						buf.WriteString("," + fillToken)
+					} else if c.integer {
+						buf.WriteString(fmt.Sprintf(",%.0f", value))
+					} else {
+						buf.WriteString(fmt.Sprintf(",%f", value))
+					}
+				}
+				buf.WriteString("\n")
+			}
+		}
+
+		buf.Flush()
+	}
+
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("write output file: %s failed: %v", path, err)
+	}
+
+	return nil
+}
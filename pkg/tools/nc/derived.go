@@ -0,0 +1,231 @@
+package nc
+
+import (
+	"fmt"
+	"gen-meteo-file/pkg/tools/derive"
+	"slices"
+)
+
+// derivation 由已有的列计算新的列, 在插值和裁剪之后计算
+type derivation struct {
+	inputs  []string
+	kind    columnKind
+	integer bool
+	compute func(values ...float32) float32
+}
+
+// 可选的派生列, 键为输出的列名
+var derivations = map[string]derivation{
+	// EC 风
+	"windSpeed": {
+		inputs:  []string{"wind10mU", "wind10mV"},
+		compute: func(v ...float32) float32 { return derive.Speed(v[0], v[1]) },
+	},
+	"windSpeedKnots": {
+		inputs:  []string{"wind10mU", "wind10mV"},
+		compute: func(v ...float32) float32 { return derive.Knots(derive.Speed(v[0], v[1])) },
+	},
+	"windDirection": {
+		inputs:  []string{"wind10mU", "wind10mV"},
+		kind:    directionKind,
+		compute: func(v ...float32) float32 { return derive.WindDirection(v[0], v[1]) },
+	},
+	"beaufort": {
+		inputs:  []string{"wind10mU", "wind10mV"},
+		integer: true,
+		compute: func(v ...float32) float32 { return category(derive.Beaufort(derive.Speed(v[0], v[1]))) },
+	},
+
+	// MFWAM 海况, 使用总浪(VHM0, VTM10)
+	"douglasSeaState": {
+		inputs:  []string{"seaWaveHeight"},
+		integer: true,
+		compute: func(v ...float32) float32 { return category(derive.DouglasSeaState(v[0])) },
+	},
+	"wmoSeaState": {
+		inputs:  []string{"seaWaveHeight"},
+		integer: true,
+		compute: func(v ...float32) float32 { return category(derive.WMOSeaState(v[0])) },
+	},
+	"waveSteepness": {
+		inputs:  []string{"seaWaveHeight", "seaWavePeriod"},
+		compute: func(v ...float32) float32 { return derive.Steepness(v[0], v[1]) },
+	},
+	"waveSteepnessClass": {
+		inputs:  []string{"seaWaveHeight", "seaWavePeriod"},
+		integer: true,
+		compute: func(v ...float32) float32 { return category(derive.SteepnessClass(derive.Steepness(v[0], v[1]))) },
+	},
+
+	// SMOC 总流
+	"currentSpeed": {
+		inputs:  []string{"uCurrent", "vCurrent"},
+		compute: func(v ...float32) float32 { return derive.Speed(v[0], v[1]) },
+	},
+	"currentSpeedKnots": {
+		inputs:  []string{"uCurrent", "vCurrent"},
+		compute: func(v ...float32) float32 { return derive.Knots(derive.Speed(v[0], v[1])) },
+	},
+	"currentDirection": {
+		inputs:  []string{"uCurrent", "vCurrent"},
+		kind:    directionKind,
+		compute: func(v ...float32) float32 { return derive.CurrentDirection(v[0], v[1]) },
+	},
+
+	// SMOC 潮流
+	"tideCurrentSpeed": {
+		inputs:  []string{"uTideCurrent", "vTideCurrent"},
+		compute: func(v ...float32) float32 { return derive.Speed(v[0], v[1]) },
+	},
+	"tideCurrentSpeedKnots": {
+		inputs:  []string{"uTideCurrent", "vTideCurrent"},
+		compute: func(v ...float32) float32 { return derive.Knots(derive.Speed(v[0], v[1])) },
+	},
+	"tideCurrentDirection": {
+		inputs:  []string{"uTideCurrent", "vTideCurrent"},
+		kind:    directionKind,
+		compute: func(v ...float32) float32 { return derive.CurrentDirection(v[0], v[1]) },
+	},
+
+	// SMOC 余流: 总流减去潮流
+	"uResidualCurrent": {
+		inputs:  []string{"uCurrent", "uTideCurrent"},
+		kind:    vectorKind,
+		compute: func(v ...float32) float32 { return derive.Residual(v[0], v[1]) },
+	},
+	"vResidualCurrent": {
+		inputs:  []string{"vCurrent", "vTideCurrent"},
+		kind:    vectorKind,
+		compute: func(v ...float32) float32 { return derive.Residual(v[0], v[1]) },
+	},
+	"residualCurrentSpeed": {
+		inputs: []string{"uCurrent", "vCurrent", "uTideCurrent", "vTideCurrent"},
+		compute: func(v ...float32) float32 {
+			return derive.Speed(derive.Residual(v[0], v[2]), derive.Residual(v[1], v[3]))
+		},
+	},
+	"residualCurrentSpeedKnots": {
+		inputs: []string{"uCurrent", "vCurrent", "uTideCurrent", "vTideCurrent"},
+		compute: func(v ...float32) float32 {
+			return derive.Knots(derive.Speed(derive.Residual(v[0], v[2]), derive.Residual(v[1], v[3])))
+		},
+	},
+	"residualCurrentDirection": {
+		inputs: []string{"uCurrent", "vCurrent", "uTideCurrent", "vTideCurrent"},
+		kind:   directionKind,
+		compute: func(v ...float32) float32 {
+			return derive.CurrentDirection(derive.Residual(v[0], v[2]), derive.Residual(v[1], v[3]))
+		},
+	},
+}
+
+// CheckDerived 检查派生列是否支持, 以及数据源是否有计算派生列需要的列, dataset 为 ec, mfwam 或 smoc
+func CheckDerived(dataset string, names []string) error {
+	placeholder, ok := combinedPlaceholders[dataset]
+	if !ok {
+		return fmt.Errorf("dataset: %s not supported", dataset)
+	}
+
+	columns := make(map[string]bool)
+	for _, c := range placeholder.columns() {
+		columns[c.name] = true
+	}
+
+	for _, name := range names {
+		d, ok := derivations[name]
+		if !ok {
+			return fmt.Errorf("derived column: %s not supported", name)
+		}
+
+		for _, input := range d.inputs {
+			if !columns[input] {
+				return fmt.Errorf("derived column: %s requires column: %s, not in %s", name, input, dataset)
+			}
+		}
+	}
+
+	return nil
+}
+
+// CheckColumns 检查输出的列是否为数据源的列或者 derived 中配置的派生列, dataset 为 combined 时包括所有数据源的列
+func CheckColumns(dataset string, derived []string, names []string) error {
+	placeholders := make([]source, 0, len(combinedPlaceholders))
+	if dataset == "combined" {
+		for _, name := range []string{CombinedECName, CombinedMFWAMName, CombinedSMOCName} {
+			placeholders = append(placeholders, combinedPlaceholders[name])
+		}
+	} else {
+		placeholder, ok := combinedPlaceholders[dataset]
+		if !ok {
+			return fmt.Errorf("dataset: %s not supported", dataset)
+		}
+		placeholders = append(placeholders, placeholder)
+	}
+
+	columns := make(map[string]bool)
+	for _, placeholder := range placeholders {
+		for _, c := range placeholder.columns() {
+			columns[c.name] = true
+		}
+	}
+
+	for _, name := range names {
+		if columns[name] || slices.Contains(derived, name) {
+			continue
+		}
+
+		if _, ok := derivations[name]; ok {
+			return fmt.Errorf("output column: %s is a derived column, not in %s derived columns", name, dataset)
+		}
+
+		return fmt.Errorf("output column: %s not found in %s", name, dataset)
+	}
+
+	return nil
+}
+
+// derive 在输出的数据中追加派生列
+func (f *frame) derive(names []string) error {
+	for _, name := range names {
+		d, ok := derivations[name]
+		if !ok {
+			return fmt.Errorf("derived column: %s not supported", name)
+		}
+
+		inputs := make([]func(t, lat, lon int) float32, 0, len(d.inputs))
+		for _, input := range d.inputs {
+			c, ok := f.column(input)
+			if !ok {
+				return fmt.Errorf("derived column: %s requires column: %s", name, input)
+			}
+			inputs = append(inputs, c.value)
+		}
+
+		// 列的值按格点依次计算, 每个派生列共用一个缓冲区
+		compute := d.compute
+		values := make([]float32, len(inputs))
+		f.columns = append(f.columns, column{
+			name:    name,
+			kind:    d.kind,
+			integer: d.integer,
+			value: func(t, lat, lon int) float32 {
+				for i, input := range inputs {
+					values[i] = input(t, lat, lon)
+				}
+
+				return compute(values...)
+			},
+		})
+	}
+
+	return nil
+}
+
+// category 分类值转换为列的值, 小于 0 表示缺测
+func category(value int) float32 {
+	if value < 0 {
+		return nan
+	}
+
+	return float32(value)
+}
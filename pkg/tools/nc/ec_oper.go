@@ -1,10 +1,8 @@
 package nc
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/batchatco/go-native-netcdf/netcdf"
@@ -23,6 +21,14 @@ const (
 	ECOperStep                 = float32(0.25)
 )
 
+// ecOperVariables NetCDF 变量名对应的列名
+var ecOperVariables = map[string]string{
+	ECOperWind10mUField:        "wind10mU",
+	ECOperWind10mVField:        "wind10mV",
+	ECOperTemperature2mField:   "temperature2m",
+	ECOperSurfacePressureField: "surfacePressure",
+}
+
 type ECOper struct {
 	info                *NCFile
 	group               api.Group
@@ -34,23 +40,16 @@ type ECOper struct {
 	surfacePressureList [][][]float32
 }
 
-func NewECOper(info *NCFile) (*ECOper, error) {
-	if _, err := os.Stat(info.InputPath); err != nil {
-		return nil, fmt.Errorf("ec_oper input file: %s not exists", info.InputPath)
-	}
-
-	if _, err := os.Stat(info.OutputPath); err == nil {
-		return nil, fmt.Errorf("ec_oper output file: %s already exists", info.OutputPath)
-	}
-
-	if _, err := os.Stat(info.CompressionPath); err == nil {
-		return nil, fmt.Errorf("ec_oper compression file: %s already exists", info.CompressionPath)
+func NewECOper(ctx context.Context, info *NCFile) (*ECOper, error) {
+	if err := info.check(ctx, "ec_oper"); err != nil {
+		return nil, err
 	}
 
-	if err := os.MkdirAll(filepath.Dir(info.OutputPath), os.FileMode(0755)); err != nil {
-		return nil, fmt.Errorf("create ec_oper output dir: %s failed: %v", filepath.Dir(info.OutputPath), err)
-	}
+	return openECOper(info)
+}
 
+// openECOper 只打开输入文件, 不检查输出文件
+func openECOper(info *NCFile) (*ECOper, error) {
 	group, err := netcdf.Open(info.InputPath)
 	if err != nil {
 		return nil, fmt.Errorf("open ec_oper netcdf file: %s failed: %v", info.InputPath, err)
@@ -113,33 +112,36 @@ func (nc *ECOper) Analysis() error {
 	return nil
 }
 
-func (nc *ECOper) GenerateCSV() error {
-	file, err := os.OpenFile(nc.info.OutputPath, os.O_CREATE|os.O_RDWR, os.FileMode(0664))
-	if err != nil {
-		return fmt.Errorf("output file: %s create failed: %v", nc.info.OutputPath, err)
-	}
-	defer file.Close()
-
-	buf := bufio.NewWriter(file)
-	buf.WriteString("lat,lon,dateTime,wind10mU,wind10mV,temperature2m,surfacePressure\n")
-	for latIndex := range ECOperLatitudeCount {
-		for lonIndex := range ECOperLongitudeCount {
-			buf.WriteString(fmt.Sprintf(
-				"%.2f,%.2f,%s",
-				nc.latitudeList[latIndex], nc.longitudeList[lonIndex],
-				nc.info.DateTime.UTC().Format(time.DateTime),
-			))
-
-			buf.WriteString(fmt.Sprintf(",%f", nc.wind10mUList[0][0][latIndex][lonIndex]))
-			buf.WriteString(fmt.Sprintf(",%f", nc.wind10mVList[0][0][latIndex][lonIndex]))
-			buf.WriteString(fmt.Sprintf(",%f", nc.temperature2mList[0][0][latIndex][lonIndex]))
-			buf.WriteString(fmt.Sprintf(",%f", nc.surfacePressureList[0][latIndex][lonIndex]))
-			buf.WriteString("\n")
-		}
-	}
+func (nc *ECOper) GenerateCSV(ctx context.Context) error {
+	return generate(ctx, nc.info, nc, product{
+		name:      "ec",
+		precision: 2,
+		open:      func(info *NCFile) (dataset, error) { return openECOper(info) },
+		layer:     "windSpeed",
+		velocity:  &velocityLayer{u: "wind10mU", v: "wind10mV", category: 2},
+		variables: ecOperVariables,
+	})
+}
 
-	buf.Flush()
-	return zipFile(nc.info.OutputPath, nc.info.CompressionPath)
+func (nc *ECOper) latitudes() []float64 {
+	return nc.latitudeList
+}
+
+func (nc *ECOper) longitudes() []float64 {
+	return nc.longitudeList
+}
+
+func (nc *ECOper) times() []time.Time {
+	return []time.Time{nc.info.DateTime}
+}
+
+func (nc *ECOper) columns() []column {
+	return []column{
+		{name: "wind10mU", kind: vectorKind, pair: "wind10mV", value: func(t, lat, lon int) float32 { return nc.wind10mUList[t][0][lat][lon] }},
+		{name: "wind10mV", kind: vectorKind, pair: "wind10mU", value: func(t, lat, lon int) float32 { return nc.wind10mVList[t][0][lat][lon] }},
+		{name: "temperature2m", value: func(t, lat, lon int) float32 { return nc.temperature2mList[t][0][lat][lon] }},
+		{name: "surfacePressure", value: func(t, lat, lon int) float32 { return nc.surfacePressureList[t][lat][lon] }},
+	}
 }
 
 func (nc *ECOper) Close() {
@@ -0,0 +1,266 @@
+package nc
+
+import (
+	"fmt"
+	"gen-meteo-file/pkg/tools/regrid"
+	"math"
+	"time"
+)
+
+var nan = float32(math.NaN())
+
+// columnKind 列的物理类型, 决定了插值的方式
+type columnKind int
+
+const (
+	scalarKind    columnKind = iota // 标量, 线性插值
+	directionKind                   // 方向(0~360 度), 按照单位向量插值
+	vectorKind                      // 矢量的分量, 时间插值时保持矢量的模长线性变化, pair 为另一个分量的列名
+)
+
+// column 输出文件中的一列数据, 缺测值返回 NaN
+// 插值到目标网格的列只缓存一个时次, 读取时时次必须在最外层循环, 所有格点读完后再读下一个时次, 见 regridColumn
+type column struct {
+	name    string
+	kind    columnKind
+	pair    string
+	integer bool   // 分类值, 输出时不保留小数
+	unit    string // 输出的单位, 只有配置了单位方案时才会记录在表头中
+	value   func(t, lat, lon int) float32
+}
+
+// source 各数据源解析之后的统一视图
+type source interface {
+	latitudes() []float64
+	longitudes() []float64
+	times() []time.Time
+	columns() []column
+}
+
+// dataset 可以独立打开和解析的数据源
+type dataset interface {
+	source
+	Analysis() error
+	Close()
+}
+
+// frame 单个输出文件的数据, 列的下标与 times/lats/lons 一一对应
+type frame struct {
+	times   []time.Time
+	lats    []float64
+	lons    []float64
+	columns []column
+	mask    [][]bool // 为空时输出所有格点, 否则只输出 mask[lat][lon] 为 true 的格点
+	region  Region
+}
+
+// newFrame 按照抽样步长和区域从数据源中选取输出的格点, target 不为空时先插值到目标网格
+func newFrame(src source, stride Stride, target *regrid.Target, region Region) (*frame, error) {
+	if target != nil {
+		return newRegridFrame(src, stride, *target, region)
+	}
+
+	var (
+		times = src.times()
+		lats  = src.latitudes()
+		lons  = src.longitudes()
+
+		timeIndexes            = strideIndexes(len(times), stride.time(), func(int) bool { return true })
+		latIndexes, lonIndexes = regionIndexes(lats, lons, stride.lat(), stride.lon(), region)
+	)
+
+	f := &frame{
+		times:  pick(times, timeIndexes),
+		lats:   pick(lats, latIndexes),
+		lons:   pick(lons, lonIndexes),
+		region: region,
+	}
+	f.mask = regionMask(f.lats, f.lons, region)
+
+	for _, c := range src.columns() {
+		value := c.value
+		f.columns = append(f.columns, column{
+			name: c.name,
+			kind: c.kind,
+			pair: c.pair,
+			value: func(t, lat, lon int) float32 {
+				return value(timeIndexes[t], latIndexes[lat], lonIndexes[lon])
+			},
+		})
+	}
+
+	return f, nil
+}
+
+// newRegridFrame 将数据源插值到目标网格, 空间抽样步长不再生效, 分辨率由目标网格决定
+func newRegridFrame(src source, stride Stride, target regrid.Target, region Region) (*frame, error) {
+	var (
+		times = src.times()
+
+		timeIndexes            = strideIndexes(len(times), stride.time(), func(int) bool { return true })
+		latIndexes, lonIndexes = regionIndexes(target.Grid.Lats, target.Grid.Lons, 1, 1, region)
+	)
+
+	f := &frame{
+		times:  pick(times, timeIndexes),
+		lats:   pick(target.Grid.Lats, latIndexes),
+		lons:   pick(target.Grid.Lons, lonIndexes),
+		region: region,
+	}
+	f.mask = regionMask(f.lats, f.lons, region)
+
+	// 只对区域内的格点计算插值权重
+	regridder, err := regrid.New(
+		regrid.Grid{Lats: src.latitudes(), Lons: src.longitudes()},
+		regrid.Grid{Lats: f.lats, Lons: f.lons},
+		target.Method,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("new regridder failed: %v", err)
+	}
+
+	for _, c := range src.columns() {
+		f.columns = append(f.columns, regridColumn(c, regridder, timeIndexes))
+	}
+
+	return f, nil
+}
+
+// regridColumn 按时次插值, 只缓存最近一个时次的结果, 输出时需要按时次顺序读取
+// 不按时次缓存所有结果: 全球 0.25 度网格每个时次约 4MB, 所有列和时次都缓存时内存占用过大
+// 乱序读取时结果仍然正确, 但是每次切换时次都会重新插值整个网格
+func regridColumn(c column, regridder *regrid.Regridder, timeIndexes []int) column {
+	var (
+		cached = -1
+		layer  [][]float32
+	)
+
+	return column{
+		name: c.name,
+		kind: c.kind,
+		pair: c.pair,
+		value: func(t, lat, lon int) float32 {
+			if t != cached {
+				value := func(lat, lon int) float32 { return c.value(timeIndexes[t], lat, lon) }
+				if c.kind == directionKind {
+					layer = regridder.RegridDirection(value)
+				} else {
+					layer = regridder.Regrid(value)
+				}
+				cached = t
+			}
+
+			return layer[lat][lon]
+		},
+	}
+}
+
+func (f *frame) empty() bool {
+	if len(f.times) == 0 || len(f.lats) == 0 || len(f.lons) == 0 {
+		return true
+	}
+
+	for i := range f.lats {
+		for j := range f.lons {
+			if f.contains(i, j) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// column 按照列名查找列
+func (f *frame) column(name string) (column, bool) {
+	for _, c := range f.columns {
+		if c.name == name {
+			return c, true
+		}
+	}
+
+	return column{}, false
+}
+
+// selectColumns 只保留指定的列, 按照指定的顺序输出, names 为空时保留所有列
+func (f *frame) selectColumns(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	columns := make([]column, 0, len(names))
+	for _, name := range names {
+		c, ok := f.column(name)
+		if !ok {
+			return fmt.Errorf("output column: %s not found", name)
+		}
+		columns = append(columns, c)
+	}
+	f.columns = columns
+
+	return nil
+}
+
+// contains 格点是否在输出区域内
+func (f *frame) contains(lat, lon int) bool {
+	return f.mask == nil || f.mask[lat][lon]
+}
+
+// regionIndexes 按照抽样步长选取区域内的经纬度下标
+func regionIndexes(lats, lons []float64, latStride, lonStride int, region Region) ([]int, []int) {
+	latIndexes := strideIndexes(len(lats), latStride, func(i int) bool { return region.containsLat(lats[i]) })
+	lonIndexes := strideIndexes(len(lons), lonStride, func(i int) bool { return region.containsLon(lons[i]) })
+
+	// 跨越 180 度经线时, 先输出西侧再输出东侧, 保证经度连续
+	if region.crossAntimeridian() {
+		west := make([]int, 0, len(lonIndexes))
+		east := make([]int, 0, len(lonIndexes))
+		for _, index := range lonIndexes {
+			if lons[index] >= region.West {
+				west = append(west, index)
+			} else {
+				east = append(east, index)
+			}
+		}
+		lonIndexes = append(west, east...)
+	}
+
+	return latIndexes, lonIndexes
+}
+
+// regionMask 多边形区域的格点掩码, 非多边形区域返回空
+func regionMask(lats, lons []float64, region Region) [][]bool {
+	if region.Mask == nil {
+		return nil
+	}
+
+	mask := make([][]bool, len(lats))
+	for i, lat := range lats {
+		mask[i] = make([]bool, len(lons))
+		for j, lon := range lons {
+			mask[i][j] = region.contains(lat, lon)
+		}
+	}
+
+	return mask
+}
+
+func strideIndexes(count, stride int, keep func(int) bool) []int {
+	indexes := make([]int, 0, count/stride+1)
+	for i := 0; i < count; i += stride {
+		if keep(i) {
+			indexes = append(indexes, i)
+		}
+	}
+
+	return indexes
+}
+
+func pick[T any](list []T, indexes []int) []T {
+	picked := make([]T, len(indexes))
+	for i, index := range indexes {
+		picked[i] = list[index]
+	}
+
+	return picked
+}
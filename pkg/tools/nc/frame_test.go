@@ -0,0 +1,47 @@
+package nc
+
+import (
+	"gen-meteo-file/pkg/tools/regrid"
+	"testing"
+)
+
+func TestRegridColumn(t *testing.T) {
+	src := regrid.Grid{Lats: []float64{0, 1}, Lons: []float64{0, 1}}
+	dst := regrid.Grid{Lats: []float64{0, 0.5}, Lons: []float64{0.5, 1}}
+	regridder, err := regrid.New(src, dst, regrid.Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 源网格上的值为 10 * 时次 + 经度下标, 插值后为 10 * 时次 + 目标经度
+	reads := 0
+	c := regridColumn(column{name: "height", value: func(t, lat, lon int) float32 {
+		reads++
+		return float32(10*t + lon)
+	}}, regridder, []int{0, 2, 4})
+
+	want := func(t, lon int) float32 { return float32(20*t) + float32(dst.Lons[lon]) }
+
+	// 按时次顺序读取时每个时次只插值一次
+	for ti := range 3 {
+		for lat := range dst.Lats {
+			for lon := range dst.Lons {
+				if got := c.value(ti, lat, lon); got != want(ti, lon) {
+					t.Errorf("value(%d, %d, %d) = %v, want %v", ti, lat, lon, got, want(ti, lon))
+				}
+			}
+		}
+	}
+	layer := reads / 3
+
+	// 乱序读取时结果不变, 但是每次切换时次都会重新插值, 第一次读取的是缓存的最后一个时次
+	reads = 0
+	for _, ti := range []int{2, 0, 2} {
+		if got := c.value(ti, 1, 0); got != want(ti, 0) {
+			t.Errorf("value(%d, 1, 0) = %v, want %v", ti, got, want(ti, 0))
+		}
+	}
+	if reads != 2*layer {
+		t.Errorf("out of order reads = %d, want %d", reads, 2*layer)
+	}
+}
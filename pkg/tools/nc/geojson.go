@@ -0,0 +1,72 @@
+package nc
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// writeGeoJSON 写入按行分隔的 GeoJSON(NDJSON), 每个格点每个时次一个 Point Feature, 按照时次排列
+// thin 大于 1 时每隔 thin 个格点输出一个, 缺测值写为 null
+func writeGeoJSON(path string, f *frame, precision, thin int) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.FileMode(0664))
+	if err != nil {
+		return fmt.Errorf("output file: %s create failed: %v", path, err)
+	}
+	defer file.Close()
+
+	thin = atLeastOne(thin)
+
+	// 列名在所有 Feature 中相同, 提前转义
+	names := make([]string, len(f.columns))
+	for i, c := range f.columns {
+		names[i] = strconv.Quote(c.name)
+	}
+
+	buf := bufio.NewWriter(file)
+	for timeIndex, dateTime := range f.times {
+		properties := `{"dateTime":"` + dateTime.UTC().Format(time.RFC3339) + `"`
+		for latIndex, lat := range f.lats {
+			if latIndex%thin != 0 {
+				continue
+			}
+
+			for lonIndex, lon := range f.lons {
+				if lonIndex%thin != 0 || !f.contains(latIndex, lonIndex) {
+					continue
+				}
+
+				// GeoJSON 的经度范围为 -180 ~ 180
+				if lon > 180 {
+					lon -= 360
+				}
+
+				buf.WriteString(fmt.Sprintf(`{"type":"Feature","geometry":{"type":"Point","coordinates":[%.*f,%.*f]},"properties":`, precision, lon, precision, lat))
+				buf.WriteString(properties)
+				for i, c := range f.columns {
+					value := c.value(timeIndex, latIndex, lonIndex)
+					buf.WriteString("," + names[i] + ":")
+					if math.IsNaN(float64(value)) {
+						buf.WriteString("null")
+					} else if c.integer {
+						buf.WriteString(fmt.Sprintf("%.0f", value))
+					} else {
+						buf.WriteString(strconv.FormatFloat(float64(value), 'f', -1, 32))
+					}
+				}
+				buf.WriteString("}}\n")
+			}
+		}
+
+		buf.Flush()
+	}
+
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("write output file: %s failed: %v", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package nc
+
+import (
+	"fmt"
+	"gen-meteo-file/pkg/tools/geotiff"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// generateGeoTIFF 每个区域每个变量输出一个 GeoTIFF, 每个时次一个波段, 已经生成的文件会被跳过
+// 变量可以是 NetCDF 变量名或者列名(包括派生列), 例如: VHM0, seaWaveHeight, douglasSeaState
+func generateGeoTIFF(info *NCFile, src source, stride Stride, p product) error {
+	regions := info.Regions
+	if len(regions) == 0 {
+		regions = []Region{{}}
+	}
+
+	for _, region := range regions {
+		pending := make([]string, 0, len(info.GeoTIFF))
+		for _, name := range info.GeoTIFF {
+			if _, err := os.Stat(geoTIFFPath(info, p, name, region)); err != nil {
+				pending = append(pending, name)
+			}
+		}
+
+		if len(pending) == 0 {
+			continue
+		}
+
+		f, err := newGeoTIFFFrame(info, src, stride, p, pending, region)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range pending {
+			c, _ := f.column(geoTIFFColumn(p, name))
+
+			path := geoTIFFPath(info, p, name, region)
+			if err := writeGeoTIFF(path+".tmp", f, c); err != nil {
+				os.Remove(path + ".tmp")
+				return err
+			}
+
+			if err := os.Rename(path+".tmp", path); err != nil {
+				return fmt.Errorf("rename geotiff file: %s failed: %v", path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// newGeoTIFFFrame 生成单个区域的数据, 只输出 GeoTIFF 的派生列没有配置时也会计算
+func newGeoTIFFFrame(info *NCFile, src source, stride Stride, p product, names []string, region Region) (*frame, error) {
+	f, err := newFrame(src, stride, info.Target, region)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.derive(info.Derived); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		column := geoTIFFColumn(p, name)
+		if _, ok := f.column(column); ok {
+			continue
+		}
+
+		if _, ok := derivations[column]; !ok {
+			return nil, fmt.Errorf("geotiff variable: %s not found", name)
+		}
+
+		if err := f.derive([]string{column}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := f.convert(info.Units); err != nil {
+		return nil, err
+	}
+
+	if f.empty() {
+		return nil, fmt.Errorf("geotiff region: %s has no grid point", region.Name)
+	}
+
+	return f, nil
+}
+
+// 每个数据源 NetCDF 变量名对应的列名, 用于启动时检查 GeoTIFF 的变量
+var geoTIFFVariables = map[string]map[string]string{
+	CombinedECName:    ecOperVariables,
+	CombinedMFWAMName: mfwamVariables,
+	CombinedSMOCName:  smocVariables,
+}
+
+// CheckGeoTIFF 检查 GeoTIFF 的变量是否为数据源的 NetCDF 变量名, 列名或者可以计算的派生列, dataset 为 ec, mfwam 或 smoc
+func CheckGeoTIFF(dataset string, names []string) error {
+	placeholder, ok := combinedPlaceholders[dataset]
+	if !ok {
+		return fmt.Errorf("dataset: %s not supported", dataset)
+	}
+
+	for _, variable := range names {
+		name := geoTIFFColumn(product{variables: geoTIFFVariables[dataset]}, variable)
+		if slices.ContainsFunc(placeholder.columns(), func(c column) bool { return c.name == name }) {
+			continue
+		}
+
+		if _, ok := derivations[name]; !ok {
+			return fmt.Errorf("geotiff variable: %s not found", variable)
+		}
+
+		if err := CheckDerived(dataset, []string{name}); err != nil {
+			return fmt.Errorf("geotiff variable: %s: %v", variable, err)
+		}
+	}
+
+	return nil
+}
+
+// geoTIFFColumn NetCDF 变量名对应的列名, 不是 NetCDF 变量名时按照列名处理
+func geoTIFFColumn(p product, name string) string {
+	if column, ok := p.variables[name]; ok {
+		return column
+	}
+
+	return name
+}
+
+// geoTIFFPath 与 zip 同目录, 例如: mfwam_VHM0_2025061300.tif, mfwam_VHM0_2025061300_north_pacific.tif
+func geoTIFFPath(info *NCFile, p product, name string, region Region) string {
+	base := fmt.Sprintf("%s_%s_%s.tif", p.name, name, info.DateTime.Format("2006010215"))
+	return filepath.Join(filepath.Dir(info.OutputPath), withSuffix(base, region.Name))
+}
+
+// writeGeoTIFF 按照从北到南的顺序写入每个时次的波段, 区域外的格点为缺测值
+func writeGeoTIFF(path string, f *frame, c column) error {
+	if len(f.lats) < 2 || len(f.lons) < 2 {
+		return fmt.Errorf("geotiff grid: %dx%d must have at least 2 points in each direction", len(f.lons), len(f.lats))
+	}
+
+	rows := make([]int, len(f.lats))
+	for i := range rows {
+		rows[i] = i
+	}
+	if f.lats[0] < f.lats[len(f.lats)-1] {
+		slices.Reverse(rows)
+	}
+
+	var (
+		lons = continuousLons(f.lons)
+		dx   = (lons[len(lons)-1] - lons[0]) / float64(len(lons)-1)
+		dy   = math.Abs(f.lats[len(f.lats)-1]-f.lats[0]) / float64(len(f.lats)-1)
+		grid = geotiff.Grid{
+			Width:  len(lons),
+			Height: len(rows),
+			West:   lons[0] - dx/2,
+			North:  f.lats[rows[0]] + dy/2,
+			DX:     dx,
+			DY:     dy,
+		}
+	)
+
+	unit := c.unit
+	if unit == "" {
+		unit = columnAttributes[c.name].unit
+	}
+
+	bands := make([]geotiff.Band, len(f.times))
+	for t, validTime := range f.times {
+		values := make([]float32, 0, grid.Width*grid.Height)
+		for _, i := range rows {
+			for j := range f.lons {
+				if !f.contains(i, j) {
+					values = append(values, nan)
+					continue
+				}
+				values = append(values, c.value(t, i, j))
+			}
+		}
+
+		bands[t] = geotiff.Band{Description: validTime.UTC().Format(time.RFC3339), Unit: unit, Values: values}
+	}
+
+	return geotiff.WriteFile(path, grid, bands)
+}
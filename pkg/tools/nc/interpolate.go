@@ -0,0 +1,234 @@
+package nc
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// opener 只打开输入文件, 不检查输出文件
+type opener func(info *NCFile) (dataset, error)
+
+// openNext 打开并解析下一个起报时次的文件, 文件不存在时返回空, 只在当前文件的时次之间插值
+func openNext(info *NCFile, open opener) (dataset, error) {
+	if info.Next == nil || open == nil {
+		return nil, nil
+	}
+
+	if _, err := os.Stat(info.Next.InputPath); err != nil {
+		return nil, nil
+	}
+
+	next, err := open(&NCFile{DateTime: info.Next.DateTime, InputPath: info.Next.InputPath})
+	if err != nil {
+		return nil, fmt.Errorf("open next file failed: %v", err)
+	}
+
+	if err := next.Analysis(); err != nil {
+		next.Close()
+		return nil, fmt.Errorf("analysis next file: %s failed: %v", info.Next.InputPath, err)
+	}
+
+	return next, nil
+}
+
+// partialPath 下一个起报时次的文件缺失时写入的标记文件, 下一个文件到达后重新生成这个起报时次
+func (info *NCFile) partialPath() string {
+	return withExt(info.OutputPath, ".partial")
+}
+
+// partialReady 上一次生成时缺少下一个起报时次的文件或者合并输出的数据源, 现在文件已经到达
+func (info *NCFile) partialReady() bool {
+	if info.sources != nil {
+		return info.missingPartial()
+	}
+
+	if info.Interval <= 0 || info.Next == nil {
+		return false
+	}
+
+	if _, err := os.Stat(info.partialPath()); err != nil {
+		return false
+	}
+
+	_, err := os.Stat(info.Next.InputPath)
+	return err == nil
+}
+
+// markPartial 记录或者清除缺少下一个起报时次文件的标记
+func (info *NCFile) markPartial(partial bool) error {
+	if !partial {
+		if err := os.Remove(info.partialPath()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove partial mark: %s failed: %v", info.partialPath(), err)
+		}
+		return nil
+	}
+
+	if err := os.WriteFile(info.partialPath(), []byte(info.Next.InputPath+"\n"), os.FileMode(0644)); err != nil {
+		return fmt.Errorf("write partial mark: %s failed: %v", info.partialPath(), err)
+	}
+
+	return nil
+}
+
+// interpolated 按照固定步长在时间上插值的数据源
+// next 为下一个起报时次的数据源, 用于插值两个文件之间的时次, 为空时只在当前文件的时次之间插值
+type interpolated struct {
+	src   source
+	next  source
+	knots []time.Time // 当前文件的所有时次, 加上下一个文件的第一个时次
+	slots []slot
+}
+
+// slot 输出时次位于 knots[k] 与 knots[k+1] 之间, 权重为 w
+type slot struct {
+	time time.Time
+	k    int
+	w    float64
+}
+
+// interpolate 从第一个时次开始按照步长生成时次
+// next 不为空时到 next 的第一个时次为止(不包含), 否则到当前文件的最后一个时次为止(包含)
+func interpolate(src, next source, interval time.Duration) source {
+	in := &interpolated{src: src, next: next, knots: src.times()}
+	if next != nil && len(next.times()) > 0 {
+		in.knots = append(append([]time.Time{}, in.knots...), next.times()[0])
+	}
+
+	if len(in.knots) == 0 {
+		return in
+	}
+
+	first, last := in.knots[0], in.knots[len(in.knots)-1]
+	k := 0
+	for t := first; t.Before(last) || (next == nil && t.Equal(last)); t = t.Add(interval) {
+		for k < len(in.knots)-2 && !t.Before(in.knots[k+1]) {
+			k++
+		}
+
+		s := slot{time: t, k: k}
+		if k+1 < len(in.knots) {
+			s.w = float64(t.Sub(in.knots[k])) / float64(in.knots[k+1].Sub(in.knots[k]))
+		}
+		in.slots = append(in.slots, s)
+	}
+
+	return in
+}
+
+func (in *interpolated) latitudes() []float64 {
+	return in.src.latitudes()
+}
+
+func (in *interpolated) longitudes() []float64 {
+	return in.src.longitudes()
+}
+
+func (in *interpolated) times() []time.Time {
+	times := make([]time.Time, len(in.slots))
+	for i, s := range in.slots {
+		times[i] = s.time
+	}
+
+	return times
+}
+
+func (in *interpolated) columns() []column {
+	// 合并当前文件和下一个文件的同名列
+	knotValues := make(map[string]func(k, lat, lon int) float32)
+	nextColumns := make(map[string]column)
+	if in.next != nil {
+		for _, c := range in.next.columns() {
+			nextColumns[c.name] = c
+		}
+	}
+
+	count := len(in.src.times())
+	for _, c := range in.src.columns() {
+		current := c.value
+		following := func(t, lat, lon int) float32 { return nan }
+		if n, ok := nextColumns[c.name]; ok {
+			following = n.value
+		}
+
+		knotValues[c.name] = func(k, lat, lon int) float32 {
+			if k < count {
+				return current(k, lat, lon)
+			}
+
+			return following(k-count, lat, lon)
+		}
+	}
+
+	cols := make([]column, 0, len(knotValues))
+	for _, c := range in.src.columns() {
+		var (
+			value = knotValues[c.name]
+			pair  = knotValues[c.pair]
+			kind  = c.kind
+		)
+
+		if kind == vectorKind && pair == nil {
+			kind = scalarKind
+		}
+
+		cols = append(cols, column{
+			name: c.name,
+			kind: kind,
+			pair: c.pair,
+			value: func(t, lat, lon int) float32 {
+				s := in.slots[t]
+				if s.w == 0 {
+					return value(s.k, lat, lon)
+				}
+
+				v0, v1 := value(s.k, lat, lon), value(s.k+1, lat, lon)
+				switch kind {
+				case directionKind:
+					return interpolateDirection(v0, v1, s.w)
+				case vectorKind:
+					p0, p1 := pair(s.k, lat, lon), pair(s.k+1, lat, lon)
+					return interpolateVector(v0, p0, v1, p1, s.w)
+				default:
+					return interpolateLinear(v0, v1, s.w)
+				}
+			},
+		})
+	}
+
+	return cols
+}
+
+func interpolateLinear(v0, v1 float32, w float64) float32 {
+	return float32((1-w)*float64(v0) + w*float64(v1))
+}
+
+// interpolateDirection 沿着较短的弧插值方向, 例如 350 与 10 度之间插值为 0 度
+func interpolateDirection(d0, d1 float32, w float64) float32 {
+	if math.IsNaN(float64(d0)) || math.IsNaN(float64(d1)) {
+		return nan
+	}
+
+	delta := math.Mod(float64(d1-d0)+540, 360) - 180
+	degree := math.Mod(float64(d0)+w*delta+360, 360)
+	return float32(degree)
+}
+
+// interpolateVector 插值矢量的一个分量 v, p 为另一个分量
+// 分量线性插值决定方向, 模长单独线性插值, 避免方向变化较大时模长被低估
+func interpolateVector(v0, p0, v1, p1 float32, w float64) float32 {
+	v := float64(interpolateLinear(v0, v1, w))
+	p := float64(interpolateLinear(p0, p1, w))
+	if math.IsNaN(v) || math.IsNaN(p) {
+		return float32(v)
+	}
+
+	speed := (1-w)*math.Hypot(float64(v0), float64(p0)) + w*math.Hypot(float64(v1), float64(p1))
+	length := math.Hypot(v, p)
+	if length == 0 {
+		return float32(v)
+	}
+
+	return float32(v * speed / length)
+}
@@ -0,0 +1,230 @@
+package nc
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testSource 固定值的数据源, values[name][t] 为所有格点在时次 t 的值
+type testSource struct {
+	steps  []time.Time
+	kinds  map[string]columnKind
+	pairs  map[string]string
+	values map[string][]float32
+}
+
+func (s *testSource) latitudes() []float64  { return []float64{0} }
+func (s *testSource) longitudes() []float64 { return []float64{0} }
+func (s *testSource) times() []time.Time    { return s.steps }
+
+func (s *testSource) columns() []column {
+	cols := make([]column, 0, len(s.values))
+	for _, name := range []string{"u", "v", "direction", "height"} {
+		values, ok := s.values[name]
+		if !ok {
+			continue
+		}
+		cols = append(cols, column{
+			name:  name,
+			kind:  s.kinds[name],
+			pair:  s.pairs[name],
+			value: func(t, lat, lon int) float32 { return values[t] },
+		})
+	}
+
+	return cols
+}
+
+func equalFloat(a, b float32) bool {
+	if math.IsNaN(float64(a)) || math.IsNaN(float64(b)) {
+		return math.IsNaN(float64(a)) && math.IsNaN(float64(b))
+	}
+
+	return math.Abs(float64(a-b)) < 1e-4
+}
+
+func TestInterpolateDirection(t *testing.T) {
+	tests := []struct {
+		d0, d1 float32
+		w      float64
+		want   float32
+	}{
+		// 沿着较短的弧跨越 0 度
+		{d0: 350, d1: 10, w: 0.5, want: 0},
+		{d0: 350, d1: 10, w: 0.25, want: 355},
+		{d0: 350, d1: 10, w: 0.75, want: 5},
+		{d0: 10, d1: 350, w: 0.5, want: 0},
+		{d0: 10, d1: 350, w: 0.75, want: 355},
+		{d0: 80, d1: 100, w: 0.5, want: 90},
+		{d0: 350, d1: 10, w: 0, want: 350},
+		{d0: 350, d1: 10, w: 1, want: 10},
+		{d0: 0, d1: 360, w: 0.5, want: 0},
+		{d0: 350, d1: nan, w: 0.5, want: nan},
+		{d0: nan, d1: 10, w: 0.5, want: nan},
+	}
+
+	for _, tt := range tests {
+		got := interpolateDirection(tt.d0, tt.d1, tt.w)
+		if !equalFloat(got, tt.want) {
+			t.Errorf("interpolateDirection(%v, %v, %v) = %v, want %v", tt.d0, tt.d1, tt.w, got, tt.want)
+		}
+		if got < 0 || got >= 360 {
+			t.Errorf("interpolateDirection(%v, %v, %v) = %v out of range", tt.d0, tt.d1, tt.w, got)
+		}
+	}
+}
+
+func TestInterpolateVector(t *testing.T) {
+	tests := []struct {
+		name           string
+		v0, p0, v1, p1 float32
+		w              float64
+		want           float32
+	}{
+		{name: "same direction", v0: 2, p0: 0, v1: 4, p1: 0, w: 0.5, want: 3},
+		// 方向旋转 90 度, 分量线性插值的模长为 0.707, 按照模长 1 缩放
+		{name: "rotate", v0: 1, p0: 0, v1: 0, p1: 1, w: 0.5, want: float32(math.Sqrt(0.5))},
+		{name: "rotate pair", v0: 0, p0: 1, v1: 1, p1: 0, w: 0.5, want: float32(math.Sqrt(0.5))},
+		// 模长 5 到 10 之间插值为 7.5
+		{name: "rotate and grow", v0: 3, p0: 4, v1: -6, p1: 8, w: 0.5, want: float32(-1.5 * 7.5 / math.Hypot(-1.5, 6))},
+		{name: "opposite", v0: 1, p0: 0, v1: -1, p1: 0, w: 0.5, want: 0},
+		{name: "start", v0: 1, p0: 0, v1: 0, p1: 1, w: 0, want: 1},
+		{name: "nan pair", v0: 1, p0: nan, v1: 3, p1: 0, w: 0.5, want: 2},
+		{name: "nan", v0: nan, p0: 0, v1: 3, p1: 0, w: 0.5, want: nan},
+	}
+
+	for _, tt := range tests {
+		if got := interpolateVector(tt.v0, tt.p0, tt.v1, tt.p1, tt.w); !equalFloat(got, tt.want) {
+			t.Errorf("interpolateVector(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	start := time.Date(2025, 6, 13, 0, 0, 0, 0, time.UTC)
+	kinds := map[string]columnKind{"u": vectorKind, "v": vectorKind, "direction": directionKind}
+	pairs := map[string]string{"u": "v", "v": "u"}
+
+	src := &testSource{
+		steps:  []time.Time{start, start.Add(6 * time.Hour)},
+		kinds:  kinds,
+		pairs:  pairs,
+		values: map[string][]float32{"u": {1, 0}, "v": {0, 1}, "direction": {350, 10}, "height": {1, 3}},
+	}
+	next := &testSource{
+		steps:  []time.Time{start.Add(12 * time.Hour)},
+		kinds:  kinds,
+		pairs:  pairs,
+		values: map[string][]float32{"u": {0}, "v": {1}, "direction": {50}},
+	}
+
+	tests := []struct {
+		name  string
+		next  source
+		times int
+		want  map[string][]float32
+	}{
+		// 只在当前文件的时次之间插值, 包含最后一个时次
+		{name: "without next", times: 3, want: map[string][]float32{
+			"u":         {1, float32(math.Sqrt(0.5)), 0},
+			"direction": {350, 0, 10},
+			"height":    {1, 2, 3},
+		}},
+		// 插值到下一个文件的第一个时次为止, 不包含, 下一个文件缺少的列为 NaN
+		{name: "with next", next: next, times: 4, want: map[string][]float32{
+			"u":         {1, float32(math.Sqrt(0.5)), 0, 0},
+			"direction": {350, 0, 10, 30},
+			"height":    {1, 2, 3, nan},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := interpolate(src, tt.next, 3*time.Hour)
+
+			times := in.times()
+			if len(times) != tt.times {
+				t.Fatalf("times = %v, want %d", times, tt.times)
+			}
+			for i, ti := range times {
+				if want := start.Add(time.Duration(i) * 3 * time.Hour); !ti.Equal(want) {
+					t.Errorf("times[%d] = %v, want %v", i, ti, want)
+				}
+			}
+
+			for _, c := range in.columns() {
+				want, ok := tt.want[c.name]
+				if !ok {
+					continue
+				}
+				for i := range times {
+					if got := c.value(i, 0, 0); !equalFloat(got, want[i]) {
+						t.Errorf("%s[%d] = %v, want %v", c.name, i, got, want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPartialLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	next := filepath.Join(dir, "ec_2025061312.nc")
+
+	info := &NCFile{
+		OutputPath: filepath.Join(dir, "ec_2025061300.csv"),
+		Interval:   3 * time.Hour,
+		Next:       &NCFile{InputPath: next},
+	}
+
+	// 下一个起报时次的文件缺失, 生成后记录标记, 起报时次当作没有完成
+	if err := info.markPartial(true); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(info.partialPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != next+"\n" {
+		t.Errorf("partial mark = %q, want %q", data, next+"\n")
+	}
+	if info.Generated(ctx) {
+		t.Errorf("Generated() = true with partial mark")
+	}
+	if info.partialReady() {
+		t.Errorf("partialReady() = true before next file arrived")
+	}
+
+	// 下一个文件到达后需要重新生成
+	if err := os.WriteFile(next, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !info.partialReady() {
+		t.Errorf("partialReady() = false after next file arrived")
+	}
+
+	// 不插值时不检查标记
+	noInterval := *info
+	noInterval.Interval = 0
+	if noInterval.partialReady() {
+		t.Errorf("partialReady() = true without interval")
+	}
+
+	// 重新生成后清除标记, 重复清除不返回错误
+	for range 2 {
+		if err := info.markPartial(false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(info.partialPath()); !os.IsNotExist(err) {
+		t.Errorf("partial mark not removed: %v", err)
+	}
+	if info.partialReady() {
+		t.Errorf("partialReady() = true after partial mark removed")
+	}
+}
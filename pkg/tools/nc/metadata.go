@@ -0,0 +1,189 @@
+package nc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gen-meteo-file/pkg/global"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// MetadataName 压缩文件中元数据文件的名称
+const MetadataName = "metadata.json"
+
+// fillToken 输出文件中缺测值的写法
+const fillToken = "NaN"
+
+// metadata 每个输出文件的说明, 与 csv 一起压缩, 便于下游系统校验和解析
+type metadata struct {
+	Dataset       string            `json:"dataset"`
+	Region        metadataRegion    `json:"region"`
+	Sources       []metadataSource  `json:"sources"`
+	ReferenceTime string            `json:"referenceTime"` // 起报时间
+	RunTime       string            `json:"runTime"`       // 生成时间
+	ValidTimes    []string          `json:"validTimes"`
+	Grid          metadataGrid      `json:"grid"`
+	Columns       []metadataColumn  `json:"columns"`
+	Missing       map[string]string `json:"missing,omitempty"` // 合并输出缺失的数据源及原因, 缺失数据源的列为缺测值, 数据源到达后重新生成
+	FillToken     string            `json:"fillToken"`
+	Generator     metadataVersion   `json:"generator"`
+}
+
+type metadataRegion struct {
+	Name    string `json:"name"`
+	Polygon bool   `json:"polygon"` // 为 true 时只输出多边形内的格点
+}
+
+type metadataSource struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// metadataGrid 输出格点的范围和间隔, 跨越 180 度经线时 west > east
+type metadataGrid struct {
+	West          float64 `json:"west"`
+	South         float64 `json:"south"`
+	East          float64 `json:"east"`
+	North         float64 `json:"north"`
+	LatResolution float64 `json:"latResolution"`
+	LonResolution float64 `json:"lonResolution"`
+	LatCount      int     `json:"latCount"`
+	LonCount      int     `json:"lonCount"`
+	LatStride     int     `json:"latStride"`
+	LonStride     int     `json:"lonStride"`
+	TimeStride    int     `json:"timeStride"`
+	Regrid        string  `json:"regrid,omitempty"`       // 插值方法, 为空表示原始网格
+	TimeInterval  string  `json:"timeInterval,omitempty"` // 时间插值的步长, 为空表示不插值
+	Partial       bool    `json:"partial,omitempty"`      // 缺少下一个起报时次的文件, 只在当前文件的时次之间插值, 文件到达后重新生成
+}
+
+type metadataColumn struct {
+	Name        string `json:"name"`
+	Unit        string `json:"unit,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type metadataVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// newMetadata 生成单个输出文件的元数据, stride 为实际生效的抽样步长
+func newMetadata(info *NCFile, p product, sources []metadataSource, stride Stride, f *frame) *metadata {
+	m := &metadata{
+		Dataset:       p.name,
+		Region:        metadataRegion{Name: GlobalRegionName, Polygon: false},
+		Sources:       sources,
+		ReferenceTime: info.DateTime.UTC().Format(time.RFC3339),
+		RunTime:       time.Now().UTC().Format(time.RFC3339),
+		ValidTimes:    make([]string, 0, len(f.times)),
+		Grid: metadataGrid{
+			LatCount:   len(f.lats),
+			LonCount:   len(f.lons),
+			LatStride:  stride.lat(),
+			LonStride:  stride.lon(),
+			TimeStride: stride.time(),
+		},
+		Columns:   make([]metadataColumn, 0, len(f.columns)),
+		FillToken: fillToken,
+		Generator: metadataVersion{Name: global.ProgramName, Version: global.Version},
+	}
+
+	if f.region.Name != "" {
+		m.Region = metadataRegion{Name: f.region.Name, Polygon: f.region.Mask != nil}
+	}
+
+	for _, t := range f.times {
+		m.ValidTimes = append(m.ValidTimes, t.UTC().Format(time.RFC3339))
+	}
+
+	if len(f.lats) > 0 && len(f.lons) > 0 {
+		m.Grid.South = math.Min(f.lats[0], f.lats[len(f.lats)-1])
+		m.Grid.North = math.Max(f.lats[0], f.lats[len(f.lats)-1])
+		m.Grid.West, m.Grid.East = f.lons[0], f.lons[len(f.lons)-1]
+	}
+	if len(f.lats) > 1 {
+		m.Grid.LatResolution = math.Abs(f.lats[1] - f.lats[0])
+	}
+	if len(f.lons) > 1 {
+		m.Grid.LonResolution = math.Abs(f.lons[1] - f.lons[0])
+	}
+
+	m.Missing = p.missing
+
+	m.Grid.Regrid = p.regridded
+	if info.Target != nil {
+		m.Grid.Regrid = string(info.Target.Method)
+		m.Grid.LatStride, m.Grid.LonStride = 1, 1
+	}
+	if info.Interval > 0 {
+		m.Grid.TimeInterval = info.Interval.String()
+		m.Grid.Partial = p.partial
+	}
+
+	for _, c := range f.columns {
+		attribute := columnAttributes[c.name]
+		unit := c.unit
+		if unit == "" {
+			unit = attribute.unit
+		}
+
+		m.Columns = append(m.Columns, metadataColumn{Name: c.name, Unit: unit, Description: attribute.description})
+	}
+
+	return m
+}
+
+// generator 程序名称和版本
+func generator() string {
+	return global.ProgramName + " " + global.Version
+}
+
+func (m *metadata) marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal metadata failed: %v", err)
+	}
+
+	return data, nil
+}
+
+// checksums 计算输入文件的 sha256, 每个输入文件只计算一次, locations 中有原始位置时记录原始位置
+func checksums(paths []string, locations map[string]string) ([]metadataSource, error) {
+	sources := make([]metadataSource, 0, len(paths))
+	for _, path := range paths {
+		sum, err := checksum(path)
+		if err != nil {
+			return nil, err
+		}
+
+		// 对象存储下载的缓存文件记录对象的位置
+		location := path
+		if l, ok := locations[path]; ok {
+			location = l
+		}
+
+		sources = append(sources, metadataSource{Path: location, SHA256: sum})
+	}
+
+	return sources, nil
+}
+
+func checksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open input file: %s failed: %v", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("checksum input file: %s failed: %v", path, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
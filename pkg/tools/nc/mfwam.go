@@ -1,10 +1,8 @@
 package nc
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/batchatco/go-native-netcdf/netcdf"
@@ -32,6 +30,19 @@ const (
 	MFWAMStep                = float32(1. / 12.)
 )
 
+// mfwamVariables NetCDF 变量名对应的列名
+var mfwamVariables = map[string]string{
+	MFWAMSeaHeightField:      "seaWaveHeight",
+	MFWAMSeaDirectionField:   "seaWaveDirection",
+	MFWAMSeaPeriodField:      "seaWavePeriod",
+	MFWAMSwellHeightField:    "swellWaveHeight",
+	MFWAMSwellDirectionField: "swellWaveDirection",
+	MFWAMSwellPeriodField:    "swellWavePeriod",
+	MFWAMWindHeightField:     "windWaveHeight",
+	MFWAMWindDirectionField:  "windWaveDirection",
+	MFWAMWindPeriodField:     "windWavePeriod",
+}
+
 type MFWAM struct {
 	info                    *NCFile
 	group                   api.Group
@@ -75,23 +86,16 @@ type MFWAM struct {
 	windPeriodScale         float32
 }
 
-func NewMFWAM(info *NCFile) (*MFWAM, error) {
-	if _, err := os.Stat(info.InputPath); err != nil {
-		return nil, fmt.Errorf("mfwam input file: %s not exists", info.InputPath)
-	}
-
-	if _, err := os.Stat(info.OutputPath); err == nil {
-		return nil, fmt.Errorf("mfwam output file: %s already exists", info.OutputPath)
-	}
-
-	if _, err := os.Stat(info.CompressionPath); err == nil {
-		return nil, fmt.Errorf("mfwam compression file: %s already exists", info.CompressionPath)
+func NewMFWAM(ctx context.Context, info *NCFile) (*MFWAM, error) {
+	if err := info.check(ctx, "mfwam"); err != nil {
+		return nil, err
 	}
 
-	if err := os.MkdirAll(filepath.Dir(info.OutputPath), os.FileMode(0755)); err != nil {
-		return nil, fmt.Errorf("create mfwam output dir: %s failed: %v", filepath.Dir(info.OutputPath), err)
-	}
+	return openMFWAM(info)
+}
 
+// openMFWAM 只打开输入文件, 不检查输出文件
+func openMFWAM(info *NCFile) (*MFWAM, error) {
 	group, err := netcdf.Open(info.InputPath)
 	if err != nil {
 		return nil, fmt.Errorf("open mfwam netcdf file: %s failed: %v", info.InputPath, err)
@@ -329,100 +333,49 @@ func (nc *MFWAM) Analysis() error {
 	return nil
 }
 
-func (nc *MFWAM) GenerateCSV() error {
-	file, err := os.OpenFile(nc.info.OutputPath, os.O_CREATE|os.O_RDWR, os.FileMode(0664))
-	if err != nil {
-		return fmt.Errorf("output file: %s create failed: %v", nc.info.OutputPath, err)
-	}
-	defer file.Close()
+func (nc *MFWAM) GenerateCSV(ctx context.Context) error {
+	return generate(ctx, nc.info, nc, product{
+		name:      "mfwam",
+		precision: 3,
+		open:      func(info *NCFile) (dataset, error) { return openMFWAM(info) },
+		layer:     "seaWaveHeight",
+		variables: mfwamVariables,
+	})
+}
+
+func (nc *MFWAM) latitudes() []float64 {
+	return nc.latitudeList
+}
+
+func (nc *MFWAM) longitudes() []float64 {
+	return nc.longitudeList
+}
 
-	buf := bufio.NewWriter(file)
-	buf.WriteString("lat,lon,dateTime,seaWaveHeight,seaWaveDirection,seaWavePeriod,swellWaveHeight,swellWaveDirection,swellWavePeriod,windWaveHeight,windWaveDirection,windWavePeriod\n")
+// 每 3 小时一个时次
+func (nc *MFWAM) times() []time.Time {
+	times := make([]time.Time, 0, MFWAMTimeCount)
 	for timeIndex := range MFWAMTimeCount {
-		for latIndex := 0; latIndex < MFWAMLatitudeCount; latIndex += 3 {
-			for lonIndex := 0; lonIndex < MFWAMLongitudeCount; lonIndex += 3 {
-				buf.WriteString(
-					fmt.Sprintf(
-						"%.3f,%.3f,%s",
-						nc.latitudeList[latIndex],
-						nc.longitudeList[lonIndex],
-						nc.info.DateTime.Add(time.Hour*time.Duration(timeIndex*3)).Format(time.DateTime),
-					),
-				)
-
-				// 显浪
-				seaHeight := nc.seaHeightList[timeIndex][latIndex][lonIndex]
-				if seaHeight == nc.seaHeightFillValue {
-					buf.WriteString(",NaN")
-				} else {
-					buf.WriteString(fmt.Sprintf(",%f", convertInt16ToFloat32(seaHeight, nc.seaHeightScale, nc.seaHeightAddOffset)))
-				}
-
-				seaDirection := nc.seaDirectionList[timeIndex][latIndex][lonIndex]
-				if seaDirection == nc.seaDirectionFillValue {
-					buf.WriteString(",NaN")
-				} else {
-					buf.WriteString(fmt.Sprintf(",%f", convertInt16ToFloat32(seaDirection, nc.seaDirectionScale, nc.seaDirectionAddOffset)))
-				}
-
-				seaPeriod := nc.seaPeriodList[timeIndex][latIndex][lonIndex]
-				if seaPeriod == nc.seaPeriodFillValue {
-					buf.WriteString(",NaN")
-				} else {
-					buf.WriteString(fmt.Sprintf(",%f", convertInt16ToFloat32(seaPeriod, nc.seaPeriodScale, nc.seaPeriodAddOffset)))
-				}
-
-				// 涌浪
-				swellHeight := nc.swellHeightList[timeIndex][latIndex][lonIndex]
-				if swellHeight == nc.swellHeightFillValue {
-					buf.WriteString(",NaN")
-				} else {
-					buf.WriteString(fmt.Sprintf(",%f", convertInt16ToFloat32(swellHeight, nc.swellHeightScale, nc.swellHeightAddOffset)))
-				}
-
-				swellDirection := nc.swellDirectionList[timeIndex][latIndex][lonIndex]
-				if swellDirection == nc.swellDirectionFillValue {
-					buf.WriteString(",NaN")
-				} else {
-					buf.WriteString(fmt.Sprintf(",%f", convertInt16ToFloat32(swellDirection, nc.swellDirectionScale, nc.swellDirectionAddOffset)))
-				}
-
-				swellPeriod := nc.swellPeriodList[timeIndex][latIndex][lonIndex]
-				if swellPeriod == nc.swellPeriodFillValue {
-					buf.WriteString(",NaN")
-				} else {
-					buf.WriteString(fmt.Sprintf(",%f", convertInt16ToFloat32(swellPeriod, nc.swellPeriodScale, nc.swellPeriodAddOffset)))
-				}
-
-				// 风浪
-				windHeight := nc.windHeightList[timeIndex][latIndex][lonIndex]
-				if windHeight == nc.windHeightFillValue {
-					buf.WriteString(",NaN")
-				} else {
-					buf.WriteString(fmt.Sprintf(",%f", convertInt16ToFloat32(windHeight, nc.windHeightScale, nc.windHeightAddOffset)))
-				}
-
-				windDirection := nc.windDirectionList[timeIndex][latIndex][lonIndex]
-				if windDirection == nc.windDirectionFillValue {
-					buf.WriteString(",NaN")
-				} else {
-					buf.WriteString(fmt.Sprintf(",%f", convertInt16ToFloat32(windDirection, nc.windDirectionScale, nc.windDirectionAddOffset)))
-				}
-
-				windPeriod := nc.windPeriodList[timeIndex][latIndex][lonIndex]
-				if windPeriod == nc.windPeriodFillValue {
-					buf.WriteString(",NaN\n")
-				} else {
-					buf.WriteString(fmt.Sprintf(",%f\n", convertInt16ToFloat32(windPeriod, nc.windPeriodScale, nc.windPeriodAddOffset)))
-				}
-			}
-		}
-
-		buf.Flush()
-	}
-
-	buf.Flush()
-	return zipFile(nc.info.OutputPath, nc.info.CompressionPath)
+		times = append(times, nc.info.DateTime.Add(time.Hour*time.Duration(timeIndex*3)))
+	}
+
+	return times
+}
+
+func (nc *MFWAM) columns() []column {
+	return []column{
+		// 显浪
+		{name: "seaWaveHeight", value: int16Value(nc.seaHeightList, nc.seaHeightFillValue, nc.seaHeightScale, nc.seaHeightAddOffset)},
+		{name: "seaWaveDirection", kind: directionKind, value: int16Value(nc.seaDirectionList, nc.seaDirectionFillValue, nc.seaDirectionScale, nc.seaDirectionAddOffset)},
+		{name: "seaWavePeriod", value: int16Value(nc.seaPeriodList, nc.seaPeriodFillValue, nc.seaPeriodScale, nc.seaPeriodAddOffset)},
+		// 涌浪
+		{name: "swellWaveHeight", value: int16Value(nc.swellHeightList, nc.swellHeightFillValue, nc.swellHeightScale, nc.swellHeightAddOffset)},
+		{name: "swellWaveDirection", kind: directionKind, value: int16Value(nc.swellDirectionList, nc.swellDirectionFillValue, nc.swellDirectionScale, nc.swellDirectionAddOffset)},
+		{name: "swellWavePeriod", value: int16Value(nc.swellPeriodList, nc.swellPeriodFillValue, nc.swellPeriodScale, nc.swellPeriodAddOffset)},
+		// 风浪
+		{name: "windWaveHeight", value: int16Value(nc.windHeightList, nc.windHeightFillValue, nc.windHeightScale, nc.windHeightAddOffset)},
+		{name: "windWaveDirection", kind: directionKind, value: int16Value(nc.windDirectionList, nc.windDirectionFillValue, nc.windDirectionScale, nc.windDirectionAddOffset)},
+		{name: "windWavePeriod", value: int16Value(nc.windPeriodList, nc.windPeriodFillValue, nc.windPeriodScale, nc.windPeriodAddOffset)},
+	}
 }
 
 func (nc *MFWAM) Close() {
@@ -1,75 +1,239 @@
 package nc
 
 import (
-	"archive/zip"
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"gen-meteo-file/pkg/tools/regrid"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
+// ErrGenerated 输出文件, 瓦片, GeoTIFF 和 velocity JSON 已经全部完成, 不需要重新生成
+var ErrGenerated = errors.New("nc: output files already generated")
+
 type NCFile struct {
 	DateTime        time.Time
 	InputPath       string
 	OutputPath      string
 	CompressionPath string
+	Stride          Stride
+	Regions         []Region          // 为空时只输出全球数据
+	Target          *regrid.Target    // 不为空时插值到目标网格后输出
+	Interval        time.Duration     // 时间插值的步长, 0 表示不插值, 插值后时间抽样步长不再生效
+	Next            *NCFile           // 下一个起报时次的输入文件, 只使用 DateTime 和 InputPath, 用于插值两个文件之间的时次, 文件缺失时到达后重新生成
+	Derived         []string          // 追加输出的派生列, 例如: windSpeed, beaufort, currentSpeed, residualCurrentDirection
+	Columns         []string          // 只输出指定的列(包括派生列), 为空时输出所有列
+	Units           UnitProfile       // 输出列的单位方案, 为空时输出原始单位
+	Formats         []Format          // 输出格式, 为空时只输出 csv
+	ParquetCodec    ParquetCodec      // parquet 的压缩方式, 为空时使用 snappy
+	Archive         ArchiveCodec      // csv 与元数据的打包方式, 为空时使用 zip, 决定 CompressionPath 的扩展名
+	ArchiveLevel    int               // 压缩级别 1~9, 0 表示默认级别
+	GeoJSONThin     int               // GeoJSON 每隔多少个格点输出一个, 小于等于 1 时输出所有格点
+	Tiles           *Tiles            // 不为空时输出图层的 PNG 瓦片, 风: 风速, 浪: 有效波高, 流: 流速
+	Velocity        *Velocity         // 不为空时输出 leaflet-velocity 格式的 u/v JSON, 只支持风和流
+	GeoTIFF         []string          // 输出 GeoTIFF 的变量, NetCDF 变量名或列名, 每个变量一个文件, 例如: mfwam_VHM0_2025061300.tif
+	Profiles        []Profile         // 额外的输出方案, 与默认输出共用一次解码
+	Uploaders       []Uploader        // 输出文件生成后上传到远端, 为空时不上传
+	DeleteUploaded  bool              // 所有 Uploader 上传成功后删除本地的输出文件
+	Checksum        bool              // 计算输出文件的 sha256, 只在需要通知下游时计算
+	Locations       map[string]string // 本地输入文件对应的原始位置, 例如: 对象存储下载的缓存文件对应 s3://bucket/key, 元数据中记录原始位置
+
+	profile    string            // 输出方案名称, 默认输出为空
+	results    []Result          // 最近一次生成完成的输出文件
+	regenerate bool              // 上一次生成时缺少下一个起报时次的文件或者合并输出的数据源, 已经生成的文件也需要重新生成
+	sources    map[string]string // 合并输出每个数据源的输入文件, 用于检查上一次缺失的数据源是否已经到达
 }
 
-// source: https://help.marine.copernicus.eu/en/articles/5470092-how-to-use-add_offset-and-scale_factor-to-calculate-real-values-of-a-variable
-// Real_Value = (Display_Value X scale_factor) + add_offset
-func convertInt16ToFloat32(real int16, scaleFactor, addOffset float32) float32 {
-	return float32(real)*scaleFactor + addOffset
+// check 检查输出文件是否已经全部生成, 输入文件是否存在, 并创建输出目录, 已经全部生成时返回 ErrGenerated
+// 先检查输出文件, 已经生成的起报时次不需要输入文件, 例如: 对象存储中的输入文件没有下载
+func (info *NCFile) check(ctx context.Context, name string) error {
+	if err := info.checkOutputs(ctx, name); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(info.InputPath); err != nil {
+		return fmt.Errorf("%s input file: %s not exists", name, info.InputPath)
+	}
+
+	return info.createOutputDirs(name)
 }
 
-// src: /data1/cosco-generate-files/2025/06/2025-06-13/ec_2025061315.csv
-// dst: /data1/cosco-generate-files/2025/06/2025-06-13/ec_2025061315.zip
-func zipFile(src, dst string) error {
-	defer os.Remove(src)
+// checkOutputs 检查所有输出方案的文件是否已经全部生成, 已经全部生成时返回 ErrGenerated
+func (info *NCFile) checkOutputs(ctx context.Context, name string) error {
+	info.regenerate = info.partialReady()
+
+	for _, target := range info.profiles() {
+		for _, out := range target.outputs() {
+			if _, err := os.Stat(out.outputPath); err == nil {
+				return fmt.Errorf("%s output file: %s already exists", name, out.outputPath)
+			}
+		}
+	}
 
-	// 创建目标 zip 文件
-	zipFile, err := os.Create(dst)
-	if err != nil {
-		return fmt.Errorf("create zip file: %s failed: %v", dst, err)
+	// 输出文件都已经生成时, 瓦片, GeoTIFF 和 velocity JSON 也需要全部完成, 例如: 上次渲染失败或者新开启了瓦片
+	if info.pending(ctx) == 0 && info.sideGenerated() {
+		return ErrGenerated
 	}
-	defer zipFile.Close()
 
-	// 创建 zip writer
-	zipWriter := zip.NewWriter(zipFile)
-	defer zipWriter.Close()
+	return nil
+}
 
-	// 打开源文件
-	fileToZip, err := os.Open(src)
-	if err != nil {
-		return fmt.Errorf("open source file: %s failed: %v", src, err)
+// Generated 输出文件, 瓦片, GeoTIFF 和 velocity JSON 是否已经全部完成, 不检查输入文件, 例如: 下载对象存储中的输入文件之前
+// 缺少下一个起报时次的文件时生成的起报时次当作没有完成, 下载后由 checkOutputs 检查下一个文件是否已经到达
+func (info *NCFile) Generated(ctx context.Context) bool {
+	if _, err := os.Stat(info.partialPath()); err == nil {
+		return false
 	}
-	defer fileToZip.Close()
 
-	// 获取源文件信息
-	info, err := fileToZip.Stat()
-	if err != nil {
-		return fmt.Errorf("get file info failed: %v", err)
+	return info.pending(ctx) == 0 && info.sideGenerated()
+}
+
+// pending 所有输出方案中还没有生成或者还没有上传的文件数量
+func (info *NCFile) pending(ctx context.Context) int {
+	pending := 0
+	for _, target := range info.profiles() {
+		for _, out := range target.outputs() {
+			if done, _, _ := target.generated(ctx, out.resultPath); !done {
+				pending++
+			}
+		}
 	}
 
-	// 创建 zip 文件头
-	header, err := zip.FileInfoHeader(info)
-	if err != nil {
-		return fmt.Errorf("create zip header failed: %v", err)
+	return pending
+}
+
+// createOutputDirs 创建所有输出方案的输出目录
+func (info *NCFile) createOutputDirs(name string) error {
+	for _, target := range info.profiles() {
+		if err := os.MkdirAll(filepath.Dir(target.OutputPath), os.FileMode(0755)); err != nil {
+			return fmt.Errorf("create %s output dir: %s failed: %v", name, filepath.Dir(target.OutputPath), err)
+		}
+	}
+
+	return nil
+}
+
+// sideOutputs 瓦片, GeoTIFF 和 velocity JSON 的配置说明, 配置变化时需要重新检查, 为空表示没有开启
+func (info *NCFile) sideOutputs() string {
+	var parts []string
+	if info.Tiles != nil {
+		parts = append(parts, "tiles: "+info.Tiles.Dir)
+	}
+	if len(info.GeoTIFF) > 0 {
+		parts = append(parts, "geotiff: "+strings.Join(info.GeoTIFF, ","))
+	}
+	if info.Velocity != nil {
+		parts = append(parts, "velocity: "+info.Velocity.Dir)
 	}
 
-	// 设置压缩方法
-	header.Method = zip.Deflate
+	if len(parts) == 0 {
+		return ""
+	}
+
+	return strings.Join(parts, "\n") + "\n"
+}
+
+// sidePath 瓦片, GeoTIFF 和 velocity JSON 全部完成后写入的标记文件, 内容为 sideOutputs
+func (info *NCFile) sidePath() string {
+	return withExt(info.OutputPath, ".side")
+}
 
-	// 创建 zip 文件写入器
-	writer, err := zipWriter.CreateHeader(header)
-	if err != nil {
-		return fmt.Errorf("create zip writer failed: %v", err)
+// sideGenerated 瓦片, GeoTIFF 和 velocity JSON 是否已经按照当前的配置全部完成
+func (info *NCFile) sideGenerated() bool {
+	side := info.sideOutputs()
+	if side == "" {
+		return true
 	}
 
-	// 将源文件内容复制到 zip 文件中
-	_, err = io.Copy(writer, fileToZip)
-	if err != nil {
-		return fmt.Errorf("copy file content failed: %v", err)
+	data, err := os.ReadFile(info.sidePath())
+	return err == nil && string(data) == side
+}
+
+// markSideGenerated 瓦片, GeoTIFF 和 velocity JSON 全部完成后记录标记, 之后的生成不再解码这个起报时次
+func (info *NCFile) markSideGenerated() error {
+	side := info.sideOutputs()
+	if side == "" {
+		return nil
+	}
+
+	if err := os.WriteFile(info.sidePath(), []byte(side), os.FileMode(0644)); err != nil {
+		return fmt.Errorf("write side output mark: %s failed: %v", info.sidePath(), err)
 	}
 
 	return nil
 }
+
+// withExt 替换文件的扩展名
+func withExt(path, ext string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ext
+}
+
+func withSuffix(path, suffix string) string {
+	if suffix == "" {
+		return path
+	}
+
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "_" + suffix + ext
+}
+
+// Stride 输出时的抽样步长, 1 表示原始分辨率, 小于 1 时按 1 处理
+type Stride struct {
+	Lat  int
+	Lon  int
+	Time int
+}
+
+func (s Stride) lat() int  { return atLeastOne(s.Lat) }
+func (s Stride) lon() int  { return atLeastOne(s.Lon) }
+func (s Stride) time() int { return atLeastOne(s.Time) }
+
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+
+	return n
+}
+
+// source: https://help.marine.copernicus.eu/en/articles/5470092-how-to-use-add_offset-and-scale_factor-to-calculate-real-values-of-a-variable
+// Real_Value = (Display_Value X scale_factor) + add_offset
+func convertInt16ToFloat32(real int16, scaleFactor, addOffset float32) float32 {
+	return float32(real)*scaleFactor + addOffset
+}
+
+// int16Value 按照 scale_factor 和 add_offset 还原 int16 变量的真实值
+func int16Value(list [][][]int16, fillValue int16, scaleFactor, addOffset float32) func(t, lat, lon int) float32 {
+	return func(t, lat, lon int) float32 {
+		value := list[t][lat][lon]
+		if value == fillValue {
+			return nan
+		}
+
+		return convertInt16ToFloat32(value, scaleFactor, addOffset)
+	}
+}
+
+// float32Value 读取 (time, depth, lat, lon) 变量表层的值
+func float32Value(list [][][][]float32, fillValue float32) func(t, lat, lon int) float32 {
+	return func(t, lat, lon int) float32 {
+		value := list[t][0][lat][lon]
+		if value == fillValue {
+			return nan
+		}
+
+		return value
+	}
+}
+
+func toFloat64(list []float32) []float64 {
+	values := make([]float64, len(list))
+	for i, value := range list {
+		values[i] = float64(value)
+	}
+
+	return values
+}
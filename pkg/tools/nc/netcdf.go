@@ -0,0 +1,202 @@
+package nc
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/batchatco/go-native-netcdf/netcdf/api"
+	"github.com/batchatco/go-native-netcdf/netcdf/cdf"
+	"github.com/batchatco/go-native-netcdf/netcdf/util"
+)
+
+const (
+	netcdfFloatFill = float32(9.9692099683868690e+36) // NC_FILL_FLOAT
+	netcdfShortFill = int16(-32767)                   // NC_FILL_SHORT
+)
+
+// 列的单位转换为 udunits 的写法
+var cfUnits = map[string]string{
+	"m/s":  "m s-1",
+	"kn":   "knots",
+	"deg":  "degree",
+	"inHg": "inch_Hg",
+}
+
+// 列对应的 CF standard_name, 没有对应的标准名称时不写
+var cfStandardNames = map[string]string{
+	"wind10mU":           "eastward_wind",
+	"wind10mV":           "northward_wind",
+	"temperature2m":      "air_temperature",
+	"surfacePressure":    "surface_air_pressure",
+	"seaWaveHeight":      "sea_surface_wave_significant_height",
+	"seaWaveDirection":   "sea_surface_wave_from_direction",
+	"seaWavePeriod":      "sea_surface_wave_mean_period_from_variance_spectral_density_first_frequency_moment",
+	"swellWaveHeight":    "sea_surface_primary_swell_wave_significant_height",
+	"swellWaveDirection": "sea_surface_primary_swell_wave_from_direction",
+	"swellWavePeriod":    "sea_surface_primary_swell_wave_mean_period",
+	"windWaveHeight":     "sea_surface_wind_wave_significant_height",
+	"windWaveDirection":  "sea_surface_wind_wave_from_direction",
+	"windWavePeriod":     "sea_surface_wind_wave_mean_period",
+	"uCurrent":           "eastward_sea_water_velocity",
+	"vCurrent":           "northward_sea_water_velocity",
+	"windSpeed":          "wind_speed",
+	"windSpeedKnots":     "wind_speed",
+	"windDirection":      "wind_from_direction",
+	"currentSpeed":       "sea_water_speed",
+	"currentSpeedKnots":  "sea_water_speed",
+	"currentDirection":   "sea_water_velocity_to_direction",
+}
+
+// writeNetCDF 写入符合 CF 约定的 NetCDF 文件, 维度为 time, lat, lon
+// 数据列为 float(分类值为 short), 缺测值和多边形外的格点写为 _FillValue
+func writeNetCDF(path string, f *frame, name string, meta []byte) error {
+	w, err := cdf.OpenWriter(path)
+	if err != nil {
+		return fmt.Errorf("output file: %s create failed: %v", path, err)
+	}
+
+	// 出错时也需要关闭文件, 由调用方删除未完成的文件
+	if err := addNetCDFVars(w, f, name, meta); err != nil {
+		w.Close()
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("write output file: %s failed: %v", path, err)
+	}
+
+	return nil
+}
+
+func addNetCDFVars(w *cdf.CDFWriter, f *frame, name string, meta []byte) error {
+	reference := f.times[0].UTC()
+	times := make([]float64, len(f.times))
+	for i, t := range f.times {
+		times[i] = t.Sub(reference).Hours()
+	}
+
+	global, err := attributes(
+		"Conventions", "CF-1.8",
+		"title", fmt.Sprintf("%s subset", name),
+		"history", fmt.Sprintf("%s created by %s", time.Now().UTC().Format(time.RFC3339), generator()),
+		"metadata", string(meta),
+	)
+	if err != nil {
+		return err
+	}
+	if err := w.AddGlobalAttrs(global); err != nil {
+		return fmt.Errorf("add global attributes failed: %v", err)
+	}
+
+	coordinates := []struct {
+		name   string
+		values []float64
+		attrs  []interface{}
+	}{
+		{"time", times, []interface{}{"standard_name", "time", "long_name", "time", "units", "hours since " + reference.Format("2006-01-02 15:04:05"), "calendar", "standard", "axis", "T"}},
+		{"lat", f.lats, []interface{}{"standard_name", "latitude", "long_name", "latitude", "units", "degrees_north", "axis", "Y"}},
+		{"lon", continuousLons(f.lons), []interface{}{"standard_name", "longitude", "long_name", "longitude", "units", "degrees_east", "axis", "X"}},
+	}
+	for _, c := range coordinates {
+		attrs, err := attributes(c.attrs...)
+		if err != nil {
+			return err
+		}
+
+		if err := w.AddVar(c.name, api.Variable{Values: c.values, Dimensions: []string{c.name}, Attributes: attrs}); err != nil {
+			return fmt.Errorf("add variable: %s failed: %v", c.name, err)
+		}
+	}
+
+	for _, c := range f.columns {
+		kv := []interface{}{"long_name", columnAttributes[c.name].description}
+		if standardName, ok := cfStandardNames[c.name]; ok {
+			kv = append(kv, "standard_name", standardName)
+		}
+
+		unit := c.unit
+		if unit == "" {
+			unit = columnAttributes[c.name].unit
+		}
+		if cf, ok := cfUnits[unit]; ok {
+			unit = cf
+		}
+		if unit != "" {
+			kv = append(kv, "units", unit)
+		}
+
+		var values interface{}
+		if c.integer {
+			kv = append(kv, "_FillValue", netcdfShortFill)
+			values = netcdfValues(f, c, netcdfShortFill, func(v float32) int16 { return int16(v) })
+		} else {
+			kv = append(kv, "_FillValue", netcdfFloatFill)
+			values = netcdfValues(f, c, netcdfFloatFill, func(v float32) float32 { return v })
+		}
+		kv = append(kv, "coordinates", "time lat lon")
+
+		attrs, err := attributes(kv...)
+		if err != nil {
+			return err
+		}
+
+		if err := w.AddVar(c.name, api.Variable{Values: values, Dimensions: []string{"time", "lat", "lon"}, Attributes: attrs}); err != nil {
+			return fmt.Errorf("add variable: %s failed: %v", c.name, err)
+		}
+	}
+
+	return nil
+}
+
+// netcdfValues 按照 time, lat, lon 的顺序读取列的值, 缺测值和区域外的格点写为 fill
+func netcdfValues[T float32 | int16](f *frame, c column, fill T, convert func(float32) T) [][][]T {
+	values := make([][][]T, len(f.times))
+	for t := range f.times {
+		values[t] = make([][]T, len(f.lats))
+		for i := range f.lats {
+			values[t][i] = make([]T, len(f.lons))
+			for j := range f.lons {
+				v := c.value(t, i, j)
+				if !f.contains(i, j) || math.IsNaN(float64(v)) {
+					values[t][i][j] = fill
+				} else {
+					values[t][i][j] = convert(v)
+				}
+			}
+		}
+	}
+
+	return values
+}
+
+// continuousLons 跨越 180 度经线时, 东侧的经度加 360, 保证经度单调递增
+func continuousLons(lons []float64) []float64 {
+	values := make([]float64, len(lons))
+	for i, lon := range lons {
+		values[i] = lon
+		if i > 0 && values[i] < values[i-1] {
+			values[i] += 360
+		}
+	}
+
+	return values
+}
+
+// attributes 按照 key, value, key, value 的顺序生成有序的属性
+func attributes(kv ...interface{}) (api.AttributeMap, error) {
+	keys := make([]string, 0, len(kv)/2)
+	values := make(map[string]interface{}, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		key := kv[i].(string)
+		keys = append(keys, key)
+		values[key] = kv[i+1]
+	}
+
+	attrs, err := util.NewOrderedMap(keys, values)
+	if err != nil {
+		return nil, fmt.Errorf("new attributes failed: %v", err)
+	}
+
+	return attrs, nil
+}
@@ -0,0 +1,309 @@
+package nc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Format 输出格式
+type Format string
+
+const (
+	CSVFormat     Format = "csv"     // csv 与 metadata.json 一起打包, 默认为 zip
+	ParquetFormat Format = "parquet" // parquet, 元数据写入文件的 key-value metadata
+	NetCDFFormat  Format = "netcdf"  // 符合 CF 约定的 NetCDF, 元数据写入全局属性 metadata
+	GeoJSONFormat Format = "geojson" // 按行分隔的 GeoJSON Point Feature, 用于 web 地图
+)
+
+// formatWriter 输出格式的写入方式
+// archive 为 true 时写入 outputPath 后与元数据一起打包到 resultPath, 否则写入临时文件后重命名为 resultPath
+type formatWriter struct {
+	ext     string
+	archive bool
+	write   func(info *NCFile, path string, f *frame, p product, meta []byte) error
+}
+
+var formatWriters = map[Format]formatWriter{
+	CSVFormat: {
+		ext:     ".csv",
+		archive: true,
+		write: func(info *NCFile, path string, f *frame, p product, meta []byte) error {
+			return writeCSV(path, f, p.precision)
+		},
+	},
+	ParquetFormat: {
+		ext: ".parquet",
+		write: func(info *NCFile, path string, f *frame, p product, meta []byte) error {
+			return writeParquet(path, f, info.ParquetCodec, meta)
+		},
+	},
+	NetCDFFormat: {
+		ext: ".nc",
+		write: func(info *NCFile, path string, f *frame, p product, meta []byte) error {
+			return writeNetCDF(path, f, p.name, meta)
+		},
+	},
+	GeoJSONFormat: {
+		ext: ".ndjson",
+		write: func(info *NCFile, path string, f *frame, p product, meta []byte) error {
+			return writeGeoJSON(path, f, p.precision, info.GeoJSONThin)
+		},
+	},
+}
+
+// CheckFormats 检查输出格式是否支持
+func CheckFormats(formats []Format) error {
+	for _, format := range formats {
+		if _, ok := formatWriters[format]; !ok {
+			return fmt.Errorf("output format: %s not supported", format)
+		}
+	}
+
+	return nil
+}
+
+// product 输出文件的生成参数
+type product struct {
+	name      string            // 数据集名称, 写入元数据
+	precision int               // 经纬度保留的小数位数
+	open      opener            // 时间插值时用来打开下一个起报时次的文件
+	inputs    []string          // 输入文件, 写入元数据, 为空时使用 info.InputPath 和实际使用的下一个文件
+	regridded string            // 数据源已经插值到目标网格时的插值方法, 写入元数据
+	layer     string            // 瓦片的图层列, 为空时不输出瓦片
+	velocity  *velocityLayer    // u/v 列, 为空时不输出 velocity JSON
+	variables map[string]string // NetCDF 变量名对应的列名, 用于按变量名输出 GeoTIFF
+	partial   bool              // 时间插值缺少下一个起报时次的文件
+	missing   map[string]string // 合并输出缺失的数据源及原因
+}
+
+// output 单个区域单个格式对应的输出文件
+type output struct {
+	region     Region
+	format     Format
+	outputPath string // 正在写入的文件, 存在时表示上一次生成没有完成
+	resultPath string // 最终生成的文件, 存在时跳过
+}
+
+// outputs 每个区域每个格式的输出文件, 按照区域排列, 非全球区域在文件名后追加区域名称
+// 例如: ec_2025061315_north_pacific.zip, ec_2025061315_north_pacific.tar.zst, ec_2025061315_north_pacific.parquet
+func (info *NCFile) outputs() []output {
+	regions := info.Regions
+	if len(regions) == 0 {
+		regions = []Region{{}}
+	}
+
+	formats := info.Formats
+	if len(formats) == 0 {
+		formats = []Format{CSVFormat}
+	}
+
+	outputs := make([]output, 0, len(regions)*len(formats))
+	for _, region := range regions {
+		for _, format := range formats {
+			out := output{region: region, format: format}
+			if formatWriters[format].archive {
+				out.outputPath = withExt(withSuffix(info.OutputPath, region.Name), formatWriters[format].ext)
+				out.resultPath = withExt(withSuffix(info.CompressionPath, region.Name), archiveExt(info.Archive))
+			} else {
+				out.resultPath = withExt(withSuffix(info.OutputPath, region.Name), formatWriters[format].ext)
+				out.outputPath = out.resultPath + ".tmp"
+			}
+			outputs = append(outputs, out)
+		}
+	}
+
+	return outputs
+}
+
+// generate 为默认输出和每个输出方案的每个区域生成每个格式的输出文件, 已经生成的文件会被跳过
+func generate(ctx context.Context, info *NCFile, src source, p product) error {
+	inputs := p.inputs
+	if inputs == nil {
+		inputs = []string{info.InputPath}
+	}
+
+	stride := info.Stride
+	if info.Interval > 0 {
+		next, err := openNext(info, p.open)
+		if err != nil {
+			return err
+		}
+
+		// 下一个文件还没有到达时只在当前文件的时次之间插值, 记录标记后在文件到达时重新生成
+		p.partial = info.Next != nil && next == nil
+
+		var following source
+		if next != nil {
+			defer next.Close()
+			following = next
+			if p.inputs == nil {
+				inputs = append(inputs, info.Next.InputPath)
+			}
+		}
+
+		src = interpolate(src, following, info.Interval)
+		stride.Time = 1
+	}
+
+	// 所有输出方案共用解码和插值的结果, 只在需要输出时计算一次输入文件的校验值
+	// 上传失败不影响其他文件的生成和上传, 全部完成后返回上传的错误
+	g := &generation{inputs: inputs, locations: info.Locations}
+	for _, target := range info.profiles() {
+		s := target.Stride
+		if info.Interval > 0 {
+			s.Time = 1
+		}
+
+		if err := generateOutputs(ctx, target, src, p, s, g); err != nil {
+			return err
+		}
+	}
+	info.results = g.results
+
+	if info.Interval > 0 {
+		if err := info.markPartial(p.partial); err != nil {
+			return err
+		}
+	}
+
+	// 合并输出缺少数据源时记录标记, 数据源到达后重新生成
+	if info.sources != nil {
+		if err := info.markMissing(p.missing); err != nil {
+			return err
+		}
+	}
+
+	if info.Tiles != nil && p.layer != "" {
+		if err := generateTiles(info, src, stride, p); err != nil {
+			return err
+		}
+	}
+
+	if len(info.GeoTIFF) > 0 {
+		if err := generateGeoTIFF(info, src, stride, p); err != nil {
+			return err
+		}
+	}
+
+	if info.Velocity != nil && p.velocity != nil {
+		if err := generateVelocity(info, src, stride, p); err != nil {
+			return err
+		}
+	}
+
+	if err := info.markSideGenerated(); err != nil {
+		return err
+	}
+
+	return errors.Join(g.uploadErrs...)
+}
+
+// generation 一次生成中所有输出方案共用的状态
+type generation struct {
+	inputs     []string
+	locations  map[string]string
+	sources    []metadataSource // 输入文件的校验值, 为空时还没有计算
+	uploadErrs []error
+	results    []Result
+}
+
+// generateOutputs 为一个输出方案的每个区域生成每个格式的输出文件并上传, 已经生成并上传的文件会被跳过
+func generateOutputs(ctx context.Context, info *NCFile, src source, p product, stride Stride, g *generation) error {
+	var (
+		f    *frame
+		meta []byte
+	)
+	for _, out := range info.outputs() {
+		done, uploaders, local := info.generated(ctx, out.resultPath)
+		if done {
+			continue
+		}
+
+		if g.sources == nil {
+			var err error
+			if g.sources, err = checksums(g.inputs, g.locations); err != nil {
+				return err
+			}
+		}
+
+		// 同一个区域的多个格式共用一个 frame
+		if f == nil || f.region.Name != out.region.Name {
+			var err error
+			if f, meta, err = newOutputFrame(info, src, p, stride, g.sources, out.region); err != nil {
+				return err
+			}
+		}
+
+		// 本地文件存在时只需要上传, 例如: 上次上传失败
+		if !local {
+			if err := writeOutput(info, out, f, p, meta); err != nil {
+				return err
+			}
+		}
+
+		// 上传后可能删除本地文件, 先记录文件的大小和校验值
+		result, err := newResult(info, out, f)
+		if err != nil {
+			return err
+		}
+
+		if err := upload(ctx, info, out, uploaders, meta); err != nil {
+			g.uploadErrs = append(g.uploadErrs, err)
+			continue
+		}
+		g.results = append(g.results, result)
+	}
+
+	return nil
+}
+
+// newOutputFrame 生成单个区域输出的数据和元数据
+func newOutputFrame(info *NCFile, src source, p product, stride Stride, sources []metadataSource, region Region) (*frame, []byte, error) {
+	f, err := newFrame(src, stride, info.Target, region)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := f.derive(info.Derived); err != nil {
+		return nil, nil, err
+	}
+
+	if err := f.selectColumns(info.Columns); err != nil {
+		return nil, nil, err
+	}
+
+	if err := f.convert(info.Units); err != nil {
+		return nil, nil, err
+	}
+
+	if f.empty() {
+		return nil, nil, fmt.Errorf("region: %s has no grid point", region.Name)
+	}
+
+	meta, err := newMetadata(info, p, sources, stride, f).marshal()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return f, meta, nil
+}
+
+func writeOutput(info *NCFile, out output, f *frame, p product, meta []byte) error {
+	w := formatWriters[out.format]
+	if err := w.write(info, out.outputPath, f, p, meta); err != nil {
+		os.Remove(out.outputPath)
+		return err
+	}
+
+	if w.archive {
+		return archiveFile(out.outputPath, out.resultPath, meta, info.Archive, info.ArchiveLevel)
+	}
+
+	if err := os.Rename(out.outputPath, out.resultPath); err != nil {
+		return fmt.Errorf("rename output file: %s failed: %v", out.outputPath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package nc
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// ParquetCodec parquet 的压缩方式
+type ParquetCodec string
+
+const (
+	ParquetSnappy ParquetCodec = "snappy"
+	ParquetZstd   ParquetCodec = "zstd"
+	ParquetGzip   ParquetCodec = "gzip"
+	ParquetNone   ParquetCodec = "none"
+)
+
+var parquetCodecs = map[ParquetCodec]parquet.CompressionCodec{
+	"":            parquet.CompressionCodec_SNAPPY,
+	ParquetSnappy: parquet.CompressionCodec_SNAPPY,
+	ParquetZstd:   parquet.CompressionCodec_ZSTD,
+	ParquetGzip:   parquet.CompressionCodec_GZIP,
+	ParquetNone:   parquet.CompressionCodec_UNCOMPRESSED,
+}
+
+// CheckParquetCodec 检查 parquet 的压缩方式是否支持
+func CheckParquetCodec(codec ParquetCodec) error {
+	if _, ok := parquetCodecs[codec]; !ok {
+		return fmt.Errorf("parquet codec: %s not supported", codec)
+	}
+
+	return nil
+}
+
+// writeParquet 写入 parquet 文件, 每个时次一个 row group, 缺测值写为 null
+// 列: lat(double), lon(double), dateTime(timestamp, 毫秒, UTC), 数据列(float, 分类值为 int32)
+func writeParquet(path string, f *frame, codec ParquetCodec, meta []byte) error {
+	compression, ok := parquetCodecs[codec]
+	if !ok {
+		return fmt.Errorf("parquet codec: %s not supported", codec)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.FileMode(0664))
+	if err != nil {
+		return fmt.Errorf("output file: %s create failed: %v", path, err)
+	}
+	defer file.Close()
+
+	schema := []string{
+		"name=lat, type=DOUBLE",
+		"name=lon, type=DOUBLE",
+		"name=dateTime, type=INT64, convertedtype=TIMESTAMP_MILLIS",
+	}
+	for _, c := range f.columns {
+		if c.integer {
+			schema = append(schema, fmt.Sprintf("name=%s, type=INT32, repetitiontype=OPTIONAL", c.name))
+		} else {
+			schema = append(schema, fmt.Sprintf("name=%s, type=FLOAT, repetitiontype=OPTIONAL", c.name))
+		}
+	}
+
+	buf := bufio.NewWriter(file)
+	pw, err := writer.NewCSVWriterFromWriter(schema, buf, 4)
+	if err != nil {
+		return fmt.Errorf("create parquet writer failed: %v", err)
+	}
+	pw.CompressionType = compression
+
+	if meta != nil {
+		value := string(meta)
+		pw.Footer.KeyValueMetadata = append(pw.Footer.KeyValueMetadata, &parquet.KeyValue{Key: MetadataName, Value: &value})
+	}
+
+	for timeIndex, dateTime := range f.times {
+		millis := dateTime.UnixMilli()
+		for latIndex, lat := range f.lats {
+			for lonIndex, lon := range f.lons {
+				if !f.contains(latIndex, lonIndex) {
+					continue
+				}
+
+				row := make([]interface{}, 0, len(schema))
+				row = append(row, lat, lon, millis)
+				for _, c := range f.columns {
+					value := c.value(timeIndex, latIndex, lonIndex)
+					switch {
+					case math.IsNaN(float64(value)):
+						row = append(row, nil)
+					case c.integer:
+						row = append(row, int32(value))
+					default:
+						row = append(row, value)
+					}
+				}
+
+				if err := pw.Write(row); err != nil {
+					return fmt.Errorf("write parquet row failed: %v", err)
+				}
+			}
+		}
+
+		// 每个时次一个 row group
+		if err := pw.Flush(true); err != nil {
+			return fmt.Errorf("flush parquet row group failed: %v", err)
+		}
+	}
+
+	if err := pw.WriteStop(); err != nil {
+		return fmt.Errorf("write parquet footer failed: %v", err)
+	}
+
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("write output file: %s failed: %v", path, err)
+	}
+
+	return nil
+}
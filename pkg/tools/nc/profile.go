@@ -0,0 +1,46 @@
+package nc
+
+// Profile 输出方案, 同一个输入文件解码一次, 按照多个方案输出到不同的目录
+// 只替换输出相关的配置, 插值, 时间插值和派生列与 NCFile 相同, 瓦片, GeoTIFF 和 velocity JSON 只按 NCFile 输出
+type Profile struct {
+	Name            string
+	OutputPath      string
+	CompressionPath string
+	Stride          Stride
+	Regions         []Region
+	Columns         []string
+	Units           UnitProfile
+	Formats         []Format
+	Archive         ArchiveCodec
+	ArchiveLevel    int
+	Uploaders       []Uploader
+}
+
+// profiles 默认输出和每个输出方案对应的 NCFile, 第一个为默认输出
+func (info *NCFile) profiles() []*NCFile {
+	infos := make([]*NCFile, 0, len(info.Profiles)+1)
+	infos = append(infos, info)
+
+	for _, p := range info.Profiles {
+		c := *info
+		c.profile = p.Name
+		c.OutputPath = p.OutputPath
+		c.CompressionPath = p.CompressionPath
+		c.Stride = p.Stride
+		c.Regions = p.Regions
+		c.Columns = p.Columns
+		c.Units = p.Units
+		c.Formats = p.Formats
+		c.Archive = p.Archive
+		c.ArchiveLevel = p.ArchiveLevel
+		c.Uploaders = p.Uploaders
+		c.Tiles = nil
+		c.GeoTIFF = nil
+		c.Velocity = nil
+		c.Profiles = nil
+		c.results = nil
+		infos = append(infos, &c)
+	}
+
+	return infos
+}
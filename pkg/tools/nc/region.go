@@ -0,0 +1,153 @@
+package nc
+
+import (
+	"fmt"
+	"gen-meteo-file/pkg/tools/geo"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// GlobalRegionName 全球区域, 输出文件不带区域后缀
+const GlobalRegionName = "global"
+
+// Region 经纬度矩形区域, West > East 时表示跨越 180 度经线
+// Mask 不为空时只输出多边形内的格点, 矩形为多边形的外包矩形
+type Region struct {
+	Name  string
+	West  float64
+	South float64
+	East  float64
+	North float64
+	Mask  *geo.Index
+}
+
+// 预置的区域
+var presetRegions = map[string]Region{
+	"north_pacific":   {Name: "north_pacific", West: 120, South: 0, East: -100, North: 65},
+	"south_pacific":   {Name: "south_pacific", West: 150, South: -60, East: -70, North: 0},
+	"north_atlantic":  {Name: "north_atlantic", West: -100, South: 0, East: 0, North: 70},
+	"south_atlantic":  {Name: "south_atlantic", West: -70, South: -60, East: 20, North: 0},
+	"indian_ocean":    {Name: "indian_ocean", West: 20, South: -60, East: 120, North: 30},
+	"china_seas":      {Name: "china_seas", West: 99, South: 0, East: 132, North: 42},
+	"south_china_sea": {Name: "south_china_sea", West: 99, South: 0, East: 122, North: 25},
+	"mediterranean":   {Name: "mediterranean", West: -6, South: 30, East: 37, North: 46},
+}
+
+// ParseRegion 解析自定义区域, 格式: name:west,south,east,north 或者 name:/path/to/file.geojson
+// 例如: bohai:117,37,123,41
+func ParseRegion(spec string) (Region, error) {
+	name, bbox, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok || name == "" {
+		return Region{}, fmt.Errorf("region: %s format must be name:west,south,east,north", spec)
+	}
+
+	if ext := strings.ToLower(filepath.Ext(bbox)); ext == ".geojson" || ext == ".json" {
+		return LoadPolygonRegion(name, bbox)
+	}
+
+	values := strings.Split(bbox, ",")
+	if len(values) != 4 {
+		return Region{}, fmt.Errorf("region: %s bbox must have 4 values", spec)
+	}
+
+	var edges [4]float64
+	for i, value := range values {
+		edge, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return Region{}, fmt.Errorf("region: %s parse bbox value: %s failed: %v", spec, value, err)
+		}
+		edges[i] = edge
+	}
+
+	region := Region{Name: name, West: edges[0], South: edges[1], East: edges[2], North: edges[3]}
+	if region.South > region.North {
+		return Region{}, fmt.Errorf("region: %s south must not be greater than north", spec)
+	}
+	if region.West < -180 || region.West > 180 || region.East < -180 || region.East > 180 {
+		return Region{}, fmt.Errorf("region: %s longitude must be in -180~180", spec)
+	}
+
+	return region, nil
+}
+
+// LoadPolygonRegion 从 GeoJSON 文件加载多边形区域
+func LoadPolygonRegion(name, path string) (Region, error) {
+	polygons, err := geo.LoadGeoJSON(path)
+	if err != nil {
+		return Region{}, fmt.Errorf("region: %s load polygon failed: %v", name, err)
+	}
+
+	mask := geo.NewIndex(polygons)
+	bbox := mask.BBox()
+	return Region{Name: name, West: bbox.West, South: bbox.South, East: bbox.East, North: bbox.North, Mask: mask}, nil
+}
+
+// LookupRegions 按名称查找区域, 自定义区域优先于预置区域
+func LookupRegions(names []string, custom []Region) ([]Region, error) {
+	regions := make([]Region, 0, len(names))
+	for _, name := range names {
+		region, err := lookupRegion(name, custom)
+		if err != nil {
+			return nil, err
+		}
+		regions = append(regions, region)
+	}
+
+	return regions, nil
+}
+
+func lookupRegion(name string, custom []Region) (Region, error) {
+	if name == GlobalRegionName {
+		return Region{}, nil
+	}
+
+	for _, region := range custom {
+		if region.Name == name {
+			return region, nil
+		}
+	}
+
+	if region, ok := presetRegions[name]; ok {
+		return region, nil
+	}
+
+	return Region{}, fmt.Errorf("region: %s not found", name)
+}
+
+func (r Region) global() bool {
+	return r.Name == ""
+}
+
+func (r Region) crossAntimeridian() bool {
+	return r.West > r.East
+}
+
+// contains 格点是否需要输出
+func (r Region) contains(lat, lon float64) bool {
+	if r.Mask == nil {
+		return true
+	}
+
+	return r.Mask.Contains(lon, lat)
+}
+
+func (r Region) containsLat(lat float64) bool {
+	if r.global() {
+		return true
+	}
+
+	return lat >= r.South && lat <= r.North
+}
+
+func (r Region) containsLon(lon float64) bool {
+	if r.global() {
+		return true
+	}
+
+	if r.crossAntimeridian() {
+		return lon >= r.West || lon <= r.East
+	}
+
+	return lon >= r.West && lon <= r.East
+}
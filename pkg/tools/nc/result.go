@@ -0,0 +1,55 @@
+package nc
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Result 一次生成中完成(生成或者补传)的输出文件, 用于通知下游
+type Result struct {
+	Path       string   // 本地路径, 上传后可能被删除
+	Locations  []string // 上传或者投递后远端的位置
+	Profile    string   // 输出方案名称, 默认输出为空
+	Format     Format
+	Region     string // 区域名称, 全球为空
+	Size       int64
+	SHA256     string
+	ValidTimes []time.Time
+}
+
+// Results 最近一次生成完成的输出文件, 不包括已经生成过被跳过的文件
+func (info *NCFile) Results() []Result {
+	return info.results
+}
+
+func newResult(info *NCFile, out output, f *frame) (Result, error) {
+	stat, err := os.Stat(out.resultPath)
+	if err != nil {
+		return Result{}, fmt.Errorf("get result file: %s info failed: %v", out.resultPath, err)
+	}
+
+	// 只在需要通知时计算校验值
+	var sum string
+	if info.Checksum {
+		if sum, err = checksum(out.resultPath); err != nil {
+			return Result{}, err
+		}
+	}
+
+	locations := make([]string, 0, len(info.Uploaders))
+	for _, u := range info.Uploaders {
+		locations = append(locations, u.Location(out.resultPath))
+	}
+
+	return Result{
+		Path:       out.resultPath,
+		Locations:  locations,
+		Profile:    info.profile,
+		Format:     out.format,
+		Region:     out.region.Name,
+		Size:       stat.Size(),
+		SHA256:     sum,
+		ValidTimes: f.times,
+	}, nil
+}
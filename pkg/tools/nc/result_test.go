@@ -0,0 +1,42 @@
+package nc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestNewResult(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ec_2025061300.zip")
+	data := []byte("data")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+
+	out := output{format: CSVFormat, resultPath: path}
+	uploaders := []Uploader{&fakeUploader{}}
+
+	// 没有通知目标时不计算校验值
+	r, err := newResult(&NCFile{Uploaders: uploaders}, out, &frame{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SHA256 != "" || r.Size != int64(len(data)) {
+		t.Errorf("newResult() sha256 = %q, size = %d, want empty, %d", r.SHA256, r.Size, len(data))
+	}
+
+	r, err = newResult(&NCFile{Uploaders: uploaders, Checksum: true}, out, &frame{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := hex.EncodeToString(sum[:]); r.SHA256 != want {
+		t.Errorf("newResult() sha256 = %s, want %s", r.SHA256, want)
+	}
+	if want := []string{"fake://ec_2025061300.zip"}; !slices.Equal(r.Locations, want) {
+		t.Errorf("newResult() locations = %v, want %v", r.Locations, want)
+	}
+}
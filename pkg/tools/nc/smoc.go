@@ -1,10 +1,8 @@
 package nc
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/batchatco/go-native-netcdf/netcdf"
@@ -25,6 +23,14 @@ const (
 	SMOCStep               = float32(1. / 12.)
 )
 
+// smocVariables NetCDF 变量名对应的列名
+var smocVariables = map[string]string{
+	SMOCUCurrentField:     "uCurrent",
+	SMOCVCurrentField:     "vCurrent",
+	SMOCUTideCurrentField: "uTideCurrent",
+	SMOCVTideCurrentField: "vTideCurrent",
+}
+
 type SMOC struct {
 	info                  *NCFile
 	group                 api.Group
@@ -40,23 +46,16 @@ type SMOC struct {
 	vTideCurrentFillValue float32
 }
 
-func NewSMOC(info *NCFile) (*SMOC, error) {
-	if _, err := os.Stat(info.InputPath); err != nil {
-		return nil, fmt.Errorf("smoc input file: %s not exists", info.InputPath)
-	}
-
-	if _, err := os.Stat(info.OutputPath); err == nil {
-		return nil, fmt.Errorf("smoc output file: %s already exists", info.OutputPath)
-	}
-
-	if _, err := os.Stat(info.CompressionPath); err == nil {
-		return nil, fmt.Errorf("smoc compression file: %s already exists", info.CompressionPath)
+func NewSMOC(ctx context.Context, info *NCFile) (*SMOC, error) {
+	if err := info.check(ctx, "smoc"); err != nil {
+		return nil, err
 	}
 
-	if err := os.MkdirAll(filepath.Dir(info.OutputPath), os.FileMode(0755)); err != nil {
-		return nil, fmt.Errorf("create smoc output dir: %s failed: %v", filepath.Dir(info.OutputPath), err)
-	}
+	return openSMOC(info)
+}
 
+// openSMOC 只打开输入文件, 不检查输出文件
+func openSMOC(info *NCFile) (*SMOC, error) {
 	group, err := netcdf.Open(info.InputPath)
 	if err != nil {
 		return nil, fmt.Errorf("open smoc netcdf file: %s failed: %v", info.InputPath, err)
@@ -139,60 +138,42 @@ func (nc *SMOC) Analysis() error {
 	return nil
 }
 
-func (nc *SMOC) GenerateCSV() error {
-	file, err := os.OpenFile(nc.info.OutputPath, os.O_CREATE|os.O_RDWR, os.FileMode(0664))
-	if err != nil {
-		return fmt.Errorf("output file: %s create failed: %v", nc.info.OutputPath, err)
-	}
-	defer file.Close()
-
-	buf := bufio.NewWriter(file)
-	buf.WriteString("lat,lon,dateTime,uCurrent,vCurrent,uTideCurrent,vTideCurrent\n")
-
-	for timeIndex := 0; timeIndex < SMOCTimeCount; timeIndex += 3 {
-		for latIndex := 0; latIndex < SMOCLatitudeCount; latIndex += 3 {
-			for lonIndex := 0; lonIndex < SMOCLongitudeCount; lonIndex += 3 {
-				buf.WriteString(fmt.Sprintf("%.3f,%.3f,%s",
-					nc.latitudeList[latIndex],
-					nc.longitudeList[lonIndex],
-					nc.info.DateTime.Add(time.Hour*time.Duration(timeIndex)).Format(time.DateTime),
-				))
-
-				uCurrent := nc.uCurrentList[timeIndex][0][latIndex][lonIndex]
-				if uCurrent == nc.uCurrentFillValue {
-					buf.WriteString(",NaN")
-				} else {
-					buf.WriteString(fmt.Sprintf(",%f", uCurrent))
-				}
-
-				vCurrent := nc.vCurrentList[timeIndex][0][latIndex][lonIndex]
-				if vCurrent == nc.vCurrentFillValue {
-					buf.WriteString(",NaN")
-				} else {
-					buf.WriteString(fmt.Sprintf(",%f", vCurrent))
-				}
-
-				uTideCurrent := nc.uTideCurrentList[timeIndex][0][latIndex][lonIndex]
-				if uTideCurrent == nc.uTideCurrentFillValue {
-					buf.WriteString(",NaN")
-				} else {
-					buf.WriteString(fmt.Sprintf(",%f", uTideCurrent))
-				}
-
-				vTideCurrent := nc.vTideCurrentList[timeIndex][0][latIndex][lonIndex]
-				if vTideCurrent == nc.vTideCurrentFillValue {
-					buf.WriteString(",NaN\n")
-				} else {
-					buf.WriteString(fmt.Sprintf(",%f\n", vTideCurrent))
-				}
-			}
-		}
-
-		buf.Flush()
+func (nc *SMOC) GenerateCSV(ctx context.Context) error {
+	return generate(ctx, nc.info, nc, product{
+		name:      "smoc",
+		precision: 3,
+		open:      func(info *NCFile) (dataset, error) { return openSMOC(info) },
+		layer:     "currentSpeed",
+		velocity:  &velocityLayer{u: "uCurrent", v: "vCurrent", category: 1},
+		variables: smocVariables,
+	})
+}
+
+func (nc *SMOC) latitudes() []float64 {
+	return toFloat64(nc.latitudeList)
+}
+
+func (nc *SMOC) longitudes() []float64 {
+	return toFloat64(nc.longitudeList)
+}
+
+// 每小时一个时次
+func (nc *SMOC) times() []time.Time {
+	times := make([]time.Time, 0, SMOCTimeCount)
+	for timeIndex := range SMOCTimeCount {
+		times = append(times, nc.info.DateTime.Add(time.Hour*time.Duration(timeIndex)))
 	}
 
-	buf.Flush()
-	return zipFile(nc.info.OutputPath, nc.info.CompressionPath)
+	return times
+}
+
+func (nc *SMOC) columns() []column {
+	return []column{
+		{name: "uCurrent", kind: vectorKind, pair: "vCurrent", value: float32Value(nc.uCurrentList, nc.uCurrentFillValue)},
+		{name: "vCurrent", kind: vectorKind, pair: "uCurrent", value: float32Value(nc.vCurrentList, nc.vCurrentFillValue)},
+		{name: "uTideCurrent", kind: vectorKind, pair: "vTideCurrent", value: float32Value(nc.uTideCurrentList, nc.uTideCurrentFillValue)},
+		{name: "vTideCurrent", kind: vectorKind, pair: "uTideCurrent", value: float32Value(nc.vTideCurrentList, nc.vTideCurrentFillValue)},
+	}
 }
 
 func (nc *SMOC) Close() {
@@ -0,0 +1,149 @@
+package nc
+
+import (
+	"encoding/json"
+	"fmt"
+	"gen-meteo-file/pkg/tools/regrid"
+	"gen-meteo-file/pkg/tools/tile"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Tiles Web Mercator XYZ 瓦片的输出配置
+// 瓦片路径: {Dir}/{validtime}/{z}/{x}/{y}.png, validtime 格式为 2006010215
+type Tiles struct {
+	Dir      string
+	MinZoom  int
+	MaxZoom  int
+	Ramp     tile.Ramp
+	Encoding tile.Encoding
+}
+
+// tileInfo 每个时次的瓦片说明, 写入 {validtime}/tile.json, 用于客户端解码
+type tileInfo struct {
+	Dataset   string        `json:"dataset"`
+	Layer     string        `json:"layer"`
+	Unit      string        `json:"unit"`
+	ValidTime string        `json:"validTime"`
+	Encoding  tile.Encoding `json:"encoding"`
+	Min       float64       `json:"min"`
+	Max       float64       `json:"max"`
+	Ramp      string        `json:"ramp"`
+	MinZoom   int           `json:"minZoom"`
+	MaxZoom   int           `json:"maxZoom"`
+	Size      int           `json:"size"`
+}
+
+// tileRegridder 单个瓦片的插值器
+type tileRegridder struct {
+	z, x, y   int
+	regridder *regrid.Regridder
+}
+
+// generateTiles 将图层列渲染为每个时次的瓦片, 已经生成的时次会被跳过
+// 先写入 {validtime}.tmp 目录, 全部完成后重命名, 保证客户端不会读到不完整的时次
+func generateTiles(info *NCFile, src source, stride Stride, p product) error {
+	opts := info.Tiles
+	if opts.MinZoom < 0 || opts.MaxZoom < opts.MinZoom {
+		return fmt.Errorf("tile zoom: %d ~ %d is invalid", opts.MinZoom, opts.MaxZoom)
+	}
+
+	// 瓦片使用原始分辨率的全球数据, 图层列不存在时按照派生列计算
+	f, err := newFrame(src, Stride{Time: stride.Time}, info.Target, Region{})
+	if err != nil {
+		return err
+	}
+
+	layer, ok := f.column(p.layer)
+	if !ok {
+		if err := f.derive([]string{p.layer}); err != nil {
+			return err
+		}
+		layer, _ = f.column(p.layer)
+	}
+
+	var regridders []tileRegridder
+	for t, validTime := range f.times {
+		dir := filepath.Join(opts.Dir, validTime.UTC().Format("2006010215"))
+		if _, err := os.Stat(dir); err == nil {
+			continue
+		}
+
+		// 只在需要输出时计算一次所有瓦片的插值权重
+		if regridders == nil {
+			if regridders, err = newTileRegridders(f, opts.MinZoom, opts.MaxZoom); err != nil {
+				return err
+			}
+		}
+
+		tmp := dir + ".tmp"
+		if err := os.RemoveAll(tmp); err != nil {
+			return fmt.Errorf("remove tile dir: %s failed: %v", tmp, err)
+		}
+
+		for _, r := range regridders {
+			values := r.regridder.Regrid(func(lat, lon int) float32 { return layer.value(t, lat, lon) })
+
+			path := filepath.Join(tmp, strconv.Itoa(r.z), strconv.Itoa(r.x), strconv.Itoa(r.y)+".png")
+			if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+				return fmt.Errorf("create tile dir: %s failed: %v", filepath.Dir(path), err)
+			}
+
+			if err := tile.WritePNG(path, tile.Render(values, opts.Ramp, opts.Encoding)); err != nil {
+				return err
+			}
+		}
+
+		data, err := json.MarshalIndent(tileInfo{
+			Dataset:   p.name,
+			Layer:     p.layer,
+			Unit:      columnAttributes[p.layer].unit,
+			ValidTime: validTime.UTC().Format("2006-01-02T15:04:05Z"),
+			Encoding:  opts.Encoding,
+			Min:       opts.Ramp.Min(),
+			Max:       opts.Ramp.Max(),
+			Ramp:      opts.Ramp.String(),
+			MinZoom:   opts.MinZoom,
+			MaxZoom:   opts.MaxZoom,
+			Size:      tile.Size,
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal tile info failed: %v", err)
+		}
+
+		if err := os.WriteFile(filepath.Join(tmp, "tile.json"), data, os.FileMode(0664)); err != nil {
+			return fmt.Errorf("write tile info failed: %v", err)
+		}
+
+		if err := os.Rename(tmp, dir); err != nil {
+			return fmt.Errorf("rename tile dir: %s failed: %v", tmp, err)
+		}
+	}
+
+	return nil
+}
+
+func newTileRegridders(f *frame, minZoom, maxZoom int) ([]tileRegridder, error) {
+	var regridders []tileRegridder
+	for z := minZoom; z <= maxZoom; z++ {
+		n := 1 << z
+		for x := 0; x < n; x++ {
+			lons := tile.Lons(z, x, tile.Size)
+			for y := 0; y < n; y++ {
+				r, err := regrid.New(
+					regrid.Grid{Lats: f.lats, Lons: f.lons},
+					regrid.Grid{Lats: tile.Lats(z, y, tile.Size), Lons: lons},
+					regrid.Bilinear,
+				)
+				if err != nil {
+					return nil, fmt.Errorf("new tile regridder failed: %v", err)
+				}
+
+				regridders = append(regridders, tileRegridder{z: z, x: x, y: y, regridder: r})
+			}
+		}
+	}
+
+	return regridders, nil
+}
@@ -0,0 +1,84 @@
+package nc
+
+import (
+	"fmt"
+	"gen-meteo-file/pkg/tools/derive"
+	"math"
+)
+
+// UnitProfile 输出列的单位方案, 为空时输出数据源的原始单位, 表头不带单位
+type UnitProfile string
+
+const (
+	SIUnits       UnitProfile = "si"       // 国际单位: K, Pa, m/s, m
+	MarineUnits   UnitProfile = "marine"   // 航海: °C, hPa, 节, m
+	ImperialUnits UnitProfile = "imperial" // 英制: °F, inHg, 节, ft
+)
+
+// 原始单位(国际单位)到各个方案的单位
+var unitProfiles = map[UnitProfile]map[string]string{
+	SIUnits:       {},
+	MarineUnits:   {"K": "degC", "Pa": "hPa", "m/s": "kn"},
+	ImperialUnits: {"K": "degF", "Pa": "inHg", "m/s": "kn", "m": "ft"},
+}
+
+// 单位换算, 键为 原始单位>目标单位
+var unitConversions = map[string]func(value float32) float32{
+	"K>degC":  func(v float32) float32 { return v - 273.15 },
+	"K>degF":  func(v float32) float32 { return (v-273.15)*9/5 + 32 },
+	"Pa>hPa":  func(v float32) float32 { return v / 100 },
+	"Pa>inHg": func(v float32) float32 { return v / 3386.389 },
+	"m/s>kn":  func(v float32) float32 { return v * derive.MSToKnots },
+	"m>ft":    func(v float32) float32 { return v / 0.3048 },
+}
+
+// CheckUnits 检查单位方案是否支持
+func CheckUnits(profile UnitProfile) error {
+	if profile == "" {
+		return nil
+	}
+
+	if _, ok := unitProfiles[profile]; !ok {
+		return fmt.Errorf("unit profile: %s not supported", profile)
+	}
+
+	return nil
+}
+
+// convert 按照单位方案换算所有的列, 并记录列的单位, 缺测值保持为 NaN
+func (f *frame) convert(profile UnitProfile) error {
+	if profile == "" {
+		return nil
+	}
+
+	units, ok := unitProfiles[profile]
+	if !ok {
+		return fmt.Errorf("unit profile: %s not supported", profile)
+	}
+
+	for i, c := range f.columns {
+		from := columnAttributes[c.name].unit
+		if from == "" {
+			continue
+		}
+
+		to, ok := units[from]
+		if !ok {
+			f.columns[i].unit = from
+			continue
+		}
+
+		value, conversion := c.value, unitConversions[from+">"+to]
+		f.columns[i].unit = to
+		f.columns[i].value = func(t, lat, lon int) float32 {
+			v := value(t, lat, lon)
+			if math.IsNaN(float64(v)) {
+				return nan
+			}
+
+			return conversion(v)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package nc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Uploader 将生成的文件上传到远端, 例如: S3 兼容的对象存储
+type Uploader interface {
+	// Upload 上传本地文件, metadata 不为空时同时上传元数据
+	Upload(ctx context.Context, path string, metadata []byte) error
+	// Uploaded 远端是否已经有这个文件, 本地文件上传后被删除时用于跳过已经生成的文件
+	Uploaded(ctx context.Context, path string) bool
+	// Location 上传后远端的位置, 例如: s3://bucket/key, 用于通知下游
+	Location(path string) string
+}
+
+// pendingUploaders 还没有上传 path 的 Uploader
+func (info *NCFile) pendingUploaders(ctx context.Context, path string) []Uploader {
+	var pending []Uploader
+	for _, u := range info.Uploaders {
+		if !u.Uploaded(ctx, path) {
+			pending = append(pending, u)
+		}
+	}
+
+	return pending
+}
+
+// generated 输出文件是否已经完成: 本地文件存在或者已经上传, 并且所有 Uploader 都已经上传
+// 返回还没有上传的 Uploader 和本地文件是否存在, 本地文件存在时只需要上传
+func (info *NCFile) generated(ctx context.Context, path string) (bool, []Uploader, bool) {
+	// 需要重新生成时当作本地文件不存在, 重新生成后上传到所有的 Uploader
+	if info.regenerate {
+		return false, info.Uploaders, false
+	}
+
+	_, err := os.Stat(path)
+	local := err == nil
+
+	pending := info.pendingUploaders(ctx, path)
+	if len(pending) > 0 {
+		return false, pending, local
+	}
+
+	return local || len(info.Uploaders) > 0, nil, local
+}
+
+// upload 上传输出文件, 打包格式同时上传元数据, 全部上传成功后按照配置删除本地文件
+// 一个 Uploader 失败时继续上传其他的 Uploader, 返回所有的错误
+func upload(ctx context.Context, info *NCFile, out output, uploaders []Uploader, meta []byte) error {
+	if len(uploaders) == 0 {
+		return nil
+	}
+
+	if !formatWriters[out.format].archive {
+		meta = nil
+	}
+
+	var errs []error
+	for _, u := range uploaders {
+		if err := u.Upload(ctx, out.resultPath, meta); err != nil {
+			errs = append(errs, fmt.Errorf("upload file: %s failed: %v", out.resultPath, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if info.DeleteUploaded {
+		if err := os.Remove(out.resultPath); err != nil {
+			return fmt.Errorf("remove uploaded file: %s failed: %v", out.resultPath, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package nc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeUploader 记录上传的文件和元数据, err 不为空时上传失败
+type fakeUploader struct {
+	err      error
+	uploaded map[string][]byte
+}
+
+func (u *fakeUploader) Upload(ctx context.Context, path string, metadata []byte) error {
+	if u.err != nil {
+		return u.err
+	}
+
+	if u.uploaded == nil {
+		u.uploaded = make(map[string][]byte)
+	}
+	u.uploaded[path] = metadata
+	return nil
+}
+
+func (u *fakeUploader) Uploaded(ctx context.Context, path string) bool {
+	_, ok := u.uploaded[path]
+	return ok
+}
+
+func (u *fakeUploader) Location(path string) string {
+	return "fake://" + filepath.Base(path)
+}
+
+func TestUploadDeleteUploaded(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        Format
+		deleteLocal   bool
+		failed        bool
+		wantLocal     bool
+		wantMetadata  bool
+		wantUploadErr bool
+	}{
+		{name: "keep local", format: CSVFormat, wantLocal: true, wantMetadata: true},
+		{name: "delete local", format: CSVFormat, deleteLocal: true, wantMetadata: true},
+		{name: "no metadata for parquet", format: ParquetFormat, deleteLocal: true},
+		{name: "keep local when upload failed", format: CSVFormat, deleteLocal: true, failed: true, wantLocal: true, wantMetadata: true, wantUploadErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ec_2025061300.zip")
+			if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			ok := &fakeUploader{}
+			uploaders := []Uploader{ok}
+			if tt.failed {
+				uploaders = append(uploaders, &fakeUploader{err: fmt.Errorf("connection refused")})
+			}
+
+			info := &NCFile{Uploaders: uploaders, DeleteUploaded: tt.deleteLocal}
+			out := output{format: tt.format, resultPath: path}
+
+			err := upload(context.Background(), info, out, uploaders, []byte(`{}`))
+			if (err != nil) != tt.wantUploadErr {
+				t.Fatalf("upload() error = %v, want error %v", err, tt.wantUploadErr)
+			}
+
+			// 一个 Uploader 失败时其他 Uploader 照常上传
+			metadata, uploaded := ok.uploaded[path]
+			if !uploaded {
+				t.Fatalf("file: %s not uploaded", path)
+			}
+			if (metadata != nil) != tt.wantMetadata {
+				t.Errorf("metadata = %q, want metadata %v", metadata, tt.wantMetadata)
+			}
+
+			_, statErr := os.Stat(path)
+			if local := statErr == nil; local != tt.wantLocal {
+				t.Errorf("local file exists = %v, want %v", local, tt.wantLocal)
+			}
+		})
+	}
+}
+
+func TestGenerated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ec_2025061300.zip")
+	ctx := context.Background()
+
+	uploader := &fakeUploader{}
+	info := &NCFile{Uploaders: []Uploader{uploader}}
+
+	// 没有本地文件也没有上传
+	if done, pending, local := info.generated(ctx, path); done || len(pending) != 1 || local {
+		t.Errorf("generated() = %v, %d, %v, want false, 1, false", done, len(pending), local)
+	}
+
+	// 上传后删除了本地文件, 不需要重新生成
+	uploader.uploaded = map[string][]byte{path: nil}
+	if done, pending, local := info.generated(ctx, path); !done || len(pending) != 0 || local {
+		t.Errorf("generated() = %v, %d, %v, want true, 0, false", done, len(pending), local)
+	}
+}
@@ -0,0 +1,153 @@
+package nc
+
+import (
+	"bufio"
+	"fmt"
+	"gen-meteo-file/pkg/tools/regrid"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Velocity leaflet-velocity / earth 使用的 grib2json 格式的 u/v 输出配置
+// 每个时次一个文件: {Dir}/{validtime}.json, 非全球区域在文件名后追加区域名称
+type Velocity struct {
+	Dir        string
+	Resolution float64 // 输出的分辨率(度), 0 表示使用原始网格
+	Region     Region
+}
+
+// velocityLayer u/v 列名和 GRIB2 的参数类别, 风: 2(动量), 流: 1(海流)
+type velocityLayer struct {
+	u, v     string
+	category int
+}
+
+// generateVelocity 为每个时次生成 u/v 两条记录的 JSON, 已经生成的时次会被跳过
+func generateVelocity(info *NCFile, src source, stride Stride, p product) error {
+	opts := info.Velocity
+
+	target := info.Target
+	if opts.Resolution > 0 {
+		grid, err := velocityGrid(opts.Resolution, opts.Region)
+		if err != nil {
+			return err
+		}
+		target = &regrid.Target{Grid: grid, Method: regrid.Bilinear}
+	}
+
+	f, err := newFrame(src, stride, target, opts.Region)
+	if err != nil {
+		return err
+	}
+
+	u, ok := f.column(p.velocity.u)
+	if !ok {
+		return fmt.Errorf("velocity column: %s not found", p.velocity.u)
+	}
+	v, ok := f.column(p.velocity.v)
+	if !ok {
+		return fmt.Errorf("velocity column: %s not found", p.velocity.v)
+	}
+
+	if f.empty() {
+		return fmt.Errorf("velocity region: %s has no grid point", opts.Region.Name)
+	}
+
+	for t, validTime := range f.times {
+		path := filepath.Join(opts.Dir, withSuffix(validTime.UTC().Format("2006010215")+".json", opts.Region.Name))
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+
+		if err := os.MkdirAll(opts.Dir, os.FileMode(0755)); err != nil {
+			return fmt.Errorf("create velocity dir: %s failed: %v", opts.Dir, err)
+		}
+
+		if err := writeVelocity(path+".tmp", f, t, info.DateTime, p.velocity.category, u, v); err != nil {
+			os.Remove(path + ".tmp")
+			return err
+		}
+
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return fmt.Errorf("rename velocity file: %s failed: %v", path, err)
+		}
+	}
+
+	return nil
+}
+
+// velocityGrid 按照分辨率生成区域的网格, 纬度从北到南, 跨越 180 度经线时东侧经度加 360 (由 NewGrid 处理)
+func velocityGrid(resolution float64, region Region) (regrid.Grid, error) {
+	if region.global() {
+		return regrid.NewGrid(resolution, -180, -90, 180-resolution, 90, regrid.Descending)
+	}
+
+	return regrid.NewGrid(resolution, region.West, region.South, region.East, region.North, regrid.Descending)
+}
+
+// writeVelocity 写入单个时次的 JSON, 数据从北到南, 从西到东排列, 缺测值和区域外的格点写为 null
+func writeVelocity(path string, f *frame, t int, reference time.Time, category int, u, v column) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.FileMode(0664))
+	if err != nil {
+		return fmt.Errorf("output file: %s create failed: %v", path, err)
+	}
+	defer file.Close()
+
+	// 纬度升序时倒序输出
+	rows := make([]int, len(f.lats))
+	for i := range rows {
+		rows[i] = i
+		if len(f.lats) > 1 && f.lats[0] < f.lats[len(f.lats)-1] {
+			rows[i] = len(f.lats) - 1 - i
+		}
+	}
+	lons := continuousLons(f.lons)
+
+	var dx, dy float64
+	if len(lons) > 1 {
+		dx = math.Abs(lons[1] - lons[0])
+	}
+	if len(f.lats) > 1 {
+		dy = math.Abs(f.lats[1] - f.lats[0])
+	}
+
+	buf := bufio.NewWriter(file)
+	buf.WriteString("[")
+	for i, c := range []column{u, v} {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+
+		buf.WriteString(fmt.Sprintf(
+			`{"header":{"parameterCategory":%d,"parameterNumber":%d,"parameterUnit":"m.s-1","nx":%d,"ny":%d,"lo1":%v,"la1":%v,"lo2":%v,"la2":%v,"dx":%v,"dy":%v,"refTime":"%s","forecastTime":%v,"numberPoints":%d},"data":[`,
+			category, 2+i, len(lons), len(rows), lons[0], f.lats[rows[0]], lons[len(lons)-1], f.lats[rows[len(rows)-1]], dx, dy,
+			reference.UTC().Format("2006-01-02T15:04:05.000Z"), f.times[t].Sub(reference).Hours(), len(lons)*len(rows),
+		))
+
+		for k, row := range rows {
+			for j := range lons {
+				if k > 0 || j > 0 {
+					buf.WriteString(",")
+				}
+
+				value := c.value(t, row, j)
+				if !f.contains(row, j) || math.IsNaN(float64(value)) {
+					buf.WriteString("null")
+				} else {
+					buf.WriteString(strconv.FormatFloat(float64(value), 'f', 2, 32))
+				}
+			}
+		}
+		buf.WriteString("]}")
+	}
+	buf.WriteString("]")
+
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("write output file: %s failed: %v", path, err)
+	}
+
+	return nil
+}
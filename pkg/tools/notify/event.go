@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// 事件类型
+const (
+	ProductReady  = "product.ready"  // 起报时次的输出文件已经生成
+	ProductFailed = "product.failed" // 输入文件存在, 但是解码, 生成或者上传失败
+)
+
+// Publisher 发送事件到下游, 例如: Webhook, NATS 或者 Redis Stream
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// Broker 消息中间件的客户端, 例如: NATS 或者 Redis Stream, 进程内所有数据集共用一个连接
+type Broker interface {
+	Publisher
+	Close() error
+}
+
+// Event 起报时次的输出文件完成时发送给下游的事件
+type Event struct {
+	ID         string            `json:"id"` // 随机生成, 接收方用于去重
+	Type       string            `json:"type"`
+	Dataset    string            `json:"dataset"`
+	Cycle      time.Time         `json:"cycle"`
+	ValidTimes []time.Time       `json:"valid_times,omitempty"`
+	Files      []File            `json:"files,omitempty"`
+	Missing    map[string]string `json:"missing,omitempty"` // 合并输出缺失的数据源及原因, 数据源到达后重新生成并再次通知
+	Error      string            `json:"error,omitempty"`   // 失败的原因, 只用于 product.failed
+	Time       time.Time         `json:"time"`              // 事件生成的时间
+}
+
+// File 完成的输出文件
+type File struct {
+	Path      string   `json:"path"`                // 本地路径, S3_DELETE_LOCAL 时上传后被删除
+	Locations []string `json:"locations,omitempty"` // 上传或者投递后远端的位置, 例如: s3://bucket/key, sftp://{目标名称}/{远端路径}
+	Profile   string   `json:"profile,omitempty"`   // 输出方案名称, 默认输出为空
+	Format    string   `json:"format"`
+	Region    string   `json:"region,omitempty"` // 区域名称, 全球为空
+	Size      int64    `json:"size"`
+	SHA256    string   `json:"sha256"`
+}
+
+func NewEvent(typ, dataset string, cycle time.Time) Event {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return Event{
+		ID:      hex.EncodeToString(id),
+		Type:    typ,
+		Dataset: dataset,
+		Cycle:   cycle.UTC(),
+		Time:    time.Now().UTC(),
+	}
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATS 事件发布到 {Subject}.{事件类型}, 例如: meteo.product.ready, 订阅方可以使用 meteo.> 订阅所有事件
+type NATS struct {
+	URL     string // 多个地址用 , 分隔
+	Subject string
+	Timeout time.Duration // 等待服务器确认的超时
+}
+
+// NATSClient 断线后自动重连, 重连期间发布的事件缓存在客户端
+type NATSClient struct {
+	conf NATS
+	conn *nats.Conn
+}
+
+func NewNATS(c NATS) (*NATSClient, error) {
+	if c.Subject == "" {
+		return nil, fmt.Errorf("nats subject is empty")
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = DefaultTimeout
+	}
+
+	// 启动时服务器不可用也继续运行, 在后台重连
+	conn, err := nats.Connect(c.URL,
+		nats.Name("gen-meteo-file"),
+		nats.Timeout(c.Timeout),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("connect nats: %s failed: %v", c.URL, err)
+	}
+
+	return &NATSClient{conf: c, conn: conn}, nil
+}
+
+// Publish 发布事件后等待服务器确认, 消息头 Nats-Msg-Id 为事件 id, 写入 JetStream 时用于去重
+func (c *NATSClient) Publish(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal event failed: %v", err)
+	}
+
+	msg := nats.NewMsg(c.conf.Subject + "." + e.Type)
+	msg.Header.Set(nats.MsgIdHdr, e.ID)
+	msg.Data = body
+
+	if err := c.conn.PublishMsg(msg); err != nil {
+		return fmt.Errorf("nats publish event: %s to %s failed: %v", e.ID, msg.Subject, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.conf.Timeout)
+	defer cancel()
+
+	if err := c.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("nats flush event: %s to %s failed: %v", e.ID, msg.Subject, err)
+	}
+
+	return nil
+}
+
+func (c *NATSClient) Close() error {
+	return c.conn.Drain()
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+// startNATSServer 进程内的 NATS 服务器, 监听随机端口
+func startNATSServer(t *testing.T) *server.Server {
+	t.Helper()
+
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go s.Start()
+	t.Cleanup(s.Shutdown)
+
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatalf("nats server not ready")
+	}
+
+	return s
+}
+
+func TestNATSPublish(t *testing.T) {
+	s := startNATSServer(t)
+
+	sub, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	msgs, err := sub.SubscribeSync("meteo.>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewNATS(NATS{URL: s.ClientURL(), Subject: "meteo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	e := testEvent()
+	if err := c.Publish(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := msgs.NextMsg(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Subject != "meteo.product.ready" {
+		t.Errorf("subject = %s, want meteo.product.ready", msg.Subject)
+	}
+	if id := msg.Header.Get(nats.MsgIdHdr); id != e.ID {
+		t.Errorf("header %s = %s, want %s", nats.MsgIdHdr, id, e.ID)
+	}
+
+	decodeEvent(t, msg.Data, e)
+}
+
+func TestNATSPublishTimeout(t *testing.T) {
+	s := startNATSServer(t)
+
+	c, err := NewNATS(NATS{URL: s.ClientURL(), Subject: "meteo", Timeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// 服务器关闭后客户端在后台重连, 等待确认超时
+	s.Shutdown()
+
+	start := time.Now()
+	if err := c.Publish(context.Background(), testEvent()); err == nil {
+		t.Fatalf("Publish() without server succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Publish() returned after %v", elapsed)
+	}
+}
+
+func TestNewNATSWithoutSubject(t *testing.T) {
+	if _, err := NewNATS(NATS{URL: nats.DefaultURL}); err == nil {
+		t.Errorf("NewNATS() without subject succeeded")
+	}
+}
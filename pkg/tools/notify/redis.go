@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis 事件追加到 Stream, 订阅方使用 XREAD 或者消费组读取
+type Redis struct {
+	Addr     string
+	Password string
+	DB       int
+	Stream   string
+	MaxLen   int64         // Stream 近似保留的最大长度, 0 表示不裁剪
+	Timeout  time.Duration // 单次命令的超时
+}
+
+// RedisClient 每条消息的字段: id, type, dataset, cycle 和 JSON 格式的 event
+type RedisClient struct {
+	conf   Redis
+	client *redis.Client
+}
+
+func NewRedis(c Redis) (*RedisClient, error) {
+	if c.Stream == "" {
+		return nil, fmt.Errorf("redis stream is empty")
+	}
+
+	if c.MaxLen < 0 {
+		return nil, fmt.Errorf("redis stream max len: %d must not be negative", c.MaxLen)
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = DefaultTimeout
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:         c.Addr,
+		Password:     c.Password,
+		DB:           c.DB,
+		DialTimeout:  c.Timeout,
+		ReadTimeout:  c.Timeout,
+		WriteTimeout: c.Timeout,
+	})
+
+	return &RedisClient{conf: c, client: client}, nil
+}
+
+// Publish 失败时按照客户端的配置重试
+func (c *RedisClient) Publish(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal event failed: %v", err)
+	}
+
+	err = c.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: c.conf.Stream,
+		MaxLen: c.conf.MaxLen,
+		Approx: c.conf.MaxLen > 0,
+		Values: []any{
+			"id", e.ID,
+			"type", e.Type,
+			"dataset", e.Dataset,
+			"cycle", e.Cycle.Format(time.RFC3339),
+			"event", body,
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("redis add event: %s to stream: %s failed: %v", e.ID, c.conf.Stream, err)
+	}
+
+	return nil
+}
+
+func (c *RedisClient) Close() error {
+	return c.client.Close()
+}
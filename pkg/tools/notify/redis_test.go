@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testEvent 带有一个输出文件的 product.ready 事件
+func testEvent() Event {
+	e := NewEvent(ProductReady, "ec", time.Date(2025, 6, 13, 0, 0, 0, 0, time.UTC))
+	e.ValidTimes = []time.Time{time.Date(2025, 6, 13, 3, 0, 0, 0, time.UTC)}
+	e.Files = []File{{
+		Path:      "/data/ec/ec_2025061300.zip",
+		Locations: []string{"s3://meteo/ec/ec_2025061300.zip"},
+		Format:    "csv",
+		Size:      100,
+		SHA256:    "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+	}}
+
+	return e
+}
+
+// decodeEvent 解析 JSON 格式的事件, 与发送的事件比较
+func decodeEvent(t *testing.T, body []byte, want Event) {
+	t.Helper()
+
+	var got Event
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("unmarshal event failed: %v, body: %s", err, body)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("event = %+v, want %+v", got, want)
+	}
+}
+
+func TestRedisPublish(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	c, err := NewRedis(Redis{Addr: mr.Addr(), Stream: "meteo:events"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	e := testEvent()
+	if err := c.Publish(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := mr.Stream("meteo:events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("stream entries = %d, want 1", len(entries))
+	}
+
+	values := entries[0].Values
+	want := []string{"id", e.ID, "type", ProductReady, "dataset", "ec", "cycle", "2025-06-13T00:00:00Z", "event"}
+	if len(values) != len(want)+1 || !reflect.DeepEqual(values[:len(want)], want) {
+		t.Fatalf("stream fields = %q, want %q and event", values, want)
+	}
+
+	decodeEvent(t, []byte(values[len(want)]), e)
+}
+
+func TestRedisMaxLen(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	c, err := NewRedis(Redis{Addr: mr.Addr(), Stream: "meteo:events", MaxLen: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var last Event
+	for range 3 {
+		last = testEvent()
+		if err := c.Publish(context.Background(), last); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 近似裁剪时 Redis 可能保留更多的消息, 最新的消息一定保留
+	entries, err := mr.Stream("meteo:events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) < 2 || len(entries) > 3 {
+		t.Fatalf("stream entries = %d, want 2 ~ 3", len(entries))
+	}
+	if id := entries[len(entries)-1].Values[1]; id != last.ID {
+		t.Errorf("last entry id = %s, want %s", id, last.ID)
+	}
+}
+
+func TestRedisPublishFailed(t *testing.T) {
+	mr := miniredis.RunT(t)
+	addr := mr.Addr()
+	mr.Close()
+
+	c, err := NewRedis(Redis{Addr: addr, Stream: "meteo:events", Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Publish(context.Background(), testEvent()); err == nil {
+		t.Errorf("Publish() to closed server succeeded")
+	}
+}
@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 请求头, 签名为 sha256=HMAC-SHA256(secret, body) 的十六进制
+const (
+	SignatureHeader = "X-Meteo-Signature"
+	EventHeader     = "X-Meteo-Event"
+	DeliveryHeader  = "X-Meteo-Delivery"
+)
+
+// DefaultTimeout 请求超时为 0 时使用的超时
+const DefaultTimeout = 10 * time.Second
+
+// Webhook 事件以 JSON POST 到 URL, Secret 不为空时对请求体签名
+type Webhook struct {
+	Name       string
+	URL        string
+	Secret     string
+	Retries    int           // 失败后的重试次数
+	Timeout    time.Duration // 单次请求的超时
+	DeadLetter string        // 重试后仍然失败的事件追加到这个文件, 每行一个 JSON, 为空时不记录
+}
+
+// WebhookClient 发送事件到 Webhook, 2xx 为成功, 网络错误, 408, 429 和 5xx 按照指数退避重试
+type WebhookClient struct {
+	hook   Webhook
+	client *http.Client
+}
+
+func NewWebhook(w Webhook) (*WebhookClient, error) {
+	if w.URL == "" {
+		return nil, fmt.Errorf("webhook: %s url is empty", w.Name)
+	}
+
+	if w.Retries < 0 {
+		return nil, fmt.Errorf("webhook: %s retries: %d must not be negative", w.Name, w.Retries)
+	}
+
+	if w.Timeout == 0 {
+		w.Timeout = DefaultTimeout
+	}
+
+	return &WebhookClient{hook: w, client: &http.Client{Timeout: w.Timeout}}, nil
+}
+
+// Sign 请求体的签名, 接收方使用相同的 secret 计算后比较
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publish 发送事件, 重试后仍然失败时写入死信文件
+func (c *WebhookClient) Publish(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal event failed: %v", err)
+	}
+
+	wait := time.Second
+	for attempt := 1; ; attempt++ {
+		retry, err := c.post(ctx, e, body)
+		if err == nil {
+			return nil
+		}
+
+		if !retry || attempt > c.hook.Retries {
+			return c.fail(e, fmt.Errorf("webhook: %s post event: %s failed after %d attempts: %v", c.hook.Name, e.ID, attempt, err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return c.fail(e, fmt.Errorf("webhook: %s post event: %s failed: %v", c.hook.Name, e.ID, ctx.Err()))
+		case <-time.After(wait):
+		}
+
+		wait = min(wait*2, time.Minute)
+	}
+}
+
+// post 发送一次请求, 返回失败后是否需要重试
+func (c *WebhookClient) post(ctx context.Context, e Event, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, e.Type)
+	req.Header.Set(DeliveryHeader, e.ID)
+	if c.hook.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(c.hook.Secret, body))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("status: %s", resp.Status)
+}
+
+// fail 记录死信后返回错误
+func (c *WebhookClient) fail(e Event, err error) error {
+	if dlErr := c.deadLetter(e, err); dlErr != nil {
+		return fmt.Errorf("%v, %v", err, dlErr)
+	}
+
+	return err
+}
+
+// deadLetter 追加失败的事件, 每行一个 JSON: {"time", "webhook", "url", "error", "event"}
+func (c *WebhookClient) deadLetter(e Event, cause error) error {
+	if c.hook.DeadLetter == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(struct {
+		Time    time.Time `json:"time"`
+		Webhook string    `json:"webhook"`
+		URL     string    `json:"url"`
+		Error   string    `json:"error"`
+		Event   Event     `json:"event"`
+	}{time.Now(), c.hook.Name, c.hook.URL, cause.Error(), e})
+	if err != nil {
+		return fmt.Errorf("marshal dead letter failed: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.hook.DeadLetter), os.FileMode(0755)); err != nil {
+		return fmt.Errorf("create dead letter dir: %s failed: %v", filepath.Dir(c.hook.DeadLetter), err)
+	}
+
+	// 多个数据源共用一个文件, 每个事件一次 O_APPEND 写入
+	file, err := os.OpenFile(c.hook.DeadLetter, os.O_CREATE|os.O_WRONLY|os.O_APPEND, os.FileMode(0644))
+	if err != nil {
+		return fmt.Errorf("open dead letter: %s failed: %v", c.hook.DeadLetter, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write dead letter: %s failed: %v", c.hook.DeadLetter, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,340 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const (
+	// MinPartSize S3 允许的最小分片大小
+	MinPartSize = 5 << 20
+	// DefaultPartSize 分片大小为 0 时使用的大小
+	DefaultPartSize = 16 << 20
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("objstore: object not found")
+
+// Config S3 兼容对象存储(例如: MinIO)的连接配置
+type Config struct {
+	Endpoint  string // host:port, 不含协议
+	AccessKey string
+	SecretKey string
+	Region    string
+	Secure    bool   // 使用 https
+	PartSize  uint64 // 分片大小(字节), 大于该大小的文件分片上传, 0 表示 DefaultPartSize
+	Retries   int    // 失败后的重试次数
+
+	// Transport 为空时使用默认的 Transport, 例如: 自签名证书时自定义 TLS 配置
+	Transport http.RoundTripper
+}
+
+// Client 对象存储的客户端, 上传时校验 Content-MD5 和 ETag, 失败后按照指数退避重试
+type Client struct {
+	client   *minio.Client
+	partSize uint64
+	retries  int
+}
+
+func New(c Config) (*Client, error) {
+	if c.Endpoint == "" {
+		return nil, fmt.Errorf("objstore: endpoint is empty")
+	}
+
+	if c.PartSize == 0 {
+		c.PartSize = DefaultPartSize
+	}
+	if c.PartSize < MinPartSize {
+		return nil, fmt.Errorf("objstore: part size: %d must be at least %d", c.PartSize, MinPartSize)
+	}
+
+	if c.Retries < 0 {
+		return nil, fmt.Errorf("objstore: retries: %d must not be negative", c.Retries)
+	}
+
+	client, err := minio.New(c.Endpoint, &minio.Options{
+		Creds:     credentials.NewStaticV4(c.AccessKey, c.SecretKey, ""),
+		Secure:    c.Secure,
+		Region:    c.Region,
+		Transport: c.Transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("objstore: new client: %s failed: %v", c.Endpoint, err)
+	}
+
+	return &Client{client: client, partSize: c.PartSize, retries: c.Retries}, nil
+}
+
+// Upload 上传本地文件, 大于分片大小时分片上传, 上传后比较 ETag 与本地文件的 MD5
+func (c *Client) Upload(ctx context.Context, bucket, key, path string) error {
+	etag, err := fileETag(path, c.partSize)
+	if err != nil {
+		return err
+	}
+
+	return c.retry(ctx, fmt.Sprintf("upload %s to s3://%s/%s", path, bucket, key), func() error {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("open file: %s failed: %v", path, err)
+		}
+		defer file.Close()
+
+		stat, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("get file info: %s failed: %v", path, err)
+		}
+
+		info, err := c.client.PutObject(ctx, bucket, key, file, stat.Size(), minio.PutObjectOptions{
+			ContentType:    contentType(key),
+			PartSize:       c.partSize,
+			SendContentMd5: true,
+		})
+		if err != nil {
+			return err
+		}
+
+		return checkETag(info.ETag, etag)
+	})
+}
+
+// Put 上传内存中的数据, 例如: 元数据
+func (c *Client) Put(ctx context.Context, bucket, key string, data []byte) error {
+	sum := md5.Sum(data)
+	etag := hex.EncodeToString(sum[:])
+
+	return c.retry(ctx, fmt.Sprintf("put s3://%s/%s", bucket, key), func() error {
+		info, err := c.client.PutObject(ctx, bucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+			ContentType:    contentType(key),
+			SendContentMd5: true,
+		})
+		if err != nil {
+			return err
+		}
+
+		return checkETag(info.ETag, etag)
+	})
+}
+
+// Exists 对象是否存在
+func (c *Client) Exists(ctx context.Context, bucket, key string) (bool, error) {
+	_, err := c.client.StatObject(ctx, bucket, key, minio.StatObjectOptions{})
+	if err == nil {
+		return true, nil
+	}
+
+	if notFound(err) {
+		return false, nil
+	}
+
+	return false, fmt.Errorf("stat s3://%s/%s failed: %v", bucket, key, err)
+}
+
+// Stat 对象的 ETag 和大小, 对象不存在时返回 ErrNotFound
+func (c *Client) Stat(ctx context.Context, bucket, key string) (string, int64, error) {
+	info, err := c.client.StatObject(ctx, bucket, key, minio.StatObjectOptions{})
+	if notFound(err) {
+		return "", 0, ErrNotFound
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("stat s3://%s/%s failed: %v", bucket, key, err)
+	}
+
+	return strings.Trim(info.ETag, `"`), info.Size, nil
+}
+
+// List 前缀下所有对象的名称, 按照字典序排列
+func (c *Client) List(ctx context.Context, bucket, prefix string) ([]string, error) {
+	var keys []string
+	for obj := range c.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("list s3://%s/%s failed: %v", bucket, prefix, obj.Err)
+		}
+		keys = append(keys, obj.Key)
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Download 下载对象到 path, 先写入同目录的临时文件, 校验大小和 MD5 后重命名, 返回对象的 ETag
+// 分片上传的对象 ETag 不是 MD5, 只校验大小
+func (c *Client) Download(ctx context.Context, bucket, key, path string) (string, error) {
+	var etag string
+	err := c.retry(ctx, fmt.Sprintf("download s3://%s/%s to %s", bucket, key, path), func() error {
+		var err error
+		etag, err = c.download(ctx, bucket, key, path)
+		return err
+	})
+
+	return etag, err
+}
+
+func (c *Client) download(ctx context.Context, bucket, key, path string) (string, error) {
+	obj, err := c.client.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer obj.Close()
+
+	info, err := obj.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+		return "", fmt.Errorf("create dir: %s failed: %v", filepath.Dir(path), err)
+	}
+
+	// 多个数据源可能同时下载同一个对象, 每次下载使用不同的临时文件
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("create temp file failed: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := md5.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), obj)
+	if err != nil {
+		return "", err
+	}
+
+	if n != info.Size {
+		return "", fmt.Errorf("size: %d does not match object size: %d", n, info.Size)
+	}
+
+	etag := strings.Trim(info.ETag, `"`)
+	if !strings.Contains(etag, "-") {
+		if err := checkETag(etag, hex.EncodeToString(hash.Sum(nil))); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("close temp file failed: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("rename temp file: %s failed: %v", tmp.Name(), err)
+	}
+
+	return etag, nil
+}
+
+// retry 执行 fn, 失败后等待 1s, 2s, 4s ... 重试, 最多等待 1 分钟
+func (c *Client) retry(ctx context.Context, name string, fn func() error) error {
+	wait := time.Second
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+
+		if attempt >= c.retries {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s failed: %v", name, ctx.Err())
+		case <-time.After(wait):
+		}
+
+		wait = min(wait*2, time.Minute)
+	}
+
+	return fmt.Errorf("%s failed after %d attempts: %v", name, c.retries+1, err)
+}
+
+// fileETag 按照 S3 的规则计算文件的 ETag
+// 单个分片时为文件的 MD5, 多个分片时为每个分片 MD5 拼接后的 MD5 加上 -分片数
+func fileETag(path string, partSize uint64) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open file: %s failed: %v", path, err)
+	}
+	defer file.Close()
+
+	var (
+		sums  []byte
+		parts int
+	)
+	for {
+		h := md5.New()
+		n, err := io.CopyN(h, file, int64(partSize))
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("read file: %s failed: %v", path, err)
+		}
+
+		if n > 0 || parts == 0 {
+			sums = h.Sum(sums)
+			parts++
+		}
+
+		if n < int64(partSize) {
+			break
+		}
+	}
+
+	if parts == 1 {
+		return hex.EncodeToString(sums), nil
+	}
+
+	sum := md5.Sum(sums)
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), parts), nil
+}
+
+// notFound 对象或者存储桶不存在
+func notFound(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	code := minio.ToErrorResponse(err).Code
+	return code == minio.NoSuchKey || code == minio.NoSuchBucket || code == "NotFound"
+}
+
+func checkETag(got, want string) error {
+	if got = strings.Trim(got, `"`); got != want {
+		return fmt.Errorf("etag: %s does not match local md5: %s", got, want)
+	}
+
+	return nil
+}
+
+// contentType 按照扩展名设置对象的 Content-Type
+func contentType(key string) string {
+	switch {
+	case strings.HasSuffix(key, ".json"):
+		return "application/json"
+	case strings.HasSuffix(key, ".zip"):
+		return "application/zip"
+	case strings.HasSuffix(key, ".gz"):
+		return "application/gzip"
+	case strings.HasSuffix(key, ".zst"):
+		return "application/zstd"
+	case strings.HasSuffix(key, ".xz"):
+		return "application/x-xz"
+	case strings.HasSuffix(key, ".tar"):
+		return "application/x-tar"
+	case strings.HasSuffix(key, ".csv"):
+		return "text/csv"
+	}
+
+	return "application/octet-stream"
+}
@@ -0,0 +1,242 @@
+package objstore
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+)
+
+const testBucket = "products"
+
+// newTestClient 启动内存中的 S3 服务, 分片上传需要 https, 使用 httptest 的自签名证书
+// wrap 不为空时包装服务端的 handler, 用于模拟异常的响应
+func newTestClient(t *testing.T, retries int, wrap func(http.Handler) http.Handler) (*Client, *s3mem.Backend) {
+	t.Helper()
+
+	backend := s3mem.New()
+	if err := backend.CreateBucket(testBucket); err != nil {
+		t.Fatal(err)
+	}
+
+	var handler http.Handler = gofakes3.New(backend).Server()
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := New(Config{
+		Endpoint:  strings.TrimPrefix(server.URL, "https://"),
+		AccessKey: "access",
+		SecretKey: "secret",
+		Secure:    true,
+		PartSize:  MinPartSize,
+		Retries:   retries,
+		Transport: server.Client().Transport,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, backend
+}
+
+func writeRandomFile(t *testing.T, size int) (string, []byte) {
+	t.Helper()
+
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, data
+}
+
+func TestFileETag(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		want func(data []byte) string
+	}{
+		{
+			name: "empty",
+			size: 0,
+			want: func(data []byte) string { sum := md5.Sum(data); return hex.EncodeToString(sum[:]) },
+		},
+		{
+			name: "single part",
+			size: MinPartSize,
+			want: func(data []byte) string { sum := md5.Sum(data); return hex.EncodeToString(sum[:]) },
+		},
+		{
+			name: "multipart",
+			size: 2*MinPartSize + 1,
+			want: func(data []byte) string {
+				var sums []byte
+				for start := 0; start < len(data); start += MinPartSize {
+					sum := md5.Sum(data[start:min(start+MinPartSize, len(data))])
+					sums = append(sums, sum[:]...)
+				}
+				sum := md5.Sum(sums)
+				return hex.EncodeToString(sum[:]) + "-3"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, data := writeRandomFile(t, tt.size)
+
+			got, err := fileETag(path, MinPartSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := tt.want(data); got != want {
+				t.Errorf("fileETag() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestUpload(t *testing.T) {
+	client, backend := newTestClient(t, 0, nil)
+	ctx := context.Background()
+
+	for _, size := range []int{1024, MinPartSize + 1} {
+		t.Run(fmt.Sprintf("size %d", size), func(t *testing.T) {
+			path, _ := writeRandomFile(t, size)
+			key := fmt.Sprintf("ec/%d.zip", size)
+
+			if err := client.Upload(ctx, testBucket, key, path); err != nil {
+				t.Fatal(err)
+			}
+
+			// Upload 已经校验了上传返回的 ETag, 这里只检查对象的大小
+			_, n, err := client.Stat(ctx, testBucket, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != int64(size) {
+				t.Errorf("Stat() size = %d, want %d", n, size)
+			}
+
+			obj, err := backend.HeadObject(testBucket, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := obj.Metadata["Content-Type"]; got != "application/zip" {
+				t.Errorf("content type = %s, want application/zip", got)
+			}
+		})
+	}
+
+	if ok, err := client.Exists(ctx, testBucket, "ec/missing.zip"); err != nil || ok {
+		t.Errorf("Exists() = %v, %v, want false, nil", ok, err)
+	}
+
+	if _, _, err := client.Stat(ctx, testBucket, "ec/missing.zip"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUploadETagMismatch(t *testing.T) {
+	var puts atomic.Int32
+	client, _ := newTestClient(t, 1, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPut {
+				next.ServeHTTP(w, r)
+				return
+			}
+			puts.Add(1)
+
+			// 将服务端返回的 ETag 替换为错误的值
+			rec := httptest.NewRecorder()
+			next.ServeHTTP(rec, r)
+			for k, v := range rec.Header() {
+				w.Header()[k] = v
+			}
+			w.Header().Set("ETag", `"00000000000000000000000000000000"`)
+			w.WriteHeader(rec.Code)
+			w.Write(rec.Body.Bytes())
+		})
+	})
+
+	path, _ := writeRandomFile(t, 1024)
+
+	err := client.Upload(context.Background(), testBucket, "ec/mismatch.zip", path)
+	if err == nil || !strings.Contains(err.Error(), "does not match local md5") {
+		t.Fatalf("Upload() error = %v, want etag mismatch", err)
+	}
+
+	// 校验失败后重试一次
+	if got := puts.Load(); got != 2 {
+		t.Errorf("put requests = %d, want 2", got)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	t.Run("success after failure", func(t *testing.T) {
+		c := &Client{retries: 1}
+
+		attempts := 0
+		err := c.retry(context.Background(), "test", func() error {
+			attempts++
+			if attempts == 1 {
+				return fmt.Errorf("temporary")
+			}
+			return nil
+		})
+		if err != nil || attempts != 2 {
+			t.Errorf("retry() = %v, attempts %d, want nil, 2", err, attempts)
+		}
+	})
+
+	t.Run("give up", func(t *testing.T) {
+		c := &Client{retries: 0}
+
+		attempts := 0
+		err := c.retry(context.Background(), "test", func() error {
+			attempts++
+			return fmt.Errorf("permanent")
+		})
+		if err == nil || !strings.Contains(err.Error(), "failed after 1 attempts: permanent") || attempts != 1 {
+			t.Errorf("retry() = %v, attempts %d, want permanent error, 1", err, attempts)
+		}
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		c := &Client{retries: 3}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		attempts := 0
+		err := c.retry(ctx, "test", func() error {
+			attempts++
+			return fmt.Errorf("temporary")
+		})
+		if err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) || attempts != 1 {
+			t.Errorf("retry() = %v, attempts %d, want canceled error, 1", err, attempts)
+		}
+	})
+}
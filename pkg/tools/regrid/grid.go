@@ -0,0 +1,68 @@
+package regrid
+
+import (
+	"fmt"
+	"math"
+)
+
+// LatOrder 目标网格纬度的排列顺序
+type LatOrder string
+
+const (
+	Descending LatOrder = "desc" // 90 ~ -90, 与 EC 一致
+	Ascending  LatOrder = "asc"  // -90 ~ 90, 与 MFWAM/SMOC 一致
+)
+
+// Grid 规则经纬度网格
+type Grid struct {
+	Lats []float64
+	Lons []float64
+}
+
+// NewGrid 按照分辨率和范围生成规则网格, 范围包含边界
+// 西侧经度大于东侧经度时表示跨越 180 度经线, 东侧经度加 360, 经度仍然是升序
+func NewGrid(resolution, west, south, east, north float64, order LatOrder) (Grid, error) {
+	if resolution <= 0 {
+		return Grid{}, fmt.Errorf("grid resolution: %v must be greater than 0", resolution)
+	}
+	if west > east {
+		east += 360
+	}
+	if south > north || west > east {
+		return Grid{}, fmt.Errorf("grid extent: %v,%v,%v,%v is invalid", west, south, east, north)
+	}
+	if order != Descending && order != Ascending {
+		return Grid{}, fmt.Errorf("grid lat order: %s must be %s or %s", order, Descending, Ascending)
+	}
+
+	lats := axis(south, north, resolution)
+	if order == Descending {
+		for i, j := 0, len(lats)-1; i < j; i, j = i+1, j-1 {
+			lats[i], lats[j] = lats[j], lats[i]
+		}
+	}
+
+	return Grid{Lats: lats, Lons: axis(west, east, resolution)}, nil
+}
+
+func axis(from, to, resolution float64) []float64 {
+	count := int(math.Floor((to-from)/resolution+1e-6)) + 1
+	values := make([]float64, count)
+	for i := range values {
+		// 按照下标计算, 避免累加带来的误差
+		values[i] = math.Round((from+float64(i)*resolution)*1e6) / 1e6
+	}
+
+	return values
+}
+
+// periodic 经度是否覆盖全球, 覆盖全球时插值可以跨越首尾
+func periodic(lons []float64) bool {
+	if len(lons) < 2 {
+		return false
+	}
+
+	step := math.Abs(lons[1] - lons[0])
+	span := math.Abs(lons[len(lons)-1]-lons[0]) + step
+	return math.Abs(span-360) < step/2
+}
@@ -0,0 +1,83 @@
+package regrid
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewGrid(t *testing.T) {
+	tests := []struct {
+		name                     string
+		resolution               float64
+		west, south, east, north float64
+		order                    LatOrder
+		wantLats, wantLons       []float64
+		wantErr                  bool
+	}{
+		{
+			name:       "descending",
+			resolution: 0.5, west: 100, south: 10, east: 101, north: 11, order: Descending,
+			wantLats: []float64{11, 10.5, 10}, wantLons: []float64{100, 100.5, 101},
+		},
+		{
+			name:       "ascending",
+			resolution: 0.5, west: 100, south: 10, east: 101, north: 11, order: Ascending,
+			wantLats: []float64{10, 10.5, 11}, wantLons: []float64{100, 100.5, 101},
+		},
+		{
+			name:       "extent not a multiple of resolution",
+			resolution: 0.3, west: 0, south: 0, east: 1, north: 0.5, order: Ascending,
+			wantLats: []float64{0, 0.3}, wantLons: []float64{0, 0.3, 0.6, 0.9},
+		},
+		{
+			name:       "single point",
+			resolution: 1, west: 120, south: 30, east: 120, north: 30, order: Descending,
+			wantLats: []float64{30}, wantLons: []float64{120},
+		},
+		{
+			// 跨越 180 度经线, 东侧经度加 360
+			name:       "antimeridian",
+			resolution: 5, west: 170, south: -5, east: -170, north: 5, order: Descending,
+			wantLats: []float64{5, 0, -5}, wantLons: []float64{170, 175, 180, 185, 190},
+		},
+		{name: "zero resolution", resolution: 0, west: 0, south: 0, east: 1, north: 1, order: Descending, wantErr: true},
+		{name: "negative resolution", resolution: -1, west: 0, south: 0, east: 1, north: 1, order: Descending, wantErr: true},
+		{name: "south greater than north", resolution: 1, west: 0, south: 10, east: 1, north: 0, order: Descending, wantErr: true},
+		{name: "unknown lat order", resolution: 1, west: 0, south: 0, east: 1, north: 1, order: "north", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grid, err := NewGrid(tt.resolution, tt.west, tt.south, tt.east, tt.north, tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewGrid() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if !slices.Equal(grid.Lats, tt.wantLats) || !slices.Equal(grid.Lons, tt.wantLons) {
+				t.Errorf("NewGrid() lats = %v, lons = %v, want %v, %v", grid.Lats, grid.Lons, tt.wantLats, tt.wantLons)
+			}
+		})
+	}
+}
+
+func TestPeriodic(t *testing.T) {
+	tests := []struct {
+		name string
+		lons []float64
+		want bool
+	}{
+		{name: "global 0.25", lons: axis(-180, 179.75, 0.25), want: true},
+		{name: "global 0 ~ 359", lons: axis(0, 359, 1), want: true},
+		{name: "regional", lons: axis(100, 150, 1), want: false},
+		{name: "single", lons: []float64{0}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := periodic(tt.lons); got != tt.want {
+			t.Errorf("periodic(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
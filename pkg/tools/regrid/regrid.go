@@ -0,0 +1,191 @@
+package regrid
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Method 插值方法
+type Method string
+
+const (
+	Bilinear Method = "bilinear"
+	Nearest  Method = "nearest"
+)
+
+// Target 插值的目标网格和方法
+type Target struct {
+	Grid   Grid
+	Method Method
+}
+
+// Regridder 源网格到目标网格的插值器, 插值权重在创建时计算, 可以被多个变量和时次复用
+type Regridder struct {
+	method Method
+	lats   []position
+	lons   []position
+}
+
+// position 目标格点在源网格某个轴上的位置, 值为 i0 和 i1 两个格点按照权重 w 的线性组合:
+// value = (1 - w) * v[i0] + w * v[i1], i0 < 0 表示超出源网格范围
+type position struct {
+	i0, i1 int
+	w      float64
+}
+
+// New 创建插值器, 源网格和目标网格的纬度可以是升序也可以是降序, 经度必须是升序
+func New(src, dst Grid, method Method) (*Regridder, error) {
+	if method != Bilinear && method != Nearest {
+		return nil, fmt.Errorf("regrid method: %s not supported", method)
+	}
+	if len(src.Lats) < 2 || len(src.Lons) < 2 {
+		return nil, fmt.Errorf("source grid must have at least 2 latitudes and longitudes")
+	}
+
+	r := &Regridder{
+		method: method,
+		lats:   make([]position, len(dst.Lats)),
+		lons:   make([]position, len(dst.Lons)),
+	}
+
+	for i, lat := range dst.Lats {
+		r.lats[i] = locate(src.Lats, lat, false)
+	}
+
+	cyclic := periodic(src.Lons)
+	for i, lon := range dst.Lons {
+		r.lons[i] = locate(src.Lons, lon, cyclic)
+	}
+
+	return r, nil
+}
+
+// Regrid 将源网格上的变量插值到目标网格, value 为源网格上的取值, 缺测值为 NaN
+// 双线性插值时忽略缺测的角点并重新归一化权重, 四个角点都缺测时结果为 NaN
+func (r *Regridder) Regrid(value func(lat, lon int) float32) [][]float32 {
+	result := make([][]float32, len(r.lats))
+	for i, lat := range r.lats {
+		result[i] = make([]float32, len(r.lons))
+		for j, lon := range r.lons {
+			result[i][j] = r.interpolate(lat, lon, value)
+		}
+	}
+
+	return result
+}
+
+// RegridDirection 插值方向类变量(单位: 度), 先分解为单位向量插值, 再合成方向, 避免 359 与 1 度之间插值为 180 度
+func (r *Regridder) RegridDirection(value func(lat, lon int) float32) [][]float32 {
+	sin := r.Regrid(func(lat, lon int) float32 {
+		return float32(math.Sin(float64(value(lat, lon)) * math.Pi / 180))
+	})
+	cos := r.Regrid(func(lat, lon int) float32 {
+		return float32(math.Cos(float64(value(lat, lon)) * math.Pi / 180))
+	})
+
+	for i := range sin {
+		for j := range sin[i] {
+			sin[i][j] = Direction(sin[i][j], cos[i][j])
+		}
+	}
+
+	return sin
+}
+
+// Direction 由单位向量的分量计算方向(0~360 度), 任意分量缺测时为 NaN
+func Direction(sin, cos float32) float32 {
+	if math.IsNaN(float64(sin)) || math.IsNaN(float64(cos)) {
+		return float32(math.NaN())
+	}
+
+	degree := math.Atan2(float64(sin), float64(cos)) * 180 / math.Pi
+	if degree < 0 {
+		degree += 360
+	}
+
+	return float32(degree)
+}
+
+func (r *Regridder) interpolate(lat, lon position, value func(lat, lon int) float32) float32 {
+	if lat.i0 < 0 || lon.i0 < 0 {
+		return float32(math.NaN())
+	}
+
+	if r.method == Nearest {
+		i, j := lat.i0, lon.i0
+		if lat.w >= 0.5 {
+			i = lat.i1
+		}
+		if lon.w >= 0.5 {
+			j = lon.i1
+		}
+		return value(i, j)
+	}
+
+	corners := [4]struct {
+		i, j int
+		w    float64
+	}{
+		{lat.i0, lon.i0, (1 - lat.w) * (1 - lon.w)},
+		{lat.i0, lon.i1, (1 - lat.w) * lon.w},
+		{lat.i1, lon.i0, lat.w * (1 - lon.w)},
+		{lat.i1, lon.i1, lat.w * lon.w},
+	}
+
+	var sum, weight float64
+	for _, c := range corners {
+		if c.w == 0 {
+			continue
+		}
+
+		v := float64(value(c.i, c.j))
+		if math.IsNaN(v) {
+			continue
+		}
+
+		sum += v * c.w
+		weight += c.w
+	}
+
+	if weight == 0 {
+		return float32(math.NaN())
+	}
+
+	return float32(sum / weight)
+}
+
+// locate 查找 x 在单调数组 axis 中的位置
+func locate(axis []float64, x float64, cyclic bool) position {
+	n := len(axis)
+	descending := axis[0] > axis[n-1]
+
+	if cyclic {
+		// 将经度归一化到 [axis[0], axis[0] + 360)
+		x = axis[0] + math.Mod(math.Mod(x-axis[0], 360)+360, 360)
+		if x > axis[n-1] {
+			// 位于最后一个格点与第一个格点之间
+			step := axis[n-1] - axis[n-2]
+			return position{i0: n - 1, i1: 0, w: (x - axis[n-1]) / step}
+		}
+	}
+
+	// 第一个 >= x (降序时 <= x) 的下标
+	k := sort.Search(n, func(i int) bool {
+		if descending {
+			return axis[i] <= x
+		}
+		return axis[i] >= x
+	})
+
+	switch {
+	case k == n:
+		return position{i0: -1}
+	case axis[k] == x:
+		return position{i0: k, i1: k}
+	case k == 0:
+		return position{i0: -1}
+	}
+
+	return position{i0: k - 1, i1: k, w: (x - axis[k-1]) / (axis[k] - axis[k-1])}
+}
@@ -0,0 +1,178 @@
+package regrid
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLocate(t *testing.T) {
+	global := axis(0, 350, 10)
+
+	tests := []struct {
+		name   string
+		axis   []float64
+		x      float64
+		cyclic bool
+		want   position
+	}{
+		{name: "ascending", axis: []float64{0, 1, 2}, x: 1.25, want: position{i0: 1, i1: 2, w: 0.25}},
+		{name: "descending", axis: []float64{2, 1, 0}, x: 1.25, want: position{i0: 0, i1: 1, w: 0.75}},
+		{name: "on grid point", axis: []float64{0, 1, 2}, x: 1, want: position{i0: 1, i1: 1}},
+		{name: "first grid point", axis: []float64{0, 1, 2}, x: 0, want: position{i0: 0, i1: 0}},
+		{name: "last grid point", axis: []float64{0, 1, 2}, x: 2, want: position{i0: 2, i1: 2}},
+		{name: "below range", axis: []float64{0, 1, 2}, x: -0.5, want: position{i0: -1}},
+		{name: "above range", axis: []float64{0, 1, 2}, x: 2.5, want: position{i0: -1}},
+		{name: "descending above range", axis: []float64{2, 1, 0}, x: 2.5, want: position{i0: -1}},
+		// 全球经度首尾相接
+		{name: "cyclic between last and first", axis: global, x: 355, cyclic: true, want: position{i0: 35, i1: 0, w: 0.5}},
+		{name: "cyclic negative longitude", axis: global, x: -5, cyclic: true, want: position{i0: 35, i1: 0, w: 0.5}},
+		{name: "cyclic negative longitude inside", axis: global, x: -175, cyclic: true, want: position{i0: 18, i1: 19, w: 0.5}},
+		{name: "cyclic 360", axis: global, x: 360, cyclic: true, want: position{i0: 0, i1: 0}},
+		{name: "cyclic greater than 360", axis: global, x: 725, cyclic: true, want: position{i0: 0, i1: 1, w: 0.5}},
+		{name: "cyclic antimeridian", axis: axis(-180, 170, 10), x: 185, cyclic: true, want: position{i0: 0, i1: 1, w: 0.5}},
+		{name: "not cyclic after last", axis: global, x: 355, want: position{i0: -1}},
+	}
+
+	for _, tt := range tests {
+		if got := locate(tt.axis, tt.x, tt.cyclic); got != tt.want {
+			t.Errorf("locate(%s) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func equal(a, b float32) bool {
+	if math.IsNaN(float64(a)) || math.IsNaN(float64(b)) {
+		return math.IsNaN(float64(a)) && math.IsNaN(float64(b))
+	}
+
+	return math.Abs(float64(a-b)) < 1e-4
+}
+
+func TestRegrid(t *testing.T) {
+	nan := float32(math.NaN())
+	src := Grid{Lats: []float64{1, 0}, Lons: []float64{0, 1}}
+
+	tests := []struct {
+		name   string
+		method Method
+		dst    Grid
+		values [2][2]float32 // [lat][lon], 纬度从北到南
+		want   float32
+	}{
+		{name: "bilinear center", method: Bilinear, dst: Grid{Lats: []float64{0.5}, Lons: []float64{0.5}}, values: [2][2]float32{{1, 2}, {3, 4}}, want: 2.5},
+		{name: "bilinear weighted", method: Bilinear, dst: Grid{Lats: []float64{0.75}, Lons: []float64{0.25}}, values: [2][2]float32{{0, 4}, {8, 12}}, want: 3},
+		{name: "bilinear on grid point", method: Bilinear, dst: Grid{Lats: []float64{0}, Lons: []float64{1}}, values: [2][2]float32{{1, 2}, {3, 4}}, want: 4},
+		// 缺测的角点不参与插值, 其余角点的权重重新归一化
+		{name: "bilinear one nan corner", method: Bilinear, dst: Grid{Lats: []float64{0.5}, Lons: []float64{0.5}}, values: [2][2]float32{{nan, 2}, {3, 4}}, want: 3},
+		{name: "bilinear weighted nan corner", method: Bilinear, dst: Grid{Lats: []float64{0.75}, Lons: []float64{0.25}}, values: [2][2]float32{{nan, 4}, {8, 12}}, want: 3 / 0.4375},
+		{name: "bilinear three nan corners", method: Bilinear, dst: Grid{Lats: []float64{0.5}, Lons: []float64{0.5}}, values: [2][2]float32{{nan, nan}, {nan, 4}}, want: 4},
+		{name: "bilinear all nan corners", method: Bilinear, dst: Grid{Lats: []float64{0.5}, Lons: []float64{0.5}}, values: [2][2]float32{{nan, nan}, {nan, nan}}, want: nan},
+		// 权重为 0 的角点缺测时不影响结果
+		{name: "bilinear nan corner with zero weight", method: Bilinear, dst: Grid{Lats: []float64{1}, Lons: []float64{0.5}}, values: [2][2]float32{{1, 3}, {nan, nan}}, want: 2},
+		{name: "bilinear outside", method: Bilinear, dst: Grid{Lats: []float64{2}, Lons: []float64{0.5}}, values: [2][2]float32{{1, 2}, {3, 4}}, want: nan},
+		{name: "nearest", method: Nearest, dst: Grid{Lats: []float64{0.75}, Lons: []float64{0.25}}, values: [2][2]float32{{1, 2}, {3, 4}}, want: 1},
+		{name: "nearest half", method: Nearest, dst: Grid{Lats: []float64{0.5}, Lons: []float64{0.5}}, values: [2][2]float32{{1, 2}, {3, 4}}, want: 4},
+		{name: "nearest nan", method: Nearest, dst: Grid{Lats: []float64{0.75}, Lons: []float64{0.25}}, values: [2][2]float32{{nan, 2}, {3, 4}}, want: nan},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(src, tt.dst, tt.method)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := r.Regrid(func(lat, lon int) float32 { return tt.values[lat][lon] })
+			if !equal(got[0][0], tt.want) {
+				t.Errorf("Regrid() = %v, want %v", got[0][0], tt.want)
+			}
+		})
+	}
+}
+
+func TestRegridCyclic(t *testing.T) {
+	src := Grid{Lats: []float64{0, 1}, Lons: axis(0, 350, 10)}
+	dst := Grid{Lats: []float64{0}, Lons: []float64{-5, 355, 175}}
+
+	r, err := New(src, dst, Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 经度 350 ~ 360 之间使用首尾两个格点插值
+	got := r.Regrid(func(lat, lon int) float32 { return float32(src.Lons[lon]) })
+	for i, want := range []float32{175, 175, 175} {
+		if !equal(got[0][i], want) {
+			t.Errorf("Regrid() lon %v = %v, want %v", dst.Lons[i], got[0][i], want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	src := Grid{Lats: []float64{0, 1}, Lons: []float64{0, 1}}
+
+	if _, err := New(src, src, "cubic"); err == nil {
+		t.Errorf("New() with unknown method succeeded")
+	}
+	if _, err := New(Grid{Lats: []float64{0}, Lons: []float64{0, 1}}, src, Bilinear); err == nil {
+		t.Errorf("New() with single latitude succeeded")
+	}
+}
+
+func TestRegridDirection(t *testing.T) {
+	nan := float32(math.NaN())
+	src := Grid{Lats: []float64{0, 1}, Lons: []float64{0, 1}}
+	dst := Grid{Lats: []float64{0}, Lons: []float64{0.5}}
+
+	tests := []struct {
+		name   string
+		values [2]float32 // 两个经度上的方向
+		want   float32
+	}{
+		{name: "across north", values: [2]float32{350, 10}, want: 0},
+		{name: "across north unequal", values: [2]float32{340, 10}, want: 355},
+		{name: "no wrap", values: [2]float32{80, 100}, want: 90},
+		{name: "south", values: [2]float32{170, 190}, want: 180},
+		{name: "nan", values: [2]float32{nan, 10}, want: 10},
+		{name: "all nan", values: [2]float32{nan, nan}, want: nan},
+	}
+
+	r, err := New(src, dst, Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		got := r.RegridDirection(func(lat, lon int) float32 { return tt.values[lon] })[0][0]
+		// 0 与 360 度是同一个方向
+		if !equal(got, tt.want) && !(tt.want == 0 && equal(got, 360)) {
+			t.Errorf("RegridDirection(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+		if got < 0 || got > 360 {
+			t.Errorf("RegridDirection(%s) = %v out of range", tt.name, got)
+		}
+	}
+}
+
+func TestDirection(t *testing.T) {
+	nan := float32(math.NaN())
+
+	tests := []struct {
+		sin, cos float32
+		want     float32
+	}{
+		{sin: 0, cos: 1, want: 0},
+		{sin: 1, cos: 0, want: 90},
+		{sin: 0, cos: -1, want: 180},
+		{sin: -1, cos: 0, want: 270},
+		{sin: -0.5, cos: 0.5, want: 315},
+		{sin: nan, cos: 1, want: nan},
+		{sin: 1, cos: nan, want: nan},
+	}
+
+	for _, tt := range tests {
+		if got := Direction(tt.sin, tt.cos); !equal(got, tt.want) {
+			t.Errorf("Direction(%v, %v) = %v, want %v", tt.sin, tt.cos, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package tile
+
+import "math"
+
+// MaxLatitude Web Mercator 能表示的最大纬度
+const MaxLatitude = 85.05112877980659
+
+// Lons 第 z 级第 x 列瓦片每个像素中心的经度, 从西到东
+func Lons(z, x, size int) []float64 {
+	n := float64(int(1) << z)
+	lons := make([]float64, size)
+	for i := range lons {
+		lons[i] = (float64(x)+(float64(i)+0.5)/float64(size))/n*360 - 180
+	}
+
+	return lons
+}
+
+// Lats 第 z 级第 y 行瓦片每个像素中心的纬度, 从北到南
+func Lats(z, y, size int) []float64 {
+	n := float64(int(1) << z)
+	lats := make([]float64, size)
+	for i := range lats {
+		v := (float64(y) + (float64(i)+0.5)/float64(size)) / n
+		lats[i] = math.Atan(math.Sinh(math.Pi*(1-2*v))) * 180 / math.Pi
+	}
+
+	return lats
+}
@@ -0,0 +1,117 @@
+package tile
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Stop 色带上的一个颜色节点
+type Stop struct {
+	Value float64
+	Color color.NRGBA
+}
+
+// Ramp 色带, 节点之间线性插值颜色, 超出范围时使用两端的颜色
+type Ramp []Stop
+
+// ParseRamp 解析色带, 格式: value:#rrggbb[aa],value:#rrggbb[aa],..., 例如: 0:#3288bd,10:#fee08b,20:#d53e4f
+func ParseRamp(spec string) (Ramp, error) {
+	var ramp Ramp
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		value, hex, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("ramp stop: %s must be value:#rrggbb", item)
+		}
+
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse ramp value: %s failed: %v", value, err)
+		}
+
+		c, err := parseColor(strings.TrimSpace(hex))
+		if err != nil {
+			return nil, err
+		}
+
+		ramp = append(ramp, Stop{Value: v, Color: c})
+	}
+
+	if len(ramp) < 2 {
+		return nil, fmt.Errorf("ramp: %s must have at least 2 stops", spec)
+	}
+
+	sort.Slice(ramp, func(i, j int) bool { return ramp[i].Value < ramp[j].Value })
+	return ramp, nil
+}
+
+func parseColor(hex string) (color.NRGBA, error) {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 && len(hex) != 8 {
+		return color.NRGBA{}, fmt.Errorf("ramp color: #%s must be #rrggbb or #rrggbbaa", hex)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("parse ramp color: #%s failed: %v", hex, err)
+	}
+
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// Min 色带的最小值
+func (r Ramp) Min() float64 {
+	return r[0].Value
+}
+
+// Max 色带的最大值
+func (r Ramp) Max() float64 {
+	return r[len(r)-1].Value
+}
+
+// Color 值对应的颜色, 缺测值为透明
+func (r Ramp) Color(value float32) color.NRGBA {
+	v := float64(value)
+	if math.IsNaN(v) {
+		return color.NRGBA{}
+	}
+
+	if v <= r.Min() {
+		return r[0].Color
+	}
+
+	for i := 1; i < len(r); i++ {
+		if v <= r[i].Value {
+			w := (v - r[i-1].Value) / (r[i].Value - r[i-1].Value)
+			return mix(r[i-1].Color, r[i].Color, w)
+		}
+	}
+
+	return r[len(r)-1].Color
+}
+
+// String 色带的配置格式
+func (r Ramp) String() string {
+	stops := make([]string, len(r))
+	for i, s := range r {
+		stops[i] = fmt.Sprintf("%v:#%02x%02x%02x%02x", s.Value, s.Color.R, s.Color.G, s.Color.B, s.Color.A)
+	}
+
+	return strings.Join(stops, ",")
+}
+
+func mix(a, b color.NRGBA, w float64) color.NRGBA {
+	lerp := func(x, y uint8) uint8 { return uint8(math.Round(float64(x) + w*(float64(y)-float64(x)))) }
+	return color.NRGBA{R: lerp(a.R, b.R), G: lerp(a.G, b.G), B: lerp(a.B, b.B), A: lerp(a.A, b.A)}
+}
@@ -0,0 +1,70 @@
+package tile
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+)
+
+// Size 瓦片的像素大小
+const Size = 256
+
+// Encoding 瓦片的编码方式
+type Encoding string
+
+const (
+	// ColorEncoding 按照色带着色, 用于直接叠加显示
+	ColorEncoding Encoding = "color"
+	// ValueEncoding 将值编码到 RGB 中, 用于客户端解码后着色或查询:
+	// value = min + (R * 65536 + G * 256 + B) / 16777215 * (max - min), min 和 max 为色带的范围, 缺测值透明
+	ValueEncoding Encoding = "value"
+)
+
+// maxEncoded 24 位 RGB 能表示的最大整数
+const maxEncoded = 1<<24 - 1
+
+// Encode 按照编码方式将值转换为颜色
+func Encode(value float32, ramp Ramp, encoding Encoding) color.NRGBA {
+	if encoding != ValueEncoding {
+		return ramp.Color(value)
+	}
+
+	v := float64(value)
+	if math.IsNaN(v) {
+		return color.NRGBA{}
+	}
+
+	ratio := math.Min(math.Max((v-ramp.Min())/(ramp.Max()-ramp.Min()), 0), 1)
+	n := uint32(math.Round(ratio * maxEncoded))
+	return color.NRGBA{R: uint8(n >> 16), G: uint8(n >> 8), B: uint8(n), A: 255}
+}
+
+// Render 将瓦片像素上的值渲染为图片, values[row][col] 从北到南, 从西到东
+func Render(values [][]float32, ramp Ramp, encoding Encoding) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, Size, Size))
+	for row := range values {
+		for col, value := range values[row] {
+			img.SetNRGBA(col, row, Encode(value, ramp, encoding))
+		}
+	}
+
+	return img
+}
+
+// WritePNG 写入 PNG 文件
+func WritePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create tile: %s failed: %v", path, err)
+	}
+	defer file.Close()
+
+	if err := png.Encode(file, img); err != nil {
+		return fmt.Errorf("encode tile: %s failed: %v", path, err)
+	}
+
+	return nil
+}
@@ -4,5 +4,235 @@ export LOG_SIZE=20
 export LOG_AGE=10
 export LOG_BACKUPS=5
 
+# NC_DIR 可以是对象存储中的 s3://bucket/prefix, 连接配置使用 S3_ENDPOINT 等, 与 S3_ENABLE 无关
 export NC_DIR=/data2/alist_share/nc-files
 export CSV_DIR=/data1/yihailan-generate-files
+
+# 抽样步长, 1 表示原始分辨率
+export EC_LAT_STRIDE=1
+export EC_LON_STRIDE=1
+export MFWAM_LAT_STRIDE=3
+export MFWAM_LON_STRIDE=3
+export MFWAM_TIME_STRIDE=1
+export SMOC_LAT_STRIDE=3
+export SMOC_LON_STRIDE=3
+export SMOC_TIME_STRIDE=3
+
+# 输出区域, 为空时只输出全球数据, global 表示全球
+# 预置区域: north_pacific, south_pacific, north_atlantic, south_atlantic, indian_ocean, china_seas, south_china_sea, mediterranean
+# 自定义区域格式: name:west,south,east,north, 多个区域用 ; 分隔, west > east 表示跨越 180 度经线
+# 多边形区域格式: name:/path/to/file.geojson, 只输出多边形内的格点
+export REGIONS=""
+export EC_REGIONS=""
+export MFWAM_REGIONS=""
+export SMOC_REGIONS=""
+
+# 插值目标网格, 插值方法为空时不插值, 可选: bilinear, nearest
+# 插值后所有数据源的经纬度一致, 空间抽样步长不再生效
+# 范围为 west,south,east,north, west 大于 east 时表示跨越 180 度经线, 东侧经度加 360 输出 (如 170,-10,-170,10 输出 170 ~ 190)
+export REGRID_METHOD=""
+export REGRID_RESOLUTION=0.25
+export REGRID_EXTENT="-180,-80,179.75,90"
+export REGRID_LAT_ORDER="desc"
+
+# 风(EC), 浪(MFWAM), 流(SMOC)合并输出, 未配置插值方法时双线性插值到默认网格
+# 缺失的数据源输出为 NaN 并记录在元数据的 missing 中, 数据源到达后重新生成; 所有数据源都没有到达时不生成也不通知
+export COMBINED_ENABLE=false
+export COMBINED_REGIONS=""
+
+# 时间插值的步长, 例如: 1h, 为空时不插值, 插值后时间抽样步长不再生效
+# 会读取下一个起报时次的文件插值两个文件之间的时次, u/v 按照矢量插值, 浪向按照圆周插值
+export EC_TIME_INTERVAL=""
+export MFWAM_TIME_INTERVAL=""
+export SMOC_TIME_INTERVAL=""
+
+# 追加输出的派生列, 多个用 , 分隔, 为空时不输出
+# EC: windSpeed(m/s), windSpeedKnots(节), windDirection(风的来向, 度), beaufort(蒲福风级)
+# MFWAM: douglasSeaState(道格拉斯风浪等级 0~9), wmoSeaState(WMO 海况代码 0~9)
+#        waveSteepness(波陡), waveSteepnessClass(波陡等级 0: 平缓, 1: 一般, 2: 陡, 3: 很陡)
+# SMOC: currentSpeed, currentSpeedKnots, currentDirection(流的去向, 度)
+#       tideCurrentSpeed, tideCurrentSpeedKnots, tideCurrentDirection(潮流)
+#       uResidualCurrent, vResidualCurrent, residualCurrentSpeed, residualCurrentSpeedKnots, residualCurrentDirection(余流 = 总流 - 潮流)
+# 任意输入分量缺测时派生列输出 NaN
+export EC_DERIVED=""
+export MFWAM_DERIVED=""
+export SMOC_DERIVED=""
+
+# 输出列的单位方案, 为空时输出原始单位(K, Pa, m/s, m), 配置后表头记录单位, 例如: temperature2m[degC]
+# si: 国际单位, marine: °C, hPa, 节, m, imperial: °F, inHg, 节, ft
+export EC_UNITS=""
+export MFWAM_UNITS=""
+export SMOC_UNITS=""
+export COMBINED_UNITS=""
+
+# 输出格式, 多个用 , 分隔, 为空时只输出 csv
+# csv: csv 与 metadata.json 一起压缩为 zip, parquet: 与 zip 同名的 .parquet 文件, 每个时次一个 row group, 缺测值为 null
+# netcdf: 与 zip 同名的 .nc 文件, 符合 CF 约定, 可以直接用 xarray 打开
+# geojson: 与 zip 同名的 .ndjson 文件, 每行一个 Point Feature, 缺测值为 null
+export EC_FORMATS=""
+export MFWAM_FORMATS=""
+export SMOC_FORMATS=""
+export COMBINED_FORMATS=""
+# parquet 的压缩方式: snappy, zstd, gzip, none
+export PARQUET_CODEC="snappy"
+# csv 与 metadata.json 的打包方式, 决定压缩包的扩展名
+# zip: .zip, gzip: .tar.gz, zstd: .tar.zst(解压更快), xz: .tar.xz(压缩率最高), none: 不压缩的 .tar
+# 压缩级别 1~9, 0 表示默认级别; zstd 按照 zstd 命令行的级别映射(1 最快, 8~9 最好), xz 按照 xz 预设映射字典大小(9 为 64MiB); none 只能为 0
+export ARCHIVE_CODEC="zip"
+export ARCHIVE_LEVEL=0
+
+# 只输出指定的列(包括派生列), 多个用 , 分隔, 为空时输出所有列, 例如: wind10mU,wind10mV,windSpeed
+export EC_COLUMNS=""
+export MFWAM_COLUMNS=""
+export SMOC_COLUMNS=""
+export COMBINED_COLUMNS=""
+
+# GeoJSON 每隔多少个格点输出一个, 只对 geojson 格式生效, 为 0 或 1 时输出所有格点
+export EC_GEOJSON_THIN=0
+export MFWAM_GEOJSON_THIN=0
+export SMOC_GEOJSON_THIN=0
+export COMBINED_GEOJSON_THIN=0
+
+# PNG 瓦片(Web Mercator XYZ), 输出到 CSV_DIR/tiles/{dataset}/{validtime}/{z}/{x}/{y}.png, 每个时次附带 tile.json
+# 图层: EC 风速(m/s), MFWAM 有效波高(m), SMOC 流速(m/s), 使用原始分辨率的全球数据
+# 色带格式: value:#rrggbb[aa],..., 节点之间线性插值, 缺测值透明
+# 编码方式: color: 按色带着色, value: 值编码到 RGB, value = min + (R * 65536 + G * 256 + B) / 16777215 * (max - min)
+export EC_TILES_ENABLE=false
+export EC_TILES_RAMP="0:#3288bd,5:#66c2a5,10:#abdda4,15:#fee08b,20:#fdae61,25:#f46d43,30:#d53e4f"
+export EC_TILES_ENCODING="color"
+export MFWAM_TILES_ENABLE=false
+export MFWAM_TILES_RAMP="0:#f7fbff,1:#c6dbef,2:#6baed6,4:#2171b5,6:#fdae61,9:#d7301f,14:#7f0000"
+export MFWAM_TILES_ENCODING="color"
+export SMOC_TILES_ENABLE=false
+export SMOC_TILES_RAMP="0:#ffffcc,0.25:#c7e9b4,0.5:#7fcdbb,1:#41b6c4,1.5:#1d91c0,2:#225ea8,3:#0c2c84"
+export SMOC_TILES_ENCODING="color"
+export TILE_MIN_ZOOM=0
+export TILE_MAX_ZOOM=4
+
+# leaflet-velocity 格式的 u/v JSON, 输出到 CSV_DIR/velocity/{dataset}/{validtime}[_region].json, 只支持 EC(10 米风) 和 SMOC(总流)
+# 分辨率(度)大于 0 时双线性插值到规则网格, 为 0 时使用原始网格; 区域为空时输出全球
+export EC_VELOCITY_ENABLE=false
+export EC_VELOCITY_RESOLUTION=1
+export EC_VELOCITY_REGION=""
+export SMOC_VELOCITY_ENABLE=false
+export SMOC_VELOCITY_RESOLUTION=1
+export SMOC_VELOCITY_REGION=""
+
+# GeoTIFF(COG) 输出的变量, 多个用 , 分隔, 为空时不输出, 可以是 NetCDF 变量名或列名(包括派生列), 例如: VHM0,douglasSeaState
+# 与 zip 同目录, 每个区域每个变量一个文件, 例如: mfwam_VHM0_2025061300.tif, 每个时次一个波段, 波段描述为时次
+# float32, EPSG:4326, 缺测值 -9999, 内部 256x256 分块, deflate 压缩, 附带概视图
+export EC_GEOTIFF=""
+export MFWAM_GEOTIFF=""
+export SMOC_GEOTIFF=""
+
+# 额外的输出方案, 例如为不同的客户输出到不同的目录, 多个用 , 分隔, 同一个输入文件只解码一次
+# 每个方案的配置以 PROFILE_{方案名称} 为前缀(大写, - 替换为 _), 为空的配置使用数据源自身的配置
+# DIR: 输出的根目录(必填), DATASETS: 适用的数据源(ec, mfwam, smoc, combined), 为空时适用于所有数据源
+# LAYOUT: 相对根目录的文件路径模板, 格式与 *_OUTPUT_LAYOUT 相同, 为空时使用数据源的输出路径模板
+# 瓦片, GeoTIFF 和 velocity JSON 只按照数据源自身的配置输出; 合并输出忽略抽样步长
+export PROFILES=""
+# export PROFILES="cosco"
+# export PROFILE_COSCO_DIR=/data1/cosco-generate-files
+# export PROFILE_COSCO_LAYOUT="{dataset}/{year}/{month}/{dataset}_{cycle:2006010215}.{ext}"
+# export PROFILE_COSCO_DATASETS="ec,mfwam"
+# export PROFILE_COSCO_REGIONS="north_pacific"
+# export PROFILE_COSCO_COLUMNS=""
+# export PROFILE_COSCO_UNITS="marine"
+# export PROFILE_COSCO_FORMATS="csv,parquet"
+# export PROFILE_COSCO_LAT_STRIDE=0
+# export PROFILE_COSCO_LON_STRIDE=0
+# export PROFILE_COSCO_TIME_STRIDE=0
+# export PROFILE_COSCO_ARCHIVE_CODEC="zstd"
+# export PROFILE_COSCO_ARCHIVE_LEVEL=0
+
+# 输入和输出文件的路径模板, 变量格式: {name} 或 {name:layout}, layout 为 Go 的时间格式
+# {year}, {month}, {day}, {hour}: 起报时次的年, 月, 日, 时, {date}: 2006-01-02
+# {cycle:layout}: 起报时次, {run:layout}: 模式的起报时刻(EC 每 12 小时起报一次), 默认格式 2006010215
+# {step}: 起报时次相对模式起报时刻的小时数, {dataset}: ec, mfwam, smoc, combined, {ext}: 扩展名
+# 输入模板相对 NC_DIR, 可以使用通配符 * ? [], 匹配多个文件时使用排序后的第一个
+# 输出模板相对 CSV_DIR(或输出方案的根目录), 必须以 .{ext} 结尾, 各输出格式替换为各自的扩展名
+export EC_INPUT_LAYOUT="ec_0p25/{year}/{date}/oper-{run:15}/ec_0p25_oper_{run:2006010215}_{step}h.nc"
+export MFWAM_INPUT_LAYOUT="mfwam/{year}/{month}/*{cycle:2006010215}*"
+export SMOC_INPUT_LAYOUT="smoc/{year}/{month}/*{cycle:20060102}*"
+export EC_OUTPUT_LAYOUT="{year}/{month}/{date}/{dataset}_{cycle:2006010215}.{ext}"
+export MFWAM_OUTPUT_LAYOUT="{year}/{month}/{date}/{dataset}_{cycle:2006010215}.{ext}"
+export SMOC_OUTPUT_LAYOUT="{year}/{month}/{date}/{dataset}_{cycle:20060102}.{ext}"
+export COMBINED_OUTPUT_LAYOUT="{year}/{month}/{date}/{dataset}_{cycle:2006010215}.{ext}"
+
+# 输出文件上传到 S3 兼容的对象存储(例如: MinIO), 每个输出文件生成后上传, 打包格式同时上传元数据 {文件名}.metadata.json
+# 对象名为 S3_PREFIX 加上文件名, S3_PREFIX 的变量与输出路径模板相同, 另外 {profile} 为输出方案名称(默认输出为空)
+# 大于 S3_PART_SIZE(MB, 最小 5)的文件分片上传, 上传时发送 Content-MD5, 上传后比较 ETag 与本地文件的 MD5
+# 失败后按照 1s, 2s, 4s ... 重试 S3_RETRIES 次; 上传失败的文件在下一轮只重新上传, 不重新生成
+# S3_DELETE_LOCAL=true 时上传成功后删除本地文件, 对象存储中已经存在的文件不再重新生成
+export S3_ENABLE=false
+export S3_ENDPOINT="127.0.0.1:9000"
+export S3_ACCESS_KEY=""
+export S3_SECRET_KEY=""
+export S3_REGION=""
+export S3_SECURE=false
+export S3_BUCKET="meteo-products"
+export S3_PREFIX="{profile}/{dataset}/{year}/{month}/{date}"
+export S3_PART_SIZE=16
+export S3_RETRIES=3
+export S3_DELETE_LOCAL=false
+
+# NC_DIR 为 s3:// 时, 按照输入模板列出通配符之前的前缀, 使用字典序第一个匹配的对象
+# 对象下载到 {S3_CACHE_DIR}/{bucket}/{key} 后解码, ETag 没有变化时使用缓存, S3_CACHE_DIR 为空时使用系统临时目录
+# 超过 S3_CACHE_DAYS 天没有使用的缓存文件在下载新文件时删除, 0 表示不删除
+export S3_CACHE_DIR=""
+export S3_CACHE_DAYS=7
+
+# 输出文件投递到客户的 SFTP 服务器, 多个目标用 , 分隔, 每个目标的配置以 SFTP_{目标名称} 为前缀(大写, - 替换为 _)
+# 只支持密钥认证, KEY_FILE 为 PEM 格式的私钥, KNOWN_HOSTS 用于校验服务器的主机密钥, INSECURE=true 时不校验(只用于测试环境)
+# PATH: 远端目录模板, 变量与输出路径模板相同, 相对登录目录, 文件名与本地相同; 先写入 {文件名}.part, 大小一致后重命名
+# DATASETS: 投递的数据源, 为空时投递所有数据源; PROFILE: 投递的输出方案, 为空时投递默认输出
+# 每个目标每个数据源的投递状态保存在 {SFTP_STATUS_DIR}/{目标名称}_{数据源}.json, 已经投递的文件不会重复投递
+# 失败后按照 1s, 2s, 4s ... 重试 SFTP_RETRIES 次, 一个目标失败不影响其他目标, 下一轮重新投递
+# 超过 SFTP_STATUS_DAYS 天没有更新的投递状态在更新状态时删除, 0 表示不删除, 需要大于重新检查起报时次的天数(5 天)
+export SFTP_TARGETS=""
+export SFTP_RETRIES=3
+export SFTP_TIMEOUT=30
+export SFTP_STATUS_DIR=""
+export SFTP_STATUS_DAYS=30
+# export SFTP_TARGETS="cosco"
+# export SFTP_COSCO_ADDR="sftp.example.com:22"
+# export SFTP_COSCO_USER="meteo"
+# export SFTP_COSCO_KEY_FILE=/etc/gen-meteo-file/id_ed25519
+# export SFTP_COSCO_PASSPHRASE=""
+# export SFTP_COSCO_KNOWN_HOSTS=/etc/gen-meteo-file/known_hosts
+# export SFTP_COSCO_INSECURE=false
+# export SFTP_COSCO_PATH="upload/{dataset}/{date}"
+# export SFTP_COSCO_DATASETS="ec,mfwam"
+# export SFTP_COSCO_PROFILE="cosco"
+
+# 起报时次的输出文件生成(或者补传)完成后, 以 JSON POST 通知 Webhook, 多个 Webhook 用 , 分隔, 每个的配置以 WEBHOOK_{名称} 为前缀(大写, - 替换为 _)
+# 请求体: {"id", "type": "product.ready", "dataset", "cycle", "valid_times", "files": [{"path", "locations", "profile", "format", "region", "size", "sha256"}], "missing", "time"}
+# missing 为合并输出缺失的数据源及原因, 例如: {"smoc": "input file not found"}, 数据源到达后重新生成并再次通知
+# path 为本地路径(S3_DELETE_LOCAL=true 时上传后被删除), locations 为上传或者投递后的位置: s3://{bucket}/{key}, sftp://{目标名称}/{远端路径}
+# 输入文件存在但是生成失败时发送 {"id", "type": "product.failed", "dataset", "cycle", "error", "time"}, 还没有输入文件的起报时次不发送
+# SECRET 不为空时请求头 X-Meteo-Signature 为 sha256={HMAC-SHA256(SECRET, 请求体)的十六进制}, X-Meteo-Delivery 为事件 id, 用于去重
+# DATASETS: 通知的数据源, 为空时通知所有数据源; 返回 2xx 为成功
+# 网络错误, 408, 429 和 5xx 按照 1s, 2s, 4s ... 重试 WEBHOOK_RETRIES 次, WEBHOOK_TIMEOUT 为单次请求的超时(秒)
+# 重试后仍然失败的事件追加到 WEBHOOK_DEAD_LETTER, 每行一个 JSON, 为空时使用 {CSV_DIR}/.webhook/dead_letter.jsonl
+export WEBHOOKS=""
+export WEBHOOK_RETRIES=3
+export WEBHOOK_TIMEOUT=10
+export WEBHOOK_DEAD_LETTER=""
+# export WEBHOOKS="routing"
+# export WEBHOOK_ROUTING_URL="https://routing.example.com/hooks/meteo"
+# export WEBHOOK_ROUTING_SECRET=""
+# export WEBHOOK_ROUTING_DATASETS="ec,mfwam"
+
+# 事件同时发布到消息中间件, BROKER_TYPE: nats 或者 redis, 为空时不发布; 事件格式与 Webhook 的请求体相同, 所有数据集共用一个连接
+# nats: 发布到 {NATS_SUBJECT}.product.ready 和 {NATS_SUBJECT}.product.failed, 消息头 Nats-Msg-Id 为事件 id, NATS_URL 多个地址用 , 分隔
+# redis: XADD 到 REDIS_STREAM, 字段为 id, type, dataset, cycle 和 JSON 格式的 event, 近似保留 REDIS_MAX_LEN 条, 0 表示不裁剪
+# BROKER_TIMEOUT 为单次发布的超时(秒), 发布失败只记录日志, 不影响生成
+export BROKER_TYPE=""
+export BROKER_TIMEOUT=10
+export NATS_URL="nats://127.0.0.1:4222"
+export NATS_SUBJECT="meteo"
+export REDIS_ADDR="127.0.0.1:6379"
+export REDIS_PASSWORD=""
+export REDIS_DB=0
+export REDIS_STREAM="meteo:products"
+export REDIS_MAX_LEN=10000